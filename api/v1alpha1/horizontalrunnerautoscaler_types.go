@@ -33,15 +33,39 @@ type HorizontalRunnerAutoscalerSpec struct {
 	// +optional
 	MaxReplicas *int `json:"maxReplicas,omitempty"`
 
+	// OverflowTargetRef is the reference to the RunnerDeployment or RunnerSet that receives the replicas above MaxReplicas,
+	// which are otherwise dropped. It's scaled to zero while the demand is within MaxReplicas, so it must not be
+	// scaled by anything else, like another HorizontalRunnerAutoscaler.
+	// +optional
+	OverflowTargetRef *ScaleTargetRef `json:"overflowTargetRef,omitempty"`
+
+	// Priority is used by the webhook-based autoscaler to select one of the HRAs that match the same webhook event,
+	// when it's configured with the Priority or Spillover selection policy. The higher one is preferred. Defaults to 0.
+	// +optional
+	Priority *int `json:"priority,omitempty"`
+
 	// ScaleDownDelaySecondsAfterScaleUp is the approximate delay for a scale down followed by a scale up
 	// Used to prevent flapping (down->up->down->... loop)
 	// +optional
 	ScaleDownDelaySecondsAfterScaleUp *int `json:"scaleDownDelaySecondsAfterScaleOut,omitempty"`
 
+	// Behavior configures the stabilization windows and the rate limits of scaling up and down,
+	// modeled on the behavior of HorizontalPodAutoscaler.
+	// +optional
+	Behavior *HorizontalRunnerAutoscalerBehavior `json:"behavior,omitempty"`
+
 	// Metrics is the collection of various metric targets to calculate desired number of runners
 	// +optional
 	Metrics []MetricSpec `json:"metrics,omitempty"`
 
+	// MetricsPolicy is the policy to combine the suggested replicas of all the Metrics.
+	// The valid values are "Max", "Min", and "FirstNonNil".
+	// If empty, Metrics must have at most 2 entries and the only allowed combination is
+	// PercentageRunnersBusy followed by TotalNumberOfQueuedAndInProgressWorkflowRuns as the fallback.
+	// +optional
+	// +kubebuilder:validation:Enum=Max;Min;FirstNonNil
+	MetricsPolicy string `json:"metricsPolicy,omitempty"`
+
 	// ScaleUpTriggers is an experimental feature to increase the desired replicas by 1
 	// on each webhook requested received by the webhookBasedAutoscaler.
 	//
@@ -53,6 +77,8 @@ type HorizontalRunnerAutoscalerSpec struct {
 	// receive a webhook from GitHub, so that you can loosely expect MinReplicas runners to be always available.
 	ScaleUpTriggers []ScaleUpTrigger `json:"scaleUpTriggers,omitempty"`
 
+	// CapacityReservations is the list of the replicas temporarily added to the scale target, declared by the user.
+	// The reservations made by the webhook-based autoscaler are recorded in the status instead.
 	CapacityReservations []CapacityReservation `json:"capacityReservations,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
 
 	// ScheduledOverrides is the list of ScheduledOverride.
@@ -60,6 +86,85 @@ type HorizontalRunnerAutoscalerSpec struct {
 	// The earlier a scheduled override is, the higher it is prioritized.
 	// +optional
 	ScheduledOverrides []ScheduledOverride `json:"scheduledOverrides,omitempty"`
+
+	// Predictive enables raising minReplicas ahead of the recurring peaks learned from the past demand.
+	// +optional
+	Predictive *PredictiveScaling `json:"predictive,omitempty"`
+
+	// ScaleToZero drains the scale target to zero replicas after an idle period, regardless of minReplicas,
+	// and wakes it up on the first queued workflow job.
+	// +optional
+	ScaleToZero *ScaleToZero `json:"scaleToZero,omitempty"`
+
+	// GitHubAPICredentialsFrom is the reference to the GitHub API credentials used for polling metrics.
+	// If omitted, the credentials of the scale target are used, and then the controller-wide ones.
+	// +optional
+	GitHubAPICredentialsFrom *GitHubAPICredentialsFrom `json:"githubAPICredentialsFrom,omitempty"`
+}
+
+// HorizontalRunnerAutoscalerBehavior configures the scaling behavior in the up and down directions.
+// A direction without rules isn't stabilized nor rate-limited.
+type HorizontalRunnerAutoscalerBehavior struct {
+	// ScaleUp is the rules for scaling up.
+	// +optional
+	ScaleUp *ScalingRules `json:"scaleUp,omitempty"`
+
+	// ScaleDown is the rules for scaling down.
+	// +optional
+	ScaleDown *ScalingRules `json:"scaleDown,omitempty"`
+}
+
+const (
+	// ScalingPolicyTypePods limits the change to the number of replicas in the period.
+	ScalingPolicyTypePods = "Pods"
+
+	// ScalingPolicyTypePercent limits the change to the percentage of the replicas at the start of the period.
+	ScalingPolicyTypePercent = "Percent"
+
+	// ScalingPolicySelectMax selects the policy allowing the largest change.
+	ScalingPolicySelectMax = "Max"
+
+	// ScalingPolicySelectMin selects the policy allowing the smallest change.
+	ScalingPolicySelectMin = "Min"
+
+	// ScalingPolicySelectDisabled disables scaling in the direction.
+	ScalingPolicySelectDisabled = "Disabled"
+)
+
+// ScalingRules configures the scaling behavior in one direction.
+type ScalingRules struct {
+	// StabilizationWindowSeconds is the number of seconds for which the past recommendations are considered while scaling,
+	// so that e.g. the highest recommendation within the window is used for scaling down.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=3600
+	StabilizationWindowSeconds *int32 `json:"stabilizationWindowSeconds,omitempty"`
+
+	// SelectPolicy is the policy to select one of Policies, either "Max", "Min", or "Disabled". Defaults to "Max".
+	// +optional
+	// +kubebuilder:validation:Enum=Max;Min;Disabled
+	SelectPolicy string `json:"selectPolicy,omitempty"`
+
+	// Policies limit how many replicas can be changed within a period.
+	// If empty, the change isn't rate-limited.
+	// +optional
+	Policies []ScalingPolicy `json:"policies,omitempty"`
+}
+
+// ScalingPolicy limits the change of the replicas within a period.
+type ScalingPolicy struct {
+	// Type is either "Pods" or "Percent".
+	// +kubebuilder:validation:Enum=Pods;Percent
+	Type string `json:"type"`
+
+	// Value is the number or the percentage of the replicas that can be changed within the period.
+	// +kubebuilder:validation:Minimum=1
+	Value int32 `json:"value"`
+
+	// PeriodSeconds is the length of the period in seconds.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=1800
+	PeriodSeconds int32 `json:"periodSeconds"`
 }
 
 type ScaleUpTrigger struct {
@@ -94,6 +199,36 @@ type CheckRunSpec struct {
 
 // https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#workflow_job
 type WorkflowJobSpec struct {
+	// Repositories is a list of GitHub repositories.
+	// Any workflow_job event whose repository matches one of repositories in the list can trigger autoscaling.
+	// Each entry can be either the REPO part or the OWNER/REPO of `github.com/OWNER/REPO`.
+	// +optional
+	Repositories []string `json:"repositories,omitempty"`
+
+	// WorkflowNames is a list of GitHub Actions glob patterns.
+	// Any workflow_job event whose workflow name matches one of patterns in the list can trigger autoscaling.
+	// +optional
+	WorkflowNames []string `json:"workflowNames,omitempty"`
+
+	// Names is a list of GitHub Actions glob patterns.
+	// Any workflow_job event whose job name matches one of patterns in the list can trigger autoscaling.
+	// +optional
+	Names []string `json:"names,omitempty"`
+
+	// Branches is a list of GitHub Actions glob patterns.
+	// Any workflow_job event whose head branch matches one of patterns in the list can trigger autoscaling.
+	// +optional
+	Branches []string `json:"branches,omitempty"`
+
+	// Labels is a list of runner labels that the workflow_job must request in its `runs-on`,
+	// in addition to the labels of the scale target's runners.
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+
+	// ExcludedLabels is a list of runner labels.
+	// Any workflow_job event that requests one of labels in the list never triggers autoscaling.
+	// +optional
+	ExcludedLabels []string `json:"excludedLabels,omitempty"`
 }
 
 // https://docs.github.com/en/actions/reference/events-that-trigger-workflows#pull_request
@@ -116,6 +251,28 @@ type CapacityReservation struct {
 
 	// +optional
 	EffectiveTime metav1.Time `json:"effectiveTime,omitempty"`
+
+	// JobID is the ID of the GitHub Actions workflow job the reservation is made for.
+	// The completion of the job removes exactly this reservation.
+	// +optional
+	JobID int64 `json:"jobID,omitempty"`
+
+	// RunID and JobName identify the workflow job the reservation is made for, when JobID is not known.
+	// +optional
+	RunID int64 `json:"runID,omitempty"`
+
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+
+	// Repository is the owner and the name of the repository of the workflow job, like "owner/name".
+	// +optional
+	Repository string `json:"repository,omitempty"`
+}
+
+// CompletedJob is a workflow job the webhook-based autoscaler received the completed event of.
+type CompletedJob struct {
+	JobID          int64       `json:"jobID"`
+	CompletionTime metav1.Time `json:"completionTime"`
 }
 
 type ScaleTargetRef struct {
@@ -128,9 +285,31 @@ type ScaleTargetRef struct {
 	Name string `json:"name,omitempty"`
 }
 
+// RefersTo returns true when both refer to the same RunnerDeployment or RunnerSet.
+func (r ScaleTargetRef) RefersTo(other ScaleTargetRef) bool {
+	kind := func(ref ScaleTargetRef) string {
+		if ref.Kind == "" {
+			return "RunnerDeployment"
+		}
+		return ref.Kind
+	}
+
+	return kind(r) == kind(other) && r.Name == other.Name
+}
+
+const (
+	// MetricsPolicyMax uses the largest replicas suggested by any of the metrics.
+	MetricsPolicyMax = "Max"
+	// MetricsPolicyMin uses the smallest replicas suggested by any of the metrics.
+	MetricsPolicyMin = "Min"
+	// MetricsPolicyFirstNonNil uses the replicas suggested by the first metric that suggested any.
+	MetricsPolicyFirstNonNil = "FirstNonNil"
+)
+
 type MetricSpec struct {
 	// Type is the type of metric to be used for autoscaling.
-	// The only supported Type is TotalNumberOfQueuedAndInProgressWorkflowRuns
+	// The built-in types are TotalNumberOfQueuedAndInProgressWorkflowRuns, PercentageRunnersBusy, and Prometheus.
+	// Any other type must be served by a metric provider registered to the controller.
 	Type string `json:"type,omitempty"`
 
 	// RepositoryNames is the list of repository names to be used for calculating the metric.
@@ -138,6 +317,20 @@ type MetricSpec struct {
 	// +optional
 	RepositoryNames []string `json:"repositoryNames,omitempty"`
 
+	// DiscoverRepositories makes TotalNumberOfQueuedAndInProgressWorkflowRuns of an organizational scale target
+	// find the repositories to be scanned by itself, so that you don't need to list RepositoryNames.
+	// Half of the repositories scanned per sync are the most recently pushed ones, and the other half rotates through
+	// the rest of the repositories across syncs. The result is shared among all the HRAs
+	// that target the same organization. It's ignored when RepositoryNames is set.
+	// +optional
+	DiscoverRepositories bool `json:"discoverRepositories,omitempty"`
+
+	// MaxRepositoriesPerSync is the maximum number of repositories scanned per sync when DiscoverRepositories is enabled.
+	// Defaults to 30.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxRepositoriesPerSync int `json:"maxRepositoriesPerSync,omitempty"`
+
 	// ScaleUpThreshold is the percentage of busy runners greater than which will
 	// trigger the hpa to scale runners up.
 	// +optional
@@ -167,16 +360,63 @@ type MetricSpec struct {
 	// You can only specify either ScaleDownFactor or ScaleDownAdjustment.
 	// +optional
 	ScaleDownAdjustment int `json:"scaleDownAdjustment,omitempty"`
+
+	// Prometheus is the query used for calculating the metric of the Prometheus type.
+	// +optional
+	Prometheus *PrometheusMetricSource `json:"prometheus,omitempty"`
+}
+
+// PrometheusMetricSource is a PromQL query against a Prometheus-compatible HTTP API
+// whose result is divided by the target value per runner to suggest the desired replicas.
+type PrometheusMetricSource struct {
+	// Address is the base URL of the Prometheus-compatible HTTP API, like http://prometheus.monitoring:9090.
+	Address string `json:"address"`
+
+	// Query is the PromQL expression to be evaluated as an instant query.
+	// It should return a scalar or a vector. The values of all the series in a vector are summed up.
+	Query string `json:"query"`
+
+	// TargetValuePerRunner is the value of the query result each runner is supposed to handle.
+	// For example, the desired replicas is 3 when the query results in 10 and the target value per runner is 4.
+	TargetValuePerRunner string `json:"targetValuePerRunner"`
+
+	// Timeout is the timeout of the query. Defaults to 10s.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
 }
 
 // ScheduledOverride can be used to override a few fields of HorizontalRunnerAutoscalerSpec on schedule.
-// A schedule can optionally be recurring, so that the correspoding override happens every day, week, month, or year.
+// A schedule can optionally be recurring, so that the correspoding override happens every day, week, month, or year,
+// or at each occurrence of a cron expression or an RRULE.
 type ScheduledOverride struct {
 	// StartTime is the time at which the first override starts.
-	StartTime metav1.Time `json:"startTime"`
+	// When Schedule is set, it's optional and no override starts before it.
+	// +optional
+	// +nullable
+	StartTime metav1.Time `json:"startTime,omitempty"`
 
 	// EndTime is the time at which the first override ends.
-	EndTime metav1.Time `json:"endTime"`
+	// It's ignored when Schedule is set.
+	// +optional
+	// +nullable
+	EndTime metav1.Time `json:"endTime,omitempty"`
+
+	// Schedule is either a standard 5-field cron expression like "0 8 * * 1-5",
+	// or an RRULE like "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR;BYHOUR=8;BYMINUTE=0;BYSECOND=0".
+	// An override of Duration starts at each occurrence.
+	// An RRULE needs either DTSTART or StartTime.
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// Duration is how long each override lasts when Schedule is set, like "11h".
+	// +optional
+	// +nullable
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// TimeZone is the IANA time zone name like "Europe/Berlin" in which Schedule and RecurrenceRule are interpreted.
+	// Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
 
 	// MinReplicas is the number of runners while overriding.
 	// If omitted, it doesn't override minReplicas.
@@ -185,6 +425,21 @@ type ScheduledOverride struct {
 	// +kubebuilder:validation:Minimum=0
 	MinReplicas *int `json:"minReplicas,omitempty"`
 
+	// MaxReplicas is the maximum number of runners while overriding.
+	// If omitted, it doesn't override maxReplicas.
+	// +optional
+	// +nullable
+	// +kubebuilder:validation:Minimum=0
+	MaxReplicas *int `json:"maxReplicas,omitempty"`
+
+	// ScaleUpThreshold overrides the scaleUpThreshold of the PercentageRunnersBusy metric while overriding.
+	// +optional
+	ScaleUpThreshold string `json:"scaleUpThreshold,omitempty"`
+
+	// ScaleDownThreshold overrides the scaleDownThreshold of the PercentageRunnersBusy metric while overriding.
+	// +optional
+	ScaleDownThreshold string `json:"scaleDownThreshold,omitempty"`
+
 	// +optional
 	RecurrenceRule RecurrenceRule `json:"recurrenceRule,omitempty"`
 }
@@ -203,6 +458,98 @@ type RecurrenceRule struct {
 	UntilTime metav1.Time `json:"untilTime,omitempty"`
 }
 
+// ScaleToZero configures draining the scale target to zero replicas while it's idle.
+type ScaleToZero struct {
+	// IdlePeriod is how long the scale target needs to have no demand before it's scaled to zero. Defaults to 10m.
+	// +optional
+	// +nullable
+	IdlePeriod *metav1.Duration `json:"idlePeriod,omitempty"`
+
+	// Placeholder registers an offline runner with the labels of the scale target while it has zero replicas,
+	// so that GitHub doesn't reject workflow jobs for the labels because no runner with them is registered.
+	// +optional
+	Placeholder bool `json:"placeholder,omitempty"`
+}
+
+// PlaceholderRunnerStatus identifies the placeholder runner registered on GitHub.
+type PlaceholderRunnerStatus struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+
+	// +optional
+	Enterprise string `json:"enterprise,omitempty"`
+
+	// +optional
+	Organization string `json:"organization,omitempty"`
+
+	// +optional
+	Repository string `json:"repository,omitempty"`
+}
+
+const (
+	// PredictiveScalingStorageStatus stores the demand history in the HorizontalRunnerAutoscaler status.
+	PredictiveScalingStorageStatus = "Status"
+
+	// PredictiveScalingStorageConfigMap stores the demand history in the ConfigMap named "<HRA name>-demand-history".
+	PredictiveScalingStorageConfigMap = "ConfigMap"
+)
+
+// PredictiveScaling records the desired and busy runners in a weekly history of hourly buckets,
+// and raises minReplicas ahead of the hours whose demand peaks recurred in the past weeks.
+type PredictiveScaling struct {
+	// Storage is where the demand history is stored, either "Status" or "ConfigMap". Defaults to "Status".
+	// +optional
+	// +kubebuilder:validation:Enum=Status;ConfigMap
+	Storage string `json:"storage,omitempty"`
+
+	// LeadTime is how long before a recurring peak minReplicas is raised. Defaults to 15m.
+	// +optional
+	// +nullable
+	LeadTime *metav1.Duration `json:"leadTime,omitempty"`
+
+	// TimeZone is the IANA time zone name like "Europe/Berlin" in which the days of week and the hours of day are bucketed.
+	// Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// MinWeeks is the number of weeks a bucket must have been observed before its peak is predicted to recur. Defaults to 2.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MinWeeks *int `json:"minWeeks,omitempty"`
+}
+
+// DemandHistoryBucket is the demand observed in an hour of a day of week.
+type DemandHistoryBucket struct {
+	// Weekday is the day of week, where 0 is Sunday.
+	Weekday int `json:"weekday"`
+
+	// Hour is the hour of day.
+	Hour int `json:"hour"`
+
+	// Weeks is the number of the past weeks folded into Desired and Busy.
+	// +optional
+	Weeks int `json:"weeks,omitempty"`
+
+	// Desired is the average of the peak desired replicas in the hour in the past weeks.
+	// +optional
+	Desired int `json:"desired,omitempty"`
+
+	// Busy is the average of the peak busy runners in the hour in the past weeks.
+	// +optional
+	Busy int `json:"busy,omitempty"`
+
+	// CurrentDesired and CurrentBusy are the peaks observed so far in the latest occurrence of the hour,
+	// which are folded into Desired and Busy once the hour is over.
+	// +optional
+	CurrentDesired int `json:"currentDesired,omitempty"`
+	// +optional
+	CurrentBusy int `json:"currentBusy,omitempty"`
+
+	// ObservedHour is the start of the latest occurrence of the hour.
+	// +optional
+	ObservedHour metav1.Time `json:"observedHour,omitempty"`
+}
+
 type HorizontalRunnerAutoscalerStatus struct {
 	// ObservedGeneration is the most recent generation observed for the target. It corresponds to e.g.
 	// RunnerDeployment's generation, which is updated on mutation by the API Server.
@@ -218,13 +565,136 @@ type HorizontalRunnerAutoscalerStatus struct {
 	// +nullable
 	LastSuccessfulScaleOutTime *metav1.Time `json:"lastSuccessfulScaleOutTime,omitempty"`
 
+	// DesiredOverflowReplicas is the number of replicas above MaxReplicas applied to OverflowTargetRef.
+	// +optional
+	DesiredOverflowReplicas *int `json:"desiredOverflowReplicas,omitempty"`
+
+	// OverflowTargetRef is the overflow target last scaled by the controller,
+	// which is scaled back to zero once it's changed in or removed from the spec.
+	// +optional
+	OverflowTargetRef *ScaleTargetRef `json:"overflowTargetRef,omitempty"`
+
 	// +optional
 	CacheEntries []CacheEntry `json:"cacheEntries,omitempty"`
 
+	// CapacityReservations is the list of the replicas temporarily added to the scale target by the webhook-based autoscaler.
+	// They're added up with the ones in the spec.
+	// +optional
+	CapacityReservations []CapacityReservation `json:"capacityReservations,omitempty"`
+
+	// CompletedJobs is the list of the workflow jobs whose reservations were removed by their completed events,
+	// so that the reservation resync doesn't add the reservations back from the job states fetched before the jobs completed.
+	// +optional
+	CompletedJobs []CompletedJob `json:"completedJobs,omitempty"`
+
 	// ScheduledOverridesSummary is the summary of active and upcoming scheduled overrides to be shown in e.g. a column of a `kubectl get hra` output
 	// for observability.
 	// +optional
 	ScheduledOverridesSummary *string `json:"scheduledOverridesSummary,omitempty"`
+
+	// LastActiveTime is the last time the scale target had demand, which is used to scale it to zero after the idle period.
+	// +optional
+	// +nullable
+	LastActiveTime *metav1.Time `json:"lastActiveTime,omitempty"`
+
+	// PlaceholderRunner is the placeholder runner registered while the scale target has zero replicas.
+	// It's unregistered once the scale target scales up, the placeholder is disabled, or the HRA is deleted.
+	// +optional
+	PlaceholderRunner *PlaceholderRunnerStatus `json:"placeholderRunner,omitempty"`
+
+	// DemandHistory is the weekly history of the demand recorded by the predictive scaling with the "Status" storage.
+	// +optional
+	DemandHistory []DemandHistoryBucket `json:"demandHistory,omitempty"`
+
+	// PredictiveSummary is the summary of minReplicas raised ahead of a recurring peak by the predictive scaling,
+	// to be shown in e.g. a column of a `kubectl get hra` output for observability.
+	// +optional
+	PredictiveSummary *string `json:"predictiveSummary,omitempty"`
+
+	// Conditions describe whether the HRA is able to scale the target, whether the desired replicas are computed
+	// from the metrics, and whether they're limited by e.g. minReplicas and maxReplicas,
+	// like the ones of HorizontalPodAutoscaler.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// LastScalingDecision is the breakdown of the latest desired replicas computed by the controller.
+	// +optional
+	LastScalingDecision *ScalingDecision `json:"lastScalingDecision,omitempty"`
+
+	// LastMetrics is the latest values observed for each of the metrics, along with the replicas suggested by them.
+	// +optional
+	LastMetrics []MetricStatus `json:"lastMetrics,omitempty"`
+
+	// LastError is the latest error the controller encountered while scaling the target.
+	// It's kept after the controller recovers from the error, so see the conditions for the current state.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// LastErrorTime is the time LastError first occurred, which isn't updated while the same error persists.
+	// +optional
+	// +nullable
+	LastErrorTime *metav1.Time `json:"lastErrorTime,omitempty"`
+}
+
+const (
+	// ConditionTypeAbleToScale indicates whether the controller is able to get and update the replicas of the scale target.
+	ConditionTypeAbleToScale = "AbleToScale"
+
+	// ConditionTypeScalingActive indicates whether the controller is able to compute the desired replicas from the metrics.
+	ConditionTypeScalingActive = "ScalingActive"
+
+	// ConditionTypeScalingLimited indicates whether the desired replicas are limited by e.g. minReplicas and maxReplicas.
+	ConditionTypeScalingLimited = "ScalingLimited"
+)
+
+// ScalingDecision is the breakdown of the desired replicas.
+type ScalingDecision struct {
+	// Suggested is the replicas suggested by the metrics, or minReplicas when no metric has an opinion.
+	Suggested int `json:"suggested"`
+
+	// Reserved is the replicas added by the valid capacity reservations.
+	Reserved int `json:"reserved"`
+
+	// MinReplicas is the effective minReplicas, which accounts for the scheduled overrides, the predictive scaling and scaling to zero.
+	MinReplicas int `json:"minReplicas"`
+
+	// MaxReplicas is the effective maxReplicas, which accounts for the scheduled overrides.
+	// +optional
+	MaxReplicas *int `json:"maxReplicas,omitempty"`
+
+	// Desired is the desired replicas after applying the limits, the scale-down delay and the behavior.
+	Desired int `json:"desired"`
+
+	// Overflow is the replicas above maxReplicas applied to the overflow target.
+	// +optional
+	Overflow *int `json:"overflow,omitempty"`
+
+	// ScaleDownDelayUntil is set while scaling down is delayed after the last scale out.
+	// +optional
+	// +nullable
+	ScaleDownDelayUntil *metav1.Time `json:"scaleDownDelayUntil,omitempty"`
+
+	// LimitedByBehaviorFrom is the desired replicas before being stabilized or rate-limited by the behavior, if they were.
+	// +optional
+	LimitedByBehaviorFrom *int `json:"limitedByBehaviorFrom,omitempty"`
+}
+
+// MetricStatus is the latest value observed for a metric.
+type MetricStatus struct {
+	// Type is the type of the metric, like PercentageRunnersBusy.
+	Type string `json:"type"`
+
+	// Value is the human-readable summary of the observed value, like "busy=3 registered=4 replicas=4".
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// SuggestedReplicas is the replicas suggested by the metric, or omitted when the metric had no opinion.
+	// +optional
+	SuggestedReplicas *int `json:"suggestedReplicas,omitempty"`
 }
 
 const CacheEntryKeyDesiredReplicas = "desiredReplicas"
@@ -241,7 +711,10 @@ type CacheEntry struct {
 // +kubebuilder:printcolumn:JSONPath=".spec.minReplicas",name=Min,type=number
 // +kubebuilder:printcolumn:JSONPath=".spec.maxReplicas",name=Max,type=number
 // +kubebuilder:printcolumn:JSONPath=".status.desiredReplicas",name=Desired,type=number
+// +kubebuilder:printcolumn:JSONPath=".status.desiredOverflowReplicas",name=Overflow,type=number,priority=1
 // +kubebuilder:printcolumn:JSONPath=".status.scheduledOverridesSummary",name=Schedule,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.predictiveSummary",name=Predicted,type=string,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.conditions[?(@.type==\"ScalingLimited\")].reason",name=Limited,type=string,priority=1
 
 // HorizontalRunnerAutoscaler is the Schema for the horizontalrunnerautoscaler API
 type HorizontalRunnerAutoscaler struct {
@@ -0,0 +1,248 @@
+/*
+Copyright 2022 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actions-runner-controller/actions-runner-controller/pkg/cron"
+	"github.com/teambition/rrule-go"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var horizontalRunnerAutoscalerLog = logf.Log.WithName("horizontalrunnerautoscaler-resource")
+
+func (r *HorizontalRunnerAutoscaler) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&horizontalRunnerAutoscalerValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-actions-summerwind-dev-v1alpha1-horizontalrunnerautoscaler,verbs=create;update,mutating=false,failurePolicy=fail,groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers,versions=v1alpha1,name=validate.horizontalrunnerautoscaler.actions.summerwind.dev,sideEffects=None,admissionReviewVersions=v1beta1
+
+// horizontalRunnerAutoscalerValidator validates HorizontalRunnerAutoscalers against the other ones in the same namespace,
+// in addition to validating their specs.
+type horizontalRunnerAutoscalerValidator struct {
+	Client client.Reader
+}
+
+var _ admission.CustomValidator = &horizontalRunnerAutoscalerValidator{}
+
+// ValidateCreate implements admission.CustomValidator so a webhook will be registered for the type
+func (v *horizontalRunnerAutoscalerValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	r, ok := obj.(*HorizontalRunnerAutoscaler)
+	if !ok {
+		return fmt.Errorf("expected a HorizontalRunnerAutoscaler but got a %T", obj)
+	}
+
+	horizontalRunnerAutoscalerLog.Info("validate resource to be created", "name", r.Name)
+
+	return v.validate(ctx, r)
+}
+
+// ValidateUpdate implements admission.CustomValidator so a webhook will be registered for the type
+func (v *horizontalRunnerAutoscalerValidator) ValidateUpdate(ctx context.Context, old, obj runtime.Object) error {
+	r, ok := obj.(*HorizontalRunnerAutoscaler)
+	if !ok {
+		return fmt.Errorf("expected a HorizontalRunnerAutoscaler but got a %T", obj)
+	}
+
+	horizontalRunnerAutoscalerLog.Info("validate resource to be updated", "name", r.Name)
+
+	return v.validate(ctx, r)
+}
+
+// ValidateDelete implements admission.CustomValidator so a webhook will be registered for the type
+func (v *horizontalRunnerAutoscalerValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func (v *horizontalRunnerAutoscalerValidator) validate(ctx context.Context, r *HorizontalRunnerAutoscaler) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	var hraList HorizontalRunnerAutoscalerList
+
+	if err := v.Client.List(ctx, &hraList, client.InNamespace(r.Namespace)); err != nil {
+		return fmt.Errorf("listing horizontalrunnerautoscalers to validate overflowTargetRef: %w", err)
+	}
+
+	if errList := validateOverflowTargetRef(r, hraList.Items); len(errList) > 0 {
+		return apierrors.NewInvalid(r.GroupVersionKind().GroupKind(), r.Name, errList)
+	}
+
+	return nil
+}
+
+// validateOverflowTargetRef rejects the overflow target scaled by the HRA itself or any other HRA,
+// as the HRA scales the overflow target to zero while the demand is within maxReplicas, which fights with the other scaling.
+// It also rejects the scale target that is the overflow target of another HRA, regardless of which HRA is created first.
+func validateOverflowTargetRef(r *HorizontalRunnerAutoscaler, others []HorizontalRunnerAutoscaler) field.ErrorList {
+	var errList field.ErrorList
+
+	ref := r.Spec.OverflowTargetRef
+	path := field.NewPath("spec", "overflowTargetRef")
+
+	if ref != nil && ref.RefersTo(r.Spec.ScaleTargetRef) {
+		errList = append(errList, field.Invalid(path, *ref, "must not be the same as scaleTargetRef"))
+	}
+
+	for _, o := range others {
+		if o.Name == r.Name {
+			continue
+		}
+
+		if ref != nil && ref.RefersTo(o.Spec.ScaleTargetRef) {
+			errList = append(errList, field.Invalid(path, *ref, fmt.Sprintf("must not be the scaleTargetRef of horizontalrunnerautoscaler %s", o.Name)))
+		}
+
+		if o.Spec.OverflowTargetRef != nil && o.Spec.OverflowTargetRef.RefersTo(r.Spec.ScaleTargetRef) {
+			errList = append(errList, field.Invalid(field.NewPath("spec", "scaleTargetRef"), r.Spec.ScaleTargetRef, fmt.Sprintf("must not be the overflowTargetRef of horizontalrunnerautoscaler %s", o.Name)))
+		}
+	}
+
+	return errList
+}
+
+// Validate validates resource spec.
+func (r *HorizontalRunnerAutoscaler) Validate() error {
+	var errList field.ErrorList
+
+	for i, t := range r.Spec.ScaleUpTriggers {
+		if t.GitHubEvent == nil || t.GitHubEvent.WorkflowJob == nil {
+			continue
+		}
+
+		wj := t.GitHubEvent.WorkflowJob
+		path := field.NewPath("spec", "scaleUpTriggers").Index(i).Child("githubEvent", "workflowJob")
+
+		errList = append(errList, validateNonEmptyPatterns(path.Child("workflowNames"), wj.WorkflowNames)...)
+		errList = append(errList, validateNonEmptyPatterns(path.Child("names"), wj.Names)...)
+		errList = append(errList, validateNonEmptyPatterns(path.Child("branches"), wj.Branches)...)
+	}
+
+	for i, m := range r.Spec.Metrics {
+		if m.Prometheus == nil {
+			continue
+		}
+
+		errList = append(errList, validateTargetValuePerRunner(field.NewPath("spec", "metrics").Index(i).Child("prometheus", "targetValuePerRunner"), m.Prometheus.TargetValuePerRunner)...)
+	}
+
+	for i, o := range r.Spec.ScheduledOverrides {
+		errList = append(errList, validateScheduledOverride(field.NewPath("spec", "scheduledOverrides").Index(i), o)...)
+	}
+
+	if len(errList) > 0 {
+		return apierrors.NewInvalid(r.GroupVersionKind().GroupKind(), r.Name, errList)
+	}
+
+	return nil
+}
+
+// validateScheduledOverride rejects the scheduled override the controller would fail to match on every reconciliation
+func validateScheduledOverride(path *field.Path, o ScheduledOverride) field.ErrorList {
+	var errList field.ErrorList
+
+	if o.TimeZone != "" {
+		if _, err := time.LoadLocation(o.TimeZone); err != nil {
+			errList = append(errList, field.Invalid(path.Child("timeZone"), o.TimeZone, err.Error()))
+		}
+	}
+
+	if o.Schedule != "" {
+		if strings.Contains(o.Schedule, "FREQ=") {
+			opt, err := rrule.StrToROption(strings.ReplaceAll(o.Schedule, "RRULE:", ""))
+			if err != nil {
+				errList = append(errList, field.Invalid(path.Child("schedule"), o.Schedule, err.Error()))
+			} else if opt.Dtstart.IsZero() && o.StartTime.IsZero() {
+				errList = append(errList, field.Invalid(path.Child("schedule"), o.Schedule, "either DTSTART or startTime must be set for an RRULE"))
+			}
+		} else if _, err := cron.Parse(o.Schedule); err != nil {
+			errList = append(errList, field.Invalid(path.Child("schedule"), o.Schedule, err.Error()))
+		}
+
+		if o.Duration == nil {
+			errList = append(errList, field.Required(path.Child("duration"), "must be set when schedule is set"))
+		} else if o.Duration.Duration <= 0 {
+			errList = append(errList, field.Invalid(path.Child("duration"), o.Duration.Duration.String(), "must be positive"))
+		}
+	}
+
+	up, upErr := validateThreshold(path.Child("scaleUpThreshold"), o.ScaleUpThreshold)
+	errList = append(errList, upErr...)
+
+	down, downErr := validateThreshold(path.Child("scaleDownThreshold"), o.ScaleDownThreshold)
+	errList = append(errList, downErr...)
+
+	if up != nil && down != nil && *up <= *down {
+		errList = append(errList, field.Invalid(path.Child("scaleUpThreshold"), o.ScaleUpThreshold, "must be greater than scaleDownThreshold"))
+	}
+
+	return errList
+}
+
+// validateThreshold returns the threshold parsed as a float, or nil when it's omitted or invalid
+func validateThreshold(path *field.Path, threshold string) (*float64, field.ErrorList) {
+	if threshold == "" {
+		return nil, nil
+	}
+
+	v, err := strconv.ParseFloat(threshold, 64)
+	if err != nil {
+		return nil, field.ErrorList{field.Invalid(path, threshold, "must be a float like \"0.75\"")}
+	}
+
+	return &v, nil
+}
+
+// validateTargetValuePerRunner rejects the target value the desired replicas can't be divided by
+func validateTargetValuePerRunner(path *field.Path, target string) field.ErrorList {
+	v, err := strconv.ParseFloat(target, 64)
+	if err != nil || v <= 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return field.ErrorList{field.Invalid(path, target, "must be a positive finite float like \"4\" or \"2.5\"")}
+	}
+
+	return nil
+}
+
+// validateNonEmptyPatterns rejects empty glob patterns, as an empty pattern matches nothing
+func validateNonEmptyPatterns(path *field.Path, patterns []string) field.ErrorList {
+	var errList field.ErrorList
+
+	for i, p := range patterns {
+		if p == "" {
+			errList = append(errList, field.Invalid(path.Index(i), p, "glob pattern must not be empty"))
+		}
+	}
+
+	return errList
+}
@@ -54,6 +54,12 @@ type RunnerConfig struct {
 	// +optional
 	Ephemeral *bool `json:"ephemeral,omitempty"`
 
+	// JITConfig registers each runner with a just-in-time runner configuration generated by the controller,
+	// which is mounted into the runner container from a per-pod Secret, instead of a registration token.
+	// The runner never holds a credential that can register other runners. Requires ephemeral runners.
+	// +optional
+	JITConfig *bool `json:"jitConfig,omitempty"`
+
 	// +optional
 	Image string `json:"image"`
 
@@ -75,6 +81,24 @@ type RunnerConfig struct {
 
 	// +optional
 	ContainerMode string `json:"containerMode,omitempty"`
+
+	// GitHubAPICredentialsFrom is the reference to the GitHub API credentials used to manage the runners,
+	// instead of the controller-wide ones.
+	// +optional
+	GitHubAPICredentialsFrom *GitHubAPICredentialsFrom `json:"githubAPICredentialsFrom,omitempty"`
+}
+
+// GitHubAPICredentialsFrom is the source of GitHub API credentials.
+type GitHubAPICredentialsFrom struct {
+	// SecretRef is the reference to the Secret in the same namespace that holds either `github_token`, or
+	// `github_app_id`, `github_app_installation_id`, and `github_app_private_key`.
+	// `github_app_installation_id` can be omitted to use the installation for the enterprise or the organization of the runners.
+	SecretRef SecretReference `json:"secretRef"`
+}
+
+// SecretReference is the reference to a Secret in the same namespace as the referrer.
+type SecretReference struct {
+	Name string `json:"name"`
 }
 
 // RunnerPodSpec defines the desired pod spec fields of the runner pod
@@ -212,6 +236,18 @@ func (rs *RunnerSpec) ValidateIsServiceAccountNameSet() error {
 	return nil
 }
 
+func (rs *RunnerSpec) ValidateJITConfig() error {
+	if rs.JITConfig == nil || !*rs.JITConfig {
+		return nil
+	}
+
+	if rs.Ephemeral != nil && !*rs.Ephemeral {
+		return errors.New("jitConfig requires ephemeral runners, as a just-in-time runner can run only one job")
+	}
+
+	return nil
+}
+
 // RunnerStatus defines the observed state of Runner
 type RunnerStatus struct {
 	// Turns true only if the runner pod is ready.
@@ -228,16 +264,58 @@ type RunnerStatus struct {
 	// +optional
 	// +nullable
 	LastRegistrationCheckTime *metav1.Time `json:"lastRegistrationCheckTime,omitempty"`
+	// GitHub is the state of the runner last seen in the GitHub API, which is synced periodically
+	// +optional
+	GitHub *RunnerGitHubStatus `json:"github,omitempty"`
+	// CurrentJob is the workflow job the runner is running, which is known only when the webhook server receives workflow_job events
+	// +optional
+	CurrentJob *RunnerCurrentJob `json:"currentJob,omitempty"`
+}
+
+const (
+	RunnerGitHubStatusOnline       = "online"
+	RunnerGitHubStatusOffline      = "offline"
+	RunnerGitHubStatusUnregistered = "unregistered"
+)
+
+// RunnerGitHubStatus is the state of the runner seen in the GitHub API
+type RunnerGitHubStatus struct {
+	// ID is the ID of the runner on GitHub
+	// +optional
+	ID int64 `json:"id,omitempty"`
+	// Status is either online, offline, or unregistered when the runner isn't registered yet or anymore
+	Status string `json:"status"`
+	// Busy is true while the runner is running a job
+	Busy bool `json:"busy"`
+	// LastTransitionTime is the last time the state seen in the GitHub API changed
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+}
+
+// RunnerCurrentJob is the workflow job assigned to the runner
+type RunnerCurrentJob struct {
+	// Repository is the owner and the name of the repository of the job, like owner/repo
+	Repository string `json:"repository"`
+	// Workflow is the name of the workflow of the job
+	// +optional
+	Workflow string `json:"workflow,omitempty"`
+	RunID    int64  `json:"runID"`
+	JobID    int64  `json:"jobID"`
+	JobName  string `json:"jobName"`
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
 }
 
-// RunnerStatusRegistration contains runner registration status
+// RunnerStatusRegistration contains runner registration status.
+// The registration token itself is stored in the Secret of the runner pod, rather than in the status.
 type RunnerStatusRegistration struct {
-	Enterprise   string      `json:"enterprise,omitempty"`
-	Organization string      `json:"organization,omitempty"`
-	Repository   string      `json:"repository,omitempty"`
-	Labels       []string    `json:"labels,omitempty"`
-	Token        string      `json:"token"`
-	ExpiresAt    metav1.Time `json:"expiresAt"`
+	Enterprise   string   `json:"enterprise,omitempty"`
+	Organization string   `json:"organization,omitempty"`
+	Repository   string   `json:"repository,omitempty"`
+	Labels       []string `json:"labels,omitempty"`
+	// TokenSecretName is the name of the Secret holding the registration token of the runner
+	// +optional
+	TokenSecretName string      `json:"tokenSecretName,omitempty"`
+	ExpiresAt       metav1.Time `json:"expiresAt"`
 }
 
 type WorkVolumeClaimTemplate struct {
@@ -292,6 +370,13 @@ func (w *WorkVolumeClaimTemplate) V1VolumeMount(mountPath string) corev1.VolumeM
 // +kubebuilder:printcolumn:JSONPath=".spec.repository",name=Repository,type=string
 // +kubebuilder:printcolumn:JSONPath=".spec.labels",name=Labels,type=string
 // +kubebuilder:printcolumn:JSONPath=".status.phase",name=Status,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.github.status",name=GitHub,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.github.busy",name=Busy,type=boolean
+// +kubebuilder:printcolumn:JSONPath=".status.currentJob.repository",name=Job Repository,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.currentJob.workflow",name=Workflow,type=string,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.currentJob.jobName",name=Job,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.currentJob.runID",name=Run ID,type=integer,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.github.id",name=Runner ID,type=integer,priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Runner is the Schema for the runners API
@@ -308,7 +393,7 @@ func (r Runner) IsRegisterable() bool {
 		return false
 	}
 
-	if r.Status.Registration.Token == "" {
+	if r.Status.Registration.TokenSecretName == "" {
 		return false
 	}
 
@@ -86,6 +86,11 @@ func (r *Runner) Validate() error {
 		errList = append(errList, field.Invalid(field.NewPath("spec", "serviceAccountName"), r.Spec.ServiceAccountName, err.Error()))
 	}
 
+	err = r.Spec.ValidateJITConfig()
+	if err != nil {
+		errList = append(errList, field.Invalid(field.NewPath("spec", "jitConfig"), r.Spec.JITConfig, err.Error()))
+	}
+
 	if len(errList) > 0 {
 		return apierrors.NewInvalid(r.GroupVersionKind().GroupKind(), r.Name, errList)
 	}
@@ -23,6 +23,7 @@ import (
 const (
 	AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns = "TotalNumberOfQueuedAndInProgressWorkflowRuns"
 	AutoscalingMetricTypePercentageRunnersBusy                        = "PercentageRunnersBusy"
+	AutoscalingMetricTypePrometheus                                   = "Prometheus"
 )
 
 // RunnerDeploymentSpec defines the desired state of RunnerDeployment
@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	WorkflowJobStatusQueued     = "queued"
+	WorkflowJobStatusInProgress = "in_progress"
+	WorkflowJobStatusCompleted  = "completed"
+)
+
+// WorkflowJobRecordSpec identifies the workflow job the record is for
+type WorkflowJobRecordSpec struct {
+	// Repository is the repository of the workflow job, in the OWNER/REPO form
+	Repository string `json:"repository"`
+
+	// +optional
+	Workflow string `json:"workflow,omitempty"`
+
+	RunID int64 `json:"runID"`
+
+	JobID int64 `json:"jobID"`
+
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+
+	// Labels is the runner labels the workflow job requested in `runs-on`
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+}
+
+// WorkflowJobRecordStatus is the lifecycle of the workflow job seen in the workflow_job webhook events.
+// The times are the created_at, started_at, and completed_at of the workflow job in the respective events,
+// or when the github webhook server received the events if they're missing.
+type WorkflowJobRecordStatus struct {
+	// Status is the last status of the workflow job. Either queued, in_progress, or completed.
+	// +optional
+	Status string `json:"status,omitempty"`
+
+	// Conclusion is the conclusion of the completed workflow job, like success, failure, cancelled, or skipped.
+	// +optional
+	Conclusion string `json:"conclusion,omitempty"`
+
+	// +optional
+	// +nullable
+	QueuedAt *metav1.Time `json:"queuedAt,omitempty"`
+
+	// +optional
+	// +nullable
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+
+	// +optional
+	// +nullable
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+
+	// QueueDuration is how long the workflow job waited for a runner, from QueuedAt to StartedAt
+	// +optional
+	QueueDuration *metav1.Duration `json:"queueDuration,omitempty"`
+
+	// Duration is how long the workflow job ran, from StartedAt to CompletedAt
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// RunnerName is the name of the runner that picked up the workflow job
+	// +optional
+	RunnerName string `json:"runnerName,omitempty"`
+
+	// RunnerRef is the Runner or the runner pod that picked up the workflow job, if it's managed by this controller
+	// +optional
+	RunnerRef *WorkflowJobRunnerRef `json:"runnerRef,omitempty"`
+}
+
+type WorkflowJobRunnerRef struct {
+	// Kind is either Runner or Pod
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:JSONPath=".spec.repository",name=Repository,type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.workflow",name=Workflow,type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.jobName",name=Job,type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.runID",name=Run ID,type=integer,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.status",name=Status,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.conclusion",name=Conclusion,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.runnerName",name=Runner,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.queueDuration",name=Queued,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.duration",name=Duration,type=string
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// WorkflowJobRecord is the Schema for the workflowjobrecords API.
+// It's created by the github webhook server per workflow job, and deleted after its TTL.
+type WorkflowJobRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WorkflowJobRecordSpec   `json:"spec,omitempty"`
+	Status WorkflowJobRecordStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WorkflowJobRecordList contains a list of WorkflowJobRecord
+type WorkflowJobRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WorkflowJobRecord `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WorkflowJobRecord{}, &WorkflowJobRecordList{})
+}
@@ -37,6 +37,7 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -73,6 +74,14 @@ func main() {
 		syncPeriod           time.Duration
 		logLevel             string
 		queueLimit           int
+		queueStore           string
+		deliveryStore        string
+		deliveryWindow       time.Duration
+		maxDeliveries        int
+		resyncInterval       time.Duration
+		selectionPolicy      string
+		jobRecordNamespace   string
+		jobRecordTTL         time.Duration
 
 		ghClient *github.Client
 	)
@@ -94,6 +103,14 @@ func main() {
 	flag.DurationVar(&syncPeriod, "sync-period", 10*time.Minute, "Determines the minimum frequency at which K8s resources managed by this controller are reconciled. When you use autoscaling, set to a lower value like 10 minute, because this corresponds to the minimum time to react on demand change")
 	flag.StringVar(&logLevel, "log-level", logging.LogLevelDebug, `The verbosity of the logging. Valid values are "debug", "info", "warn", "error". Defaults to "debug".`)
 	flag.IntVar(&queueLimit, "queue-limit", controllers.DefaultQueueLimit, `The maximum length of the scale operation queue. The scale opration is enqueued per every matching webhook event, and the server returns a 500 HTTP status when the queue was already full on enqueue attempt.`)
+	flag.StringVar(&queueStore, "queue-store", "", `Where to persist the scale operations until they're applied, so that they're replayed after a restart. Either "file:<directory>" or "configmap:<namespace>/<name>". Defaults to keep them only in memory.`)
+	flag.StringVar(&deliveryStore, "delivery-store", "", `Where to remember the X-GitHub-Delivery IDs of the processed webhook deliveries, so that duplicate deliveries are ignored. Either "memory" or "configmap:<namespace>/<name>". Use the latter to share them across replicas. Defaults to not deduplicate deliveries.`)
+	flag.DurationVar(&deliveryWindow, "delivery-window", controllers.DefaultDeliveryDeduplicationWindow, "How long each delivery ID is remembered for deduplication.")
+	flag.IntVar(&maxDeliveries, "max-deliveries", controllers.DefaultMaxDeliveries, "The maximum number of delivery IDs remembered for deduplication. The oldest ones are forgotten first.")
+	flag.DurationVar(&resyncInterval, "reservation-resync-interval", 0, "How often the capacity reservations made by workflow_job events are corrected against the queued and in-progress workflow jobs on GitHub. Requires GitHub API credentials. Set to zero to disable.")
+	flag.StringVar(&selectionPolicy, "scale-target-selection-policy", "", `How one HRA is selected when two or more HRAs match the same webhook event. Either "Specificity", "Priority", "RoundRobin", or "Spillover". When empty, the first HRA is selected for a workflow_job event, and the other events are ignored.`)
+	flag.StringVar(&jobRecordNamespace, "workflow-job-record-namespace", "", "The namespace to create WorkflowJobRecords in, that record the queue time, start time, duration, and conclusion of each workflow job seen in workflow_job events. Must be the same as -watch-namespace if it's set. Set to empty to not record workflow jobs.")
+	flag.DurationVar(&jobRecordTTL, "workflow-job-record-ttl", controllers.DefaultWorkflowJobRecordTTL, "How long a WorkflowJobRecord is kept after its workflow job completes.")
 	flag.StringVar(&webhookSecretToken, "github-webhook-secret-token", "", "The personal access token of GitHub.")
 	flag.StringVar(&c.Token, "github-token", c.Token, "The personal access token of GitHub.")
 	flag.Int64Var(&c.AppID, "github-app-id", c.AppID, "The application ID of GitHub App.")
@@ -116,6 +133,16 @@ func main() {
 		setupLog.Info(fmt.Sprintf("-github-webhook-secret-token and %s are missing or empty. Create one following https://docs.github.com/en/developers/webhooks-and-events/securing-your-webhooks and specify it via the flag or the envvar", webhookSecretTokenEnvName))
 	}
 
+	if err := controllers.ValidateScaleTargetSelectionPolicy(selectionPolicy); err != nil {
+		setupLog.Error(err, "invalid -scale-target-selection-policy")
+		os.Exit(1)
+	}
+
+	if jobRecordNamespace != "" && watchNamespace != "" && jobRecordNamespace != watchNamespace {
+		setupLog.Error(fmt.Errorf("%q is not the watched namespace %q", jobRecordNamespace, watchNamespace), "invalid -workflow-job-record-namespace")
+		os.Exit(1)
+	}
+
 	if watchNamespace == "" {
 		setupLog.Info("-watch-namespace is empty. HorizontalRunnerAutoscalers in all the namespaces are watched, cached, and considered as scale targets.")
 	} else {
@@ -131,7 +158,7 @@ func main() {
 	// Without an opt-in, runner groups with custom visibility won't be supported to save API calls
 	// That is, all runner groups managed by ARC are assumed to be visible to any repositories,
 	// which is wrong when you have one or more non-default runner groups in your organization or enterprise.
-	if len(c.Token) > 0 || (c.AppID > 0 && c.AppInstallationID > 0 && c.AppPrivateKey != "") || (len(c.BasicauthUsername) > 0 && len(c.BasicauthPassword) > 0) {
+	if len(c.Token) > 0 || (c.AppID > 0 && c.AppPrivateKey != "") || (len(c.BasicauthUsername) > 0 && len(c.BasicauthPassword) > 0) {
 		c.Log = &logger
 
 		ghClient, err = c.NewClient()
@@ -157,6 +184,48 @@ func main() {
 		os.Exit(1)
 	}
 
+	var (
+		scaleOperationStore controllers.ScaleOperationStore
+		deliveryIDStore     controllers.DeliveryStore
+		uncachedClient      client.Client
+		ghClients           *controllers.MultiGitHubClient
+	)
+
+	if queueStore != "" || deliveryStore != "" || resyncInterval > 0 {
+		// The stores and the GitHub API credentials read the API server directly, as the cache isn't started before the first replay
+		// and we don't want to cache all the configmaps and secrets in the cluster
+		uncachedClient, err = client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+		if err != nil {
+			setupLog.Error(err, "unable to create client for queue and delivery stores")
+			os.Exit(1)
+		}
+	}
+
+	if resyncInterval > 0 {
+		// The capacity reservations of the HRAs with their own GitHub API credentials are resynced with the credentials
+		ghClients = controllers.NewMultiGitHubClient(uncachedClient, c)
+	}
+
+	if queueStore != "" {
+		scaleOperationStore, err = controllers.NewScaleOperationStore(queueStore, uncachedClient)
+		if err != nil {
+			setupLog.Error(err, "unable to create queue store")
+			os.Exit(1)
+		}
+
+		setupLog.Info("Persisting scale operations", "queue-store", queueStore)
+	}
+
+	if deliveryStore != "" {
+		deliveryIDStore, err = controllers.NewDeliveryStore(deliveryStore, uncachedClient, deliveryWindow, maxDeliveries)
+		if err != nil {
+			setupLog.Error(err, "unable to create delivery store")
+			os.Exit(1)
+		}
+
+		setupLog.Info("Deduplicating webhook deliveries", "delivery-store", deliveryStore, "delivery-window", deliveryWindow)
+	}
+
 	hraGitHubWebhook := &controllers.HorizontalRunnerAutoscalerGitHubWebhook{
 		Name:           "webhookbasedautoscaler",
 		Client:         mgr.GetClient(),
@@ -166,7 +235,15 @@ func main() {
 		SecretKeyBytes: []byte(webhookSecretToken),
 		Namespace:      watchNamespace,
 		GitHubClient:   ghClient,
+		GitHubClients:  ghClients,
 		QueueLimit:     queueLimit,
+		QueueStore:     scaleOperationStore,
+		DeliveryStore:  deliveryIDStore,
+
+		ReservationResyncInterval:  resyncInterval,
+		ScaleTargetSelectionPolicy: selectionPolicy,
+		WorkflowJobRecordNamespace: jobRecordNamespace,
+		RunnerPodReader:            mgr.GetAPIReader(),
 	}
 
 	if err = hraGitHubWebhook.SetupWithManager(mgr); err != nil {
@@ -174,6 +251,21 @@ func main() {
 		os.Exit(1)
 	}
 
+	if jobRecordNamespace != "" {
+		workflowJobRecordReconciler := &controllers.WorkflowJobRecordReconciler{
+			Client: mgr.GetClient(),
+			Log:    ctrl.Log.WithName("controllers").WithName("workflowjobrecord"),
+			TTL:    jobRecordTTL,
+		}
+
+		if err = workflowJobRecordReconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "workflowjobrecord")
+			os.Exit(1)
+		}
+
+		setupLog.Info("Recording workflow jobs", "workflow-job-record-namespace", jobRecordNamespace, "workflow-job-record-ttl", jobRecordTTL)
+	}
+
 	var wg sync.WaitGroup
 
 	ctx, cancel := context.WithCancel(context.Background())
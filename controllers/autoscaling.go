@@ -9,7 +9,8 @@ import (
 	"strings"
 
 	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
-	"github.com/google/go-github/v45/github"
+	"github.com/actions-runner-controller/actions-runner-controller/github"
+	gogithub "github.com/google/go-github/v45/github"
 )
 
 const (
@@ -32,27 +33,22 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestDesiredReplicas(st scaleTa
 		// We don't default to anything since ARC 0.23.0
 		// See https://github.com/actions-runner-controller/actions-runner-controller/issues/728
 		return nil, nil
-	} else if numMetrics > 2 {
-		return nil, fmt.Errorf("too many autoscaling metrics configured: It must be 0 to 2, but got %d", numMetrics)
 	}
 
-	primaryMetric := metrics[0]
-	primaryMetricType := primaryMetric.Type
+	target := st.metricTarget()
 
-	var (
-		suggested *int
-		err       error
-	)
+	if policy := hra.Spec.MetricsPolicy; policy != "" {
+		return r.suggestReplicasByMetricsPolicy(target, hra, policy)
+	}
 
-	switch primaryMetricType {
-	case v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns:
-		suggested, err = r.suggestReplicasByQueuedAndInProgressWorkflowRuns(st, hra, &primaryMetric)
-	case v1alpha1.AutoscalingMetricTypePercentageRunnersBusy:
-		suggested, err = r.suggestReplicasByPercentageRunnersBusy(st, hra, primaryMetric)
-	default:
-		return nil, fmt.Errorf("validating autoscaling metrics: unsupported metric type %q", primaryMetric)
+	if numMetrics > 2 {
+		return nil, fmt.Errorf("too many autoscaling metrics configured: It must be 0 to 2, but got %d", numMetrics)
 	}
 
+	primaryMetric := metrics[0]
+	primaryMetricType := primaryMetric.Type
+
+	suggested, err := r.suggestReplicasByMetric(target, hra, primaryMetric)
 	if err != nil {
 		return nil, err
 	}
@@ -76,20 +72,20 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestDesiredReplicas(st scaleTa
 		fallbackMetricType != v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns {
 
 		return nil, fmt.Errorf(
-			"invalid HRA Spec: Metrics[0] of %s cannot be combined with Metrics[1] of %s: The only allowed combination is 0=PercentageRunnersBusy and 1=TotalNumberOfQueuedAndInProgressWorkflowRuns",
+			"invalid HRA Spec: Metrics[0] of %s cannot be combined with Metrics[1] of %s: The only allowed combination is 0=PercentageRunnersBusy and 1=TotalNumberOfQueuedAndInProgressWorkflowRuns. Set spec.metricsPolicy to combine other metrics",
 			primaryMetricType, fallbackMetricType,
 		)
 	}
 
-	return r.suggestReplicasByQueuedAndInProgressWorkflowRuns(st, hra, &fallbackMetric)
+	return r.suggestReplicasByMetric(target, hra, fallbackMetric)
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgressWorkflowRuns(st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics *v1alpha1.MetricSpec) (*int, error) {
+func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgressWorkflowRuns(ctx context.Context, st MetricTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics *v1alpha1.MetricSpec) (*int, error) {
 
 	var repos [][]string
-	repoID := st.repo
+	repoID := st.Repository
 	if repoID == "" {
-		orgName := st.org
+		orgName := st.Organization
 		if orgName == "" {
 			return nil, fmt.Errorf("asserting runner deployment spec to detect bug: spec.template.organization should not be empty on this code path")
 		}
@@ -101,6 +97,10 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgr
 			return nil, nil
 		}
 
+		if len(metrics.RepositoryNames) == 0 && metrics.DiscoverRepositories {
+			return r.suggestReplicasByOrganizationWorkflowRuns(ctx, st, hra, *metrics)
+		}
+
 		if len(metrics.RepositoryNames) == 0 {
 			return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].repositoryNames is required and must have one more more entries for organizational runner deployment")
 		}
@@ -114,118 +114,120 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByQueuedAndInProgr
 		repos = append(repos, repo)
 	}
 
-	var total, inProgress, queued, completed, unknown int
-	type callback func()
-	listWorkflowJobs := func(user string, repoName string, runID int64, fallback_cb callback) {
-		if runID == 0 {
-			fallback_cb()
-			return
-		}
-		opt := github.ListWorkflowJobsOptions{ListOptions: github.ListOptions{PerPage: 50}}
-		var allJobs []*github.WorkflowJob
-		for {
-			jobs, resp, err := r.GitHubClient.Actions.ListWorkflowJobs(context.TODO(), user, repoName, runID, &opt)
-			if err != nil {
-				r.Log.Error(err, "Error listing workflow jobs")
-				return //err
-			}
-			allJobs = append(allJobs, jobs.Jobs...)
-			if resp.NextPage == 0 {
-				break
-			}
-			opt.Page = resp.NextPage
-		}
-		if len(allJobs) == 0 {
-			fallback_cb()
-		} else {
-		JOB:
-			for _, job := range allJobs {
-				runnerLabels := make(map[string]struct{}, len(st.labels))
-				for _, l := range st.labels {
-					runnerLabels[l] = struct{}{}
-				}
-
-				if len(job.Labels) == 0 {
-					// This shouldn't usually happen
-					r.Log.Info("Detected job with no labels, which is not supported by ARC. Skipping anyway.", "labels", job.Labels, "run_id", job.GetRunID(), "job_id", job.GetID())
-					continue JOB
-				}
-
-				for _, l := range job.Labels {
-					if l == "self-hosted" {
-						continue
-					}
-
-					if _, ok := runnerLabels[l]; !ok {
-						continue JOB
-					}
-				}
-
-				switch job.GetStatus() {
-				case "completed":
-					// We add a case for `completed` so it is not counted in `unknown`.
-					// And we do not increment the counter for completed because
-					// that counter only refers to workflows. The reason for
-					// this is because we do not get a list of jobs for
-					// completed workflows in order to keep the number of API
-					// calls to a minimum.
-				case "in_progress":
-					inProgress++
-				case "queued":
-					queued++
-				default:
-					unknown++
-				}
-			}
-		}
-	}
+	var total, inProgress, queued, unknown int
+
+	jobStates := r.jobStateCache(st.GitHubClient)
 
 	for _, repo := range repos {
 		user, repoName := repo[0], repo[1]
-		workflowRuns, err := r.GitHubClient.ListRepositoryWorkflowRuns(context.TODO(), user, repoName)
+		state, err := jobStates.Get(ctx, user, repoName)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, run := range workflowRuns {
-			total++
+		t, i, q, u := r.countWorkflowRuns(st, state.Runs)
+		total += t
+		inProgress += i
+		queued += q
+		unknown += u
+	}
+
+	necessaryReplicas := queued + inProgress
+
+	setMetricValue(ctx, "queued=%d in_progress=%d unknown=%d", queued, inProgress, unknown)
+
+	r.Log.V(1).Info(
+		fmt.Sprintf("Suggested desired replicas of %d by TotalNumberOfQueuedAndInProgressWorkflowRuns", necessaryReplicas),
+		"workflow_runs_in_progress", inProgress,
+		"workflow_runs_queued", queued,
+		"workflow_runs_unknown", unknown,
+		"namespace", hra.Namespace,
+		"kind", st.Kind,
+		"name", st.Name,
+		"horizontal_runner_autoscaler", hra.Name,
+	)
+
+	return &necessaryReplicas, nil
+}
 
+// countWorkflowRuns counts the in_progress, queued, and unknown jobs of the workflow runs that can be run by the scale target's runners.
+// A run whose jobs are not available yet, or couldn't be listed, is counted as a single job of the run's status,
+// so that a failure to list jobs doesn't hide the demand of the run.
+func (r *HorizontalRunnerAutoscalerReconciler) countWorkflowRuns(st MetricTarget, runs []github.WorkflowRunState) (total, inProgress, queued, unknown int) {
+	for _, run := range runs {
+		total++
+
+		if !run.JobsListed || len(run.Jobs) == 0 {
 			// In May 2020, there are only 3 statuses.
 			// Follow the below links for more details:
 			// - https://developer.github.com/v3/actions/workflow-runs/#list-repository-workflow-runs
 			// - https://developer.github.com/v3/checks/runs/#create-a-check-run
-			switch run.GetStatus() {
-			case "completed":
-				completed++
+			switch run.Status {
 			case "in_progress":
-				listWorkflowJobs(user, repoName, run.GetID(), func() { inProgress++ })
+				inProgress++
 			case "queued":
-				listWorkflowJobs(user, repoName, run.GetID(), func() { queued++ })
+				queued++
 			default:
 				unknown++
 			}
+			continue
 		}
+
+		i, q, u := r.countWorkflowJobs(st, run.Jobs)
+		inProgress += i
+		queued += q
+		unknown += u
 	}
 
-	necessaryReplicas := queued + inProgress
+	return total, inProgress, queued, unknown
+}
 
-	r.Log.V(1).Info(
-		fmt.Sprintf("Suggested desired replicas of %d by TotalNumberOfQueuedAndInProgressWorkflowRuns", necessaryReplicas),
-		"workflow_runs_completed", completed,
-		"workflow_runs_in_progress", inProgress,
-		"workflow_runs_queued", queued,
-		"workflow_runs_unknown", unknown,
-		"namespace", hra.Namespace,
-		"kind", st.kind,
-		"name", st.st,
-		"horizontal_runner_autoscaler", hra.Name,
-	)
+// countWorkflowJobs counts the in_progress, queued, and unknown jobs that can be run by the scale target's runners.
+func (r *HorizontalRunnerAutoscalerReconciler) countWorkflowJobs(st MetricTarget, jobs []*gogithub.WorkflowJob) (inProgress, queued, unknown int) {
+	runnerLabels := make(map[string]struct{}, len(st.Labels))
+	for _, l := range st.Labels {
+		runnerLabels[l] = struct{}{}
+	}
 
-	return &necessaryReplicas, nil
+JOB:
+	for _, job := range jobs {
+		if len(job.Labels) == 0 {
+			// This shouldn't usually happen
+			r.Log.Info("Detected job with no labels, which is not supported by ARC. Skipping anyway.", "labels", job.Labels, "run_id", job.GetRunID(), "job_id", job.GetID())
+			continue JOB
+		}
+
+		for _, l := range job.Labels {
+			if l == "self-hosted" {
+				continue
+			}
+
+			if _, ok := runnerLabels[l]; !ok {
+				continue JOB
+			}
+		}
+
+		switch job.GetStatus() {
+		case "completed":
+			// We add a case for `completed` so it is not counted in `unknown`.
+			// And we do not increment the counter for completed because
+			// that counter only refers to workflows. The reason for
+			// this is because we do not get a list of jobs for
+			// completed workflows in order to keep the number of API
+			// calls to a minimum.
+		case "in_progress":
+			inProgress++
+		case "queued":
+			queued++
+		default:
+			unknown++
+		}
+	}
+
+	return inProgress, queued, unknown
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunnersBusy(st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics v1alpha1.MetricSpec) (*int, error) {
-	ctx := context.Background()
+func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunnersBusy(ctx context.Context, st MetricTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics v1alpha1.MetricSpec) (*int, error) {
 	scaleUpThreshold := defaultScaleUpThreshold
 	scaleDownThreshold := defaultScaleDownThreshold
 	scaleUpFactor := defaultScaleUpFactor
@@ -281,19 +283,19 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunner
 		scaleDownFactor = sdf
 	}
 
-	runnerMap, err := st.getRunnerMap()
+	runnerMap, err := st.GetRunnerMap()
 	if err != nil {
 		return nil, err
 	}
 
 	var (
-		enterprise   = st.enterprise
-		organization = st.org
-		repository   = st.repo
+		enterprise   = st.Enterprise
+		organization = st.Organization
+		repository   = st.Repository
 	)
 
 	// ListRunners will return all runners managed by GitHub - not restricted to ns
-	runners, err := r.GitHubClient.ListRunners(
+	runners, err := r.githubClient(st).ListRunners(
 		ctx,
 		enterprise,
 		organization,
@@ -304,7 +306,7 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunner
 
 	var desiredReplicasBefore int
 
-	if v := st.replicas; v == nil {
+	if v := st.Replicas; v == nil {
 		desiredReplicasBefore = 1
 	} else {
 		desiredReplicasBefore = *v
@@ -343,9 +345,11 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunner
 			desiredReplicas = int(float64(desiredReplicasBefore) * scaleDownFactor)
 		}
 	} else {
-		desiredReplicas = *st.replicas
+		desiredReplicas = *st.Replicas
 	}
 
+	setMetricValue(ctx, "busy=%d registered=%d runners=%d replicas=%d", numRunnersBusy, numRunnersRegistered, numRunners, desiredReplicasBefore)
+
 	// NOTES for operators:
 	//
 	// - num_runners can be as twice as large as replicas_desired_before while
@@ -359,8 +363,8 @@ func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPercentageRunner
 		"num_runners_registered", numRunnersRegistered,
 		"num_runners_busy", numRunnersBusy,
 		"namespace", hra.Namespace,
-		"kind", st.kind,
-		"name", st.st,
+		"kind", st.Kind,
+		"name", st.Name,
 		"horizontal_runner_autoscaler", hra.Name,
 		"enterprise", enterprise,
 		"organization", organization,
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/actions-runner-controller/actions-runner-controller/github"
+	"github.com/actions-runner-controller/actions-runner-controller/github/ratelimit"
+)
+
+// MetricTarget is the scale target of a HorizontalRunnerAutoscaler as seen by a MetricProvider.
+type MetricTarget struct {
+	// Name and Kind of the scale target, like "example-runners" and "runnerdeployment"
+	Name, Kind string
+
+	Enterprise, Organization, Repository string
+
+	// Replicas is the current desired replicas of the scale target, or nil if it isn't set yet
+	Replicas *int
+
+	// Labels is the list of runner labels of the scale target
+	Labels []string
+
+	// GetRunnerMap returns the set of runner names managed by the scale target
+	GetRunnerMap func() (map[string]struct{}, error)
+
+	// GitHubClient is the client for the GitHub API credentials of the HRA or the scale target.
+	// It's nil when the controller-wide credentials should be used.
+	GitHubClient *github.Client
+
+	// metricStatuses collects the values observed for the metrics to be shown in the HRA status, if not nil
+	metricStatuses *[]v1alpha1.MetricStatus
+}
+
+// MetricProvider suggests the desired replicas of a scale target based on a single HRA metric.
+// A nil suggestion means that the provider has no opinion, so that the suggestion is skipped
+// when combining multiple metrics.
+type MetricProvider interface {
+	SuggestReplicas(ctx context.Context, target MetricTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metric v1alpha1.MetricSpec) (*int, error)
+}
+
+// MetricProviderFunc is an adapter to allow the use of ordinary functions as MetricProviders.
+type MetricProviderFunc func(ctx context.Context, target MetricTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metric v1alpha1.MetricSpec) (*int, error)
+
+func (f MetricProviderFunc) SuggestReplicas(ctx context.Context, target MetricTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metric v1alpha1.MetricSpec) (*int, error) {
+	return f(ctx, target, hra, metric)
+}
+
+// MetricProviderRegistry holds MetricProviders keyed by the MetricSpec type they serve.
+type MetricProviderRegistry struct {
+	mu        sync.RWMutex
+	providers map[string]MetricProvider
+}
+
+func NewMetricProviderRegistry() *MetricProviderRegistry {
+	return &MetricProviderRegistry{
+		providers: map[string]MetricProvider{},
+	}
+}
+
+// Register adds the provider for the metric type. It fails when the type already has a provider,
+// so that a custom provider never silently replaces a built-in one.
+func (r *MetricProviderRegistry) Register(metricType string, provider MetricProvider) error {
+	if metricType == "" {
+		return fmt.Errorf("registering metric provider: metric type must not be empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.providers[metricType]; ok {
+		return fmt.Errorf("registering metric provider: metric type %q is already registered", metricType)
+	}
+
+	r.providers[metricType] = provider
+
+	return nil
+}
+
+// Lookup returns the provider for the metric type, if any.
+func (r *MetricProviderRegistry) Lookup(metricType string) (MetricProvider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.providers[metricType]
+
+	return p, ok
+}
+
+// metricProviders returns the registry of the reconciler populated with the built-in metric providers.
+func (r *HorizontalRunnerAutoscalerReconciler) metricProviders() (*MetricProviderRegistry, error) {
+	r.metricProvidersInit.Do(func() {
+		if r.MetricProviders == nil {
+			r.MetricProviders = NewMetricProviderRegistry()
+		}
+
+		builtins := map[string]MetricProviderFunc{
+			v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns: func(ctx context.Context, target MetricTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metric v1alpha1.MetricSpec) (*int, error) {
+				return r.suggestReplicasByQueuedAndInProgressWorkflowRuns(ctx, target, hra, &metric)
+			},
+			v1alpha1.AutoscalingMetricTypePercentageRunnersBusy: func(ctx context.Context, target MetricTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metric v1alpha1.MetricSpec) (*int, error) {
+				return r.suggestReplicasByPercentageRunnersBusy(ctx, target, hra, metric)
+			},
+			v1alpha1.AutoscalingMetricTypePrometheus: func(ctx context.Context, target MetricTarget, _ v1alpha1.HorizontalRunnerAutoscaler, metric v1alpha1.MetricSpec) (*int, error) {
+				return r.suggestReplicasByPrometheus(ctx, target, metric)
+			},
+		}
+
+		for tpe, p := range builtins {
+			if err := r.MetricProviders.Register(tpe, p); err != nil {
+				r.metricProvidersErr = err
+				return
+			}
+		}
+	})
+
+	return r.MetricProviders, r.metricProvidersErr
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByMetric(target MetricTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metric v1alpha1.MetricSpec) (*int, error) {
+	registry, err := r.metricProviders()
+	if err != nil {
+		return nil, err
+	}
+
+	p, ok := registry.Lookup(metric.Type)
+	if !ok {
+		return nil, fmt.Errorf("validating autoscaling metrics: unsupported metric type %q", metric.Type)
+	}
+
+	// Metric polling is the first thing to be skipped when we're running out of the GitHub API rate limit
+	ctx := ratelimit.WithPriority(context.TODO(), ratelimit.PriorityLow)
+
+	var value string
+
+	suggested, err := p.SuggestReplicas(withMetricValue(ctx, &value), target, hra, metric)
+	if err != nil {
+		return nil, err
+	}
+
+	target.recordMetricStatus(metric.Type, value, suggested)
+
+	return suggested, nil
+}
+
+// suggestReplicasByMetricsPolicy computes suggested replicas of all the metrics and combines them according to the policy.
+func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByMetricsPolicy(target MetricTarget, hra v1alpha1.HorizontalRunnerAutoscaler, policy string) (*int, error) {
+	switch policy {
+	case v1alpha1.MetricsPolicyMax, v1alpha1.MetricsPolicyMin, v1alpha1.MetricsPolicyFirstNonNil:
+	default:
+		return nil, fmt.Errorf("validating autoscaling metrics: unsupported metrics policy %q", policy)
+	}
+
+	var suggested *int
+
+	for i, m := range hra.Spec.Metrics {
+		v, err := r.suggestReplicasByMetric(target, hra, m)
+		if err != nil {
+			return nil, fmt.Errorf("computing suggested replicas by metrics[%d] of type %s: %w", i, m.Type, err)
+		}
+
+		if v == nil {
+			continue
+		}
+
+		if policy == v1alpha1.MetricsPolicyFirstNonNil {
+			return v, nil
+		}
+
+		if suggested == nil ||
+			(policy == v1alpha1.MetricsPolicyMax && *v > *suggested) ||
+			(policy == v1alpha1.MetricsPolicyMin && *v < *suggested) {
+			suggested = v
+		}
+	}
+
+	return suggested, nil
+}
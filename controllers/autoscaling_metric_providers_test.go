@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestMetricProviderRegistry(t *testing.T) {
+	r := NewMetricProviderRegistry()
+
+	p := MetricProviderFunc(func(_ context.Context, _ MetricTarget, _ v1alpha1.HorizontalRunnerAutoscaler, _ v1alpha1.MetricSpec) (*int, error) {
+		return nil, nil
+	})
+
+	if err := r.Register("Custom", p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := r.Register("Custom", p); err == nil {
+		t.Fatalf("expected error on duplicate registration, got none")
+	}
+
+	if err := r.Register("", p); err == nil {
+		t.Fatalf("expected error on empty metric type, got none")
+	}
+
+	if _, ok := r.Lookup("Custom"); !ok {
+		t.Errorf("expected the provider to be found")
+	}
+
+	if _, ok := r.Lookup("Unknown"); ok {
+		t.Errorf("expected the provider not to be found")
+	}
+}
+
+func TestSuggestDesiredReplicas_MetricsPolicy(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	constant := func(v *int) MetricProviderFunc {
+		return func(_ context.Context, _ MetricTarget, _ v1alpha1.HorizontalRunnerAutoscaler, _ v1alpha1.MetricSpec) (*int, error) {
+			return v, nil
+		}
+	}
+
+	failing := MetricProviderFunc(func(_ context.Context, _ MetricTarget, _ v1alpha1.HorizontalRunnerAutoscaler, _ v1alpha1.MetricSpec) (*int, error) {
+		return nil, errors.New("boom")
+	})
+
+	testcases := []struct {
+		description string
+		policy      string
+		metrics     []string
+		want        *int
+		err         string
+	}{
+		{
+			description: "max",
+			policy:      v1alpha1.MetricsPolicyMax,
+			metrics:     []string{"Three", "Nil", "Five", "One"},
+			want:        intPtr(5),
+		},
+		{
+			description: "min",
+			policy:      v1alpha1.MetricsPolicyMin,
+			metrics:     []string{"Three", "Nil", "Five", "One"},
+			want:        intPtr(1),
+		},
+		{
+			description: "first non-nil",
+			policy:      v1alpha1.MetricsPolicyFirstNonNil,
+			metrics:     []string{"Nil", "Zero", "Five"},
+			want:        intPtr(0),
+		},
+		{
+			description: "all nil",
+			policy:      v1alpha1.MetricsPolicyMax,
+			metrics:     []string{"Nil", "Nil"},
+			want:        nil,
+		},
+		{
+			description: "unknown policy",
+			policy:      "Avg",
+			metrics:     []string{"One"},
+			err:         `validating autoscaling metrics: unsupported metrics policy "Avg"`,
+		},
+		{
+			description: "unknown metric type",
+			policy:      v1alpha1.MetricsPolicyMax,
+			metrics:     []string{"One", "Unknown"},
+			err:         `computing suggested replicas by metrics[1] of type Unknown: validating autoscaling metrics: unsupported metric type "Unknown"`,
+		},
+		{
+			description: "failing metric",
+			policy:      v1alpha1.MetricsPolicyMin,
+			metrics:     []string{"Failing"},
+			err:         `computing suggested replicas by metrics[0] of type Failing: boom`,
+		},
+		{
+			description: "legacy policy rejects custom combination",
+			policy:      "",
+			metrics:     []string{"Zero", "Five"},
+			err:         "invalid HRA Spec: Metrics[0] of Zero cannot be combined with Metrics[1] of Five: The only allowed combination is 0=PercentageRunnersBusy and 1=TotalNumberOfQueuedAndInProgressWorkflowRuns. Set spec.metricsPolicy to combine other metrics",
+		},
+		{
+			description: "legacy policy accepts single custom metric",
+			policy:      "",
+			metrics:     []string{"Three"},
+			want:        intPtr(3),
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.description, func(t *testing.T) {
+			registry := NewMetricProviderRegistry()
+			for tpe, p := range map[string]MetricProvider{
+				"Nil":     constant(nil),
+				"Zero":    constant(intPtr(0)),
+				"One":     constant(intPtr(1)),
+				"Three":   constant(intPtr(3)),
+				"Five":    constant(intPtr(5)),
+				"Failing": failing,
+			} {
+				if err := registry.Register(tpe, p); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Log:             zap.New(zap.UseDevMode(true)),
+				MetricProviders: registry,
+			}
+
+			var metrics []v1alpha1.MetricSpec
+			for _, m := range tc.metrics {
+				metrics = append(metrics, v1alpha1.MetricSpec{Type: m})
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MinReplicas:   intPtr(0),
+					MaxReplicas:   intPtr(10),
+					Metrics:       metrics,
+					MetricsPolicy: tc.policy,
+				},
+			}
+
+			got, err := h.suggestDesiredReplicas(scaleTarget{}, hra)
+			if err != nil {
+				if tc.err == "" {
+					t.Fatalf("unexpected error: expected none, got %v", err)
+				} else if err.Error() != tc.err {
+					t.Fatalf("unexpected error: expected %v, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if tc.err != "" {
+				t.Fatalf("expected error %q, got none", tc.err)
+			}
+
+			if tc.want == nil {
+				if got != nil {
+					t.Fatalf("expected nil, got %d", *got)
+				}
+				return
+			}
+
+			if got == nil || *got != *tc.want {
+				t.Fatalf("expected %d, got %v", *tc.want, got)
+			}
+
+			if _, ok := registry.Lookup(v1alpha1.AutoscalingMetricTypePercentageRunnersBusy); !ok {
+				t.Errorf("expected built-in metric providers to be registered")
+			}
+		})
+	}
+}
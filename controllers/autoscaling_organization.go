@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/actions-runner-controller/actions-runner-controller/github"
+)
+
+const (
+	defaultMaxRepositoriesPerSync = 30
+
+	// defaultOrganizationWorkflowRunsCacheDuration is used to share organization-wide workflow runs among HRAs
+	// when the reconciler has no CacheDuration configured.
+	defaultOrganizationWorkflowRunsCacheDuration = 30 * time.Second
+)
+
+// organizationWorkflowRuns is a snapshot of the queued and in_progress workflow runs
+// in the active repositories of an organization.
+type organizationWorkflowRuns struct {
+	repositories []string
+	runs         []github.WorkflowRunState
+	fetchedAt    time.Time
+}
+
+type organizationWorkflowRunsCacheEntry struct {
+	mu        sync.Mutex
+	snapshot  *organizationWorkflowRuns
+	expiresAt time.Time
+}
+
+// organizationWorkflowRunsCache shares organization-wide workflow runs among all the HRAs
+// that target the same organization, so that each HRA doesn't repeat the same API walk.
+type organizationWorkflowRunsCache struct {
+	mu      sync.Mutex
+	entries map[string]*organizationWorkflowRunsCacheEntry
+}
+
+func (c *organizationWorkflowRunsCache) entry(key string) *organizationWorkflowRunsCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[string]*organizationWorkflowRunsCacheEntry{}
+	}
+
+	e, ok := c.entries[key]
+	if !ok {
+		e = &organizationWorkflowRunsCacheEntry{}
+		c.entries[key] = e
+	}
+
+	return e
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByOrganizationWorkflowRuns(ctx context.Context, st MetricTarget, hra v1alpha1.HorizontalRunnerAutoscaler, metrics v1alpha1.MetricSpec) (*int, error) {
+	maxRepos := metrics.MaxRepositoriesPerSync
+	if maxRepos < 0 {
+		return nil, fmt.Errorf("validating autoscaling metrics: spec.autoscaling.metrics[].maxRepositoriesPerSync cannot be lower than 0")
+	} else if maxRepos == 0 {
+		maxRepos = defaultMaxRepositoriesPerSync
+	}
+
+	snapshot, err := r.getOrganizationWorkflowRuns(ctx, r.githubClient(st), st.Organization, maxRepos)
+	if err != nil {
+		return nil, err
+	}
+
+	total, inProgress, queued, unknown := r.countWorkflowRuns(st, snapshot.runs)
+
+	necessaryReplicas := queued + inProgress
+
+	setMetricValue(ctx, "queued=%d in_progress=%d unknown=%d repositories=%d", queued, inProgress, unknown, len(snapshot.repositories))
+
+	r.Log.V(1).Info(
+		fmt.Sprintf("Suggested desired replicas of %d by TotalNumberOfQueuedAndInProgressWorkflowRuns across the organization", necessaryReplicas),
+		"organization", st.Organization,
+		"repositories_scanned", len(snapshot.repositories),
+		"workflow_runs", total,
+		"workflow_runs_in_progress", inProgress,
+		"workflow_runs_queued", queued,
+		"workflow_runs_unknown", unknown,
+		"fetched_at", snapshot.fetchedAt,
+		"namespace", hra.Namespace,
+		"kind", st.Kind,
+		"name", st.Name,
+		"horizontal_runner_autoscaler", hra.Name,
+	)
+
+	return &necessaryReplicas, nil
+}
+
+// getOrganizationWorkflowRuns returns the cached snapshot of workflow runs of the organization,
+// or fetches it when it's expired. Concurrent callers for the same organization wait for the single fetch.
+func (r *HorizontalRunnerAutoscalerReconciler) getOrganizationWorkflowRuns(ctx context.Context, ghc *github.Client, org string, maxRepos int) (*organizationWorkflowRuns, error) {
+	// The snapshot is shared only among HRAs using the same credentials, as they may see different repositories
+	e := r.organizationWorkflowRuns.entry(fmt.Sprintf("%s/%d/%p", org, maxRepos, ghc))
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+
+	if e.snapshot != nil && now.Before(e.expiresAt) {
+		return e.snapshot, nil
+	}
+
+	snapshot, err := r.fetchOrganizationWorkflowRuns(ctx, ghc, org, maxRepos)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheDuration := r.CacheDuration
+	if cacheDuration <= 0 {
+		cacheDuration = defaultOrganizationWorkflowRunsCacheDuration
+	}
+
+	e.snapshot = snapshot
+	e.expiresAt = now.Add(cacheDuration)
+
+	return snapshot, nil
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) fetchOrganizationWorkflowRuns(ctx context.Context, ghc *github.Client, org string, maxRepos int) (*organizationWorkflowRuns, error) {
+	repos, err := ghc.ListActiveOrganizationRepositories(ctx, org, maxRepos)
+	if err != nil {
+		return nil, fmt.Errorf("discovering repositories of organization %s: %w", org, err)
+	}
+
+	snapshot := &organizationWorkflowRuns{
+		fetchedAt: time.Now(),
+	}
+
+	jobStates := r.jobStateCache(ghc)
+
+	for _, repo := range repos {
+		repoName := repo.GetName()
+
+		snapshot.repositories = append(snapshot.repositories, repoName)
+
+		state, err := jobStates.Get(ctx, org, repoName)
+		if err != nil {
+			// A single repository with e.g. Actions disabled shouldn't prevent the whole organization from being scaled.
+			r.Log.Error(err, "Error listing workflow runs. Skipping the repository", "organization", org, "repository", repoName)
+			continue
+		}
+
+		snapshot.runs = append(snapshot.runs, state.Runs...)
+	}
+
+	return snapshot, nil
+}
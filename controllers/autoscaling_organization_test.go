@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestSuggestReplicasByOrganizationWorkflowRuns(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests = map[string]int{}
+	)
+
+	emptyRuns := `{"total_count": 0, "workflow_runs": []}`
+
+	routes := map[string]map[string]string{
+		"/orgs/test/repos": {
+			"": `[
+  {"name": "repo-a", "pushed_at": "2022-08-01T00:00:03Z"},
+  {"name": "repo-b", "pushed_at": "2022-08-01T00:00:02Z", "archived": true},
+  {"name": "repo-c", "pushed_at": "2022-08-01T00:00:01Z"},
+  {"name": "repo-d", "pushed_at": "2022-08-01T00:00:00Z"}
+]`,
+		},
+		"/repos/test/repo-a/actions/runs": {
+			"queued":      `{"total_count": 1, "workflow_runs": [{"id": 1, "status": "queued"}]}`,
+			"in_progress": emptyRuns,
+		},
+		"/repos/test/repo-a/actions/runs/1/jobs": {
+			"": `{"total_count": 3, "jobs": [
+  {"id": 11, "run_id": 1, "status": "queued", "labels": ["self-hosted", "linux"]},
+  {"id": 12, "run_id": 1, "status": "in_progress", "labels": ["self-hosted", "linux"]},
+  {"id": 13, "run_id": 1, "status": "queued", "labels": ["self-hosted", "gpu"]}
+]}`,
+		},
+		"/repos/test/repo-c/actions/runs": {
+			"queued":      emptyRuns,
+			"in_progress": `{"total_count": 1, "workflow_runs": [{"id": 2, "status": "in_progress"}]}`,
+		},
+		"/repos/test/repo-c/actions/runs/2/jobs": {
+			"": `{"total_count": 0, "jobs": []}`,
+		},
+		"/repos/test/repo-d/actions/runs": {
+			"queued":      `{"total_count": 1, "workflow_runs": [{"id": 3, "status": "queued"}]}`,
+			"in_progress": emptyRuns,
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		requests[req.URL.Path]++
+		mu.Unlock()
+
+		bodies, ok := routes[req.URL.Path]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+
+		body, ok := bodies[req.URL.Query().Get("status")]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	h := &HorizontalRunnerAutoscalerReconciler{
+		Log:          zap.New(zap.UseDevMode(true)),
+		GitHubClient: newGithubClient(server),
+	}
+
+	metric := v1alpha1.MetricSpec{
+		Type:                   v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+		DiscoverRepositories:   true,
+		MaxRepositoriesPerSync: 2,
+	}
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			Metrics: []v1alpha1.MetricSpec{metric},
+		},
+	}
+
+	testcases := []struct {
+		labels []string
+		want   int
+	}{
+		// 1 queued and 1 in_progress job in repo-a, plus the in_progress run without jobs in repo-c.
+		// repo-b is archived and repo-d is beyond maxRepositoriesPerSync in this sync.
+		{labels: []string{"linux"}, want: 3},
+		// 1 queued job in repo-a, plus the in_progress run without jobs in repo-c.
+		{labels: []string{"gpu"}, want: 2},
+	}
+
+	for _, tc := range testcases {
+		target := MetricTarget{
+			Name:         "example-runnerdeploy",
+			Kind:         "runnerdeployment",
+			Organization: "test",
+			Labels:       tc.labels,
+		}
+
+		got, err := h.suggestReplicasByMetric(target, hra, metric)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got == nil || *got != tc.want {
+			t.Errorf("labels %v: expected %d, got %v", tc.labels, tc.want, got)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Once for the most recently pushed repositories, and once for the rotated ones
+	if n := requests["/orgs/test/repos"]; n != 2 {
+		t.Errorf("expected the organization to be walked only once for all the HRAs, but repositories were listed %d times", n)
+	}
+
+	if n := requests["/repos/test/repo-a/actions/runs/1/jobs"]; n != 1 {
+		t.Errorf("expected jobs to be listed only once for all the HRAs, but got %d times", n)
+	}
+
+	if n := requests["/repos/test/repo-d/actions/runs"]; n != 0 {
+		t.Errorf("expected repositories beyond maxRepositoriesPerSync not to be scanned, but got %d requests", n)
+	}
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+)
+
+const (
+	defaultPrometheusQueryTimeout = 10 * time.Second
+
+	// maxPrometheusResponseBytes caps the size of the query response we read,
+	// so that a misconfigured query returning a huge vector can't exhaust the controller's memory.
+	maxPrometheusResponseBytes = 10 << 20
+)
+
+// prometheusQueryResponse is the subset of the Prometheus HTTP API response for instant queries
+// that we need to compute the metric.
+// See https://prometheus.io/docs/prometheus/latest/querying/api/#instant-queries
+type prometheusQueryResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) suggestReplicasByPrometheus(ctx context.Context, st MetricTarget, metric v1alpha1.MetricSpec) (*int, error) {
+	spec := metric.Prometheus
+	if spec == nil {
+		return nil, errors.New("validating autoscaling metrics: spec.metrics[].prometheus is required for the Prometheus metric type")
+	}
+
+	if spec.Address == "" {
+		return nil, errors.New("validating autoscaling metrics: spec.metrics[].prometheus.address is required")
+	}
+
+	if spec.Query == "" {
+		return nil, errors.New("validating autoscaling metrics: spec.metrics[].prometheus.query is required")
+	}
+
+	targetValuePerRunner, err := strconv.ParseFloat(spec.TargetValuePerRunner, 64)
+	if err != nil {
+		return nil, fmt.Errorf("validating autoscaling metrics: spec.metrics[].prometheus.targetValuePerRunner cannot be parsed into a float64: %w", err)
+	} else if targetValuePerRunner <= 0 || math.IsNaN(targetValuePerRunner) || math.IsInf(targetValuePerRunner, 0) {
+		return nil, fmt.Errorf("validating autoscaling metrics: spec.metrics[].prometheus.targetValuePerRunner must be a positive finite number, but got %s", spec.TargetValuePerRunner)
+	}
+
+	timeout := defaultPrometheusQueryTimeout
+	if spec.Timeout != nil && spec.Timeout.Duration > 0 {
+		timeout = spec.Timeout.Duration
+	}
+
+	value, ok, err := queryPrometheus(ctx, spec.Address, spec.Query, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		setMetricValue(ctx, "no series")
+
+		r.Log.V(1).Info(
+			"Skipped suggesting replicas as the prometheus query resulted in no series",
+			"query", spec.Query,
+			"kind", st.Kind,
+			"name", st.Name,
+		)
+
+		return nil, nil
+	}
+
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		r.Log.V(1).Info(
+			"Skipped suggesting replicas as the prometheus query resulted in a non-finite value",
+			"query", spec.Query,
+			"value", value,
+			"kind", st.Kind,
+			"name", st.Name,
+		)
+
+		return nil, nil
+	}
+
+	setMetricValue(ctx, "value=%s", strconv.FormatFloat(value, 'g', -1, 64))
+
+	desiredReplicas := 0
+	if value > 0 {
+		// The ratio is clamped before the conversion, as a huge value or a tiny target would overflow the int.
+		// The suggestion is clamped to MaxReplicas, if any, by the caller.
+		desiredReplicas = int(math.Min(math.Ceil(value/targetValuePerRunner), math.MaxInt32))
+	}
+
+	r.Log.V(1).Info(
+		fmt.Sprintf("Suggested desired replicas of %d by Prometheus", desiredReplicas),
+		"query", spec.Query,
+		"value", value,
+		"target_value_per_runner", targetValuePerRunner,
+		"kind", st.Kind,
+		"name", st.Name,
+	)
+
+	return &desiredReplicas, nil
+}
+
+// queryPrometheus evaluates the PromQL expression as an instant query and returns the sum of the resulting values.
+// The returned bool is false when the query resulted in no series.
+func queryPrometheus(ctx context.Context, address, query string, timeout time.Duration) (float64, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	u := strings.TrimSuffix(address, "/") + "/api/v1/query"
+
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("timeout", timeout.String())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(params.Encode()))
+	if err != nil {
+		return 0, false, fmt.Errorf("creating prometheus query request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, false, fmt.Errorf("querying prometheus at %s: timed out after %s", u, timeout)
+		}
+		return 0, false, fmt.Errorf("querying prometheus at %s: %w", u, err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxPrometheusResponseBytes))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, false, fmt.Errorf("reading prometheus query response from %s: timed out after %s", u, timeout)
+		}
+		return 0, false, fmt.Errorf("reading prometheus query response from %s: %w", u, err)
+	}
+
+	var qr prometheusQueryResponse
+	if err := json.Unmarshal(body, &qr); err != nil {
+		return 0, false, fmt.Errorf("parsing prometheus query response from %s with status %d: %w", u, res.StatusCode, err)
+	}
+
+	if qr.Status != "success" {
+		return 0, false, fmt.Errorf("querying prometheus at %s: status %d: %s: %s", u, res.StatusCode, qr.ErrorType, qr.Error)
+	}
+
+	switch qr.Data.ResultType {
+	case "scalar":
+		var sample []interface{}
+		if err := json.Unmarshal(qr.Data.Result, &sample); err != nil {
+			return 0, false, fmt.Errorf("parsing prometheus scalar result: %w", err)
+		}
+
+		v, err := parsePrometheusSampleValue(sample)
+		if err != nil {
+			return 0, false, err
+		}
+
+		return v, true, nil
+	case "vector":
+		var series []struct {
+			Value []interface{} `json:"value"`
+		}
+		if err := json.Unmarshal(qr.Data.Result, &series); err != nil {
+			return 0, false, fmt.Errorf("parsing prometheus vector result: %w", err)
+		}
+
+		if len(series) == 0 {
+			return 0, false, nil
+		}
+
+		var sum float64
+		for _, s := range series {
+			v, err := parsePrometheusSampleValue(s.Value)
+			if err != nil {
+				return 0, false, err
+			}
+			sum += v
+		}
+
+		return sum, true, nil
+	default:
+		return 0, false, fmt.Errorf("unsupported prometheus query result type %q: the query must result in a scalar or a vector", qr.Data.ResultType)
+	}
+}
+
+// parsePrometheusSampleValue parses a sample encoded as `[<unix_time>, "<value>"]`.
+// Prometheus encodes values as strings so that NaN and Inf can be represented, which ParseFloat understands as well.
+func parsePrometheusSampleValue(sample []interface{}) (float64, error) {
+	if len(sample) != 2 {
+		return 0, fmt.Errorf("parsing prometheus sample: expected [timestamp, value], but got %v", sample)
+	}
+
+	s, ok := sample[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("parsing prometheus sample: expected the value to be a string, but got %v", sample[1])
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing prometheus sample value %q: %w", s, err)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestSuggestDesiredReplicas_Prometheus(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	vector := func(values ...string) string {
+		var series []string
+		for i, v := range values {
+			series = append(series, fmt.Sprintf(`{"metric":{"instance":"gw-%d"},"value":[1660000000.123,"%s"]}`, i, v))
+		}
+		return fmt.Sprintf(`{"status":"success","data":{"resultType":"vector","result":[%s]}}`, strings.Join(series, ","))
+	}
+
+	testcases := []struct {
+		description string
+		response    string
+		status      int
+		delay       time.Duration
+		target      string
+		timeout     *metav1.Duration
+		want        *int
+		err         string
+	}{
+		{
+			description: "single series",
+			response:    vector("10"),
+			target:      "4",
+			want:        intPtr(3),
+		},
+		{
+			description: "multiple series are summed",
+			response:    vector("3", "4.5"),
+			target:      "2.5",
+			want:        intPtr(3),
+		},
+		{
+			description: "scalar",
+			response:    `{"status":"success","data":{"resultType":"scalar","result":[1660000000.123,"8"]}}`,
+			target:      "2",
+			want:        intPtr(4),
+		},
+		{
+			description: "zero",
+			response:    vector("0"),
+			target:      "2",
+			want:        intPtr(0),
+		},
+		{
+			description: "negative",
+			response:    vector("-3"),
+			target:      "2",
+			want:        intPtr(0),
+		},
+		{
+			description: "huge value is clamped",
+			response:    vector("1e300"),
+			target:      "1",
+			want:        intPtr(math.MaxInt32),
+		},
+		{
+			description: "tiny target is clamped",
+			response:    vector("10"),
+			target:      "1e-320",
+			want:        intPtr(math.MaxInt32),
+		},
+		{
+			description: "missing series",
+			response:    vector(),
+			target:      "2",
+			want:        nil,
+		},
+		{
+			description: "NaN",
+			response:    vector("NaN"),
+			target:      "2",
+			want:        nil,
+		},
+		{
+			description: "Inf",
+			response:    vector("+Inf"),
+			target:      "2",
+			want:        nil,
+		},
+		{
+			description: "query error",
+			status:      http.StatusBadRequest,
+			response:    `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+			target:      "2",
+			err:         "status 400: bad_data: parse error",
+		},
+		{
+			description: "unsupported result type",
+			response:    `{"status":"success","data":{"resultType":"matrix","result":[]}}`,
+			target:      "2",
+			err:         `unsupported prometheus query result type "matrix"`,
+		},
+		{
+			description: "timeout",
+			response:    vector("10"),
+			delay:       time.Second,
+			timeout:     &metav1.Duration{Duration: 50 * time.Millisecond},
+			target:      "2",
+			err:         "timed out after 50ms",
+		},
+		{
+			description: "invalid target value",
+			response:    vector("10"),
+			target:      "0",
+			err:         "targetValuePerRunner must be a positive finite number",
+		},
+		{
+			description: "negative target value",
+			response:    vector("10"),
+			target:      "-2",
+			err:         "targetValuePerRunner must be a positive finite number",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.description, func(t *testing.T) {
+			var gotQuery string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.URL.Path != "/api/v1/query" {
+					http.NotFound(w, req)
+					return
+				}
+
+				gotQuery = req.FormValue("query")
+
+				if tc.delay > 0 {
+					select {
+					case <-time.After(tc.delay):
+					case <-req.Context().Done():
+						return
+					}
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				if tc.status != 0 {
+					w.WriteHeader(tc.status)
+				}
+				fmt.Fprint(w, tc.response)
+			}))
+			defer server.Close()
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Log: zap.New(zap.UseDevMode(true)),
+			}
+
+			query := `sum(ci_gateway_queue_depth{pool="linux"})`
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MinReplicas: intPtr(0),
+					MaxReplicas: intPtr(10),
+					// The legacy metrics policy turns a suggestion of 0 into nil, so we use Max to see the raw suggestion
+					MetricsPolicy: v1alpha1.MetricsPolicyMax,
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type: v1alpha1.AutoscalingMetricTypePrometheus,
+							Prometheus: &v1alpha1.PrometheusMetricSource{
+								Address:              server.URL + "/",
+								Query:                query,
+								TargetValuePerRunner: tc.target,
+								Timeout:              tc.timeout,
+							},
+						},
+					},
+				},
+			}
+
+			got, err := h.suggestDesiredReplicas(scaleTarget{}, hra)
+			if err != nil {
+				if tc.err == "" {
+					t.Fatalf("unexpected error: expected none, got %v", err)
+				} else if !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("unexpected error: expected to contain %q, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if tc.err != "" {
+				t.Fatalf("expected error containing %q, got none", tc.err)
+			}
+
+			if gotQuery != query {
+				t.Errorf("unexpected query: expected %q, got %q", query, gotQuery)
+			}
+
+			if tc.want == nil {
+				if got != nil {
+					t.Fatalf("expected nil, got %d", *got)
+				}
+				return
+			}
+
+			if got == nil || *got != *tc.want {
+				t.Fatalf("expected %d, got %v", *tc.want, got)
+			}
+		})
+	}
+}
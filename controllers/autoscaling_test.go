@@ -6,13 +6,17 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
 	"github.com/actions-runner-controller/actions-runner-controller/github"
 	"github.com/actions-runner-controller/actions-runner-controller/github/fake"
+	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
@@ -797,3 +801,188 @@ func TestDetermineDesiredReplicas_OrganizationalRunner(t *testing.T) {
 		})
 	}
 }
+
+func TestComputeReplicasAndOverflow(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	log := zap.New(func(o *zap.Options) {
+		o.Development = true
+	})
+
+	now := time.Now()
+
+	testcases := []struct {
+		description  string
+		overflowRef  *v1alpha1.ScaleTargetRef
+		reserved     int
+		sReplicas    *int
+		sOverflow    *int
+		sTime        *metav1.Time
+		want         int
+		wantOverflow int
+	}{
+		{
+			description:  "no overflow target",
+			reserved:     5,
+			want:         3,
+			wantOverflow: 0,
+		},
+		{
+			description:  "demand above max goes to the overflow target",
+			overflowRef:  &v1alpha1.ScaleTargetRef{Name: "overflow"},
+			reserved:     5,
+			want:         3,
+			wantOverflow: 3,
+		},
+		{
+			description:  "demand within max",
+			overflowRef:  &v1alpha1.ScaleTargetRef{Name: "overflow"},
+			reserved:     1,
+			want:         2,
+			wantOverflow: 0,
+		},
+		{
+			description:  "overflow scale down is delayed as well",
+			overflowRef:  &v1alpha1.ScaleTargetRef{Name: "overflow"},
+			reserved:     1,
+			sReplicas:    intPtr(3),
+			sOverflow:    intPtr(2),
+			sTime:        &metav1.Time{Time: now.Add(-time.Minute)},
+			want:         3,
+			wantOverflow: 2,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Log:                   log,
+				DefaultScaleDownDelay: DefaultScaleDownDelay,
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MinReplicas:       intPtr(1),
+					MaxReplicas:       intPtr(3),
+					OverflowTargetRef: tc.overflowRef,
+				},
+				Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+					DesiredReplicas:            tc.sReplicas,
+					DesiredOverflowReplicas:    tc.sOverflow,
+					LastSuccessfulScaleOutTime: tc.sTime,
+					CapacityReservations: []v1alpha1.CapacityReservation{
+						{Replicas: tc.reserved, ExpirationTime: metav1.Time{Time: now.Add(time.Hour)}},
+					},
+				},
+			}
+
+			got, gotOverflow, err := h.computeReplicasAndOverflowWithCache(log, now, scaleTarget{}, hra, 1)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %d", tc.want, got)
+			}
+
+			if gotOverflow != tc.wantOverflow {
+				t.Errorf("incorrect desired overflow replicas: want %d, got %d", tc.wantOverflow, gotOverflow)
+			}
+		})
+	}
+}
+
+func TestScaleOverflowTarget(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	one := int32(1)
+
+	c := clientfake.NewFakeClientWithScheme(scheme,
+		&v1alpha1.RunnerDeployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "spot"}},
+		&v1alpha1.RunnerSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "zone-b"}, Spec: v1alpha1.RunnerSetSpec{StatefulSetSpec: appsv1.StatefulSetSpec{Replicas: &one}}},
+	)
+
+	h := &HorizontalRunnerAutoscalerReconciler{Client: c}
+
+	if err := h.scaleOverflowTarget(ctx, "default", v1alpha1.ScaleTargetRef{Name: "spot"}, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var rd v1alpha1.RunnerDeployment
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "spot"}, &rd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rd.Spec.Replicas == nil || *rd.Spec.Replicas != 2 {
+		t.Errorf("unexpected replicas of the overflow runnerdeployment: %v", rd.Spec.Replicas)
+	}
+
+	if err := h.scaleOverflowTarget(ctx, "default", v1alpha1.ScaleTargetRef{Kind: "RunnerSet", Name: "zone-b"}, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var rs v1alpha1.RunnerSet
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "zone-b"}, &rs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rs.Spec.Replicas == nil || *rs.Spec.Replicas != 0 {
+		t.Errorf("unexpected replicas of the overflow runnerset: %v", rs.Spec.Replicas)
+	}
+
+	if err := h.scaleOverflowTarget(ctx, "default", v1alpha1.ScaleTargetRef{Name: "missing"}, 1); err == nil {
+		t.Errorf("expected an error for the missing overflow target")
+	}
+}
+
+func TestApplyScheduledOverride(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			MaxReplicas: intPtr(10),
+			Metrics: []v1alpha1.MetricSpec{
+				{Type: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy, ScaleUpThreshold: "0.75", ScaleDownThreshold: "0.3"},
+			},
+		},
+	}
+
+	if got := applyScheduledOverride(hra, nil); *got.Spec.MaxReplicas != 10 {
+		t.Errorf("unexpected maxReplicas without active override: %d", *got.Spec.MaxReplicas)
+	}
+
+	got := applyScheduledOverride(hra, &Override{ScheduledOverride: v1alpha1.ScheduledOverride{MaxReplicas: intPtr(20), ScaleUpThreshold: "0.5"}})
+
+	if *got.Spec.MaxReplicas != 20 {
+		t.Errorf("unexpected overridden maxReplicas: %d", *got.Spec.MaxReplicas)
+	}
+
+	if m := got.Spec.Metrics[0]; m.ScaleUpThreshold != "0.5" || m.ScaleDownThreshold != "0.3" {
+		t.Errorf("unexpected overridden thresholds: %+v", m)
+	}
+
+	if *hra.Spec.MaxReplicas != 10 || hra.Spec.Metrics[0].ScaleUpThreshold != "0.75" {
+		t.Errorf("the original hra must not be modified")
+	}
+}
+
+func TestCountWorkflowRuns_JobsNotListed(t *testing.T) {
+	r := &HorizontalRunnerAutoscalerReconciler{Log: zap.New(zap.UseDevMode(true))}
+
+	runs := []github.WorkflowRunState{
+		// The jobs of these runs couldn't be listed, so that each run is counted as a single job of its status
+		{ID: 1, Status: "queued"},
+		{ID: 2, Status: "in_progress"},
+		{ID: 3, Status: "waiting"},
+	}
+
+	total, inProgress, queued, unknown := r.countWorkflowRuns(MetricTarget{}, runs)
+
+	if total != 3 || inProgress != 1 || queued != 1 || unknown != 1 {
+		t.Errorf("unexpected counts: total=%d in_progress=%d queued=%d unknown=%d", total, inProgress, queued, unknown)
+	}
+}
@@ -12,6 +12,9 @@ const (
 	runnerPodFinalizerName             = "actions.summerwind.dev/runner-pod"
 	runnerLinkedResourcesFinalizerName = "actions.summerwind.dev/linked-resources"
 
+	// placeholderRunnerFinalizerName keeps the HorizontalRunnerAutoscaler until its placeholder runner is unregistered
+	placeholderRunnerFinalizerName = "actions.summerwind.dev/placeholder-runner"
+
 	annotationKeyPrefix = "actions-runner/"
 
 	AnnotationKeyLastRegistrationCheckTime = "actions-runner-controller/last-registration-check-time"
@@ -35,6 +38,10 @@ const (
 
 	AnnotationKeyRunnerID = annotationKeyPrefix + "id"
 
+	// AnnotationKeyGitHubAPICredsSecret is the annotation that contains the name of the Secret that holds the GitHub API credentials
+	// for the runner pod. It's used to register and unregister the runner with the right credentials.
+	AnnotationKeyGitHubAPICredsSecret = annotationKeyPrefix + "github-api-creds-secret"
+
 	// This can be any value but a larger value can make an unregistration timeout longer than configured in practice.
 	DefaultUnregistrationRetryDelay = time.Minute
 
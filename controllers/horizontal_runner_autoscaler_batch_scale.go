@@ -8,6 +8,8 @@ import (
 
 	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
 	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -21,6 +23,9 @@ type batchScaler struct {
 
 	queue       chan *ScaleTarget
 	workerStart sync.Once
+
+	// ack is called with the IDs of the persisted operations once they're applied
+	ack func(ctx context.Context, ids []string)
 }
 
 func newBatchScaler(ctx context.Context, client client.Client, log logr.Logger) *batchScaler {
@@ -40,6 +45,191 @@ type batchScaleOperation struct {
 type scaleOperation struct {
 	trigger v1alpha1.ScaleUpTrigger
 	log     logr.Logger
+	// id is the ID of the persisted operation, if any
+	id string
+	// job is the workflow job that triggered the operation, if any
+	job *WorkflowJobRef
+}
+
+// WorkflowJobRef identifies the GitHub Actions workflow job a capacity reservation is made for.
+type WorkflowJobRef struct {
+	ID         int64  `json:"id,omitempty"`
+	RunID      int64  `json:"runID,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Repository string `json:"repository,omitempty"`
+}
+
+// matches returns true when the reservation is made for the workflow job.
+// The job is identified by its ID when known, or by the run ID and the job name otherwise.
+func (j WorkflowJobRef) matches(r v1alpha1.CapacityReservation) bool {
+	if j.ID != 0 && r.JobID != 0 {
+		return j.ID == r.JobID
+	}
+
+	return j.RunID != 0 && j.RunID == r.RunID && j.Name == r.JobName
+}
+
+func hasCapacityReservationForJob(reservations []v1alpha1.CapacityReservation, job WorkflowJobRef) bool {
+	for _, r := range reservations {
+		if job.matches(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isForJob returns true when the reservation is made for a specific workflow job.
+func isForJob(r v1alpha1.CapacityReservation) bool {
+	return r.JobID != 0 || r.RunID != 0
+}
+
+// removeCapacityReservation removes the reservation made for the job.
+// When the job is nil or there's no reservation for the job, it removes the oldest reservation of the amount
+// that isn't for any job, so that the reservations for the other jobs are kept until their own jobs complete.
+func removeCapacityReservation(reservations []v1alpha1.CapacityReservation, amount int, job *WorkflowJobRef) ([]v1alpha1.CapacityReservation, bool) {
+	index := -1
+
+	if job != nil {
+		for i, r := range reservations {
+			if job.matches(r) {
+				index = i
+				break
+			}
+		}
+	}
+
+	if index < 0 {
+		for i, r := range reservations {
+			if r.Replicas+amount == 0 && (job == nil || !isForJob(r)) {
+				index = i
+				break
+			}
+		}
+	}
+
+	if index < 0 {
+		return reservations, false
+	}
+
+	var remaining []v1alpha1.CapacityReservation
+
+	remaining = append(remaining, reservations[:index]...)
+	remaining = append(remaining, reservations[index+1:]...)
+
+	return remaining, true
+}
+
+const (
+	// completedJobRetention is how long the completed jobs are remembered in the HRA status.
+	// It's long enough to outlive the job states the reservation resync fetched before the jobs completed.
+	completedJobRetention = time.Hour
+
+	// maxCompletedJobs is the maximum number of the completed jobs remembered in the HRA status.
+	// The oldest ones are forgotten first when exceeded.
+	maxCompletedJobs = 100
+)
+
+// addCompletedJob remembers the job as completed, forgetting the ones completed before the retention period
+// and the oldest ones exceeding maxCompletedJobs.
+func addCompletedJob(jobs []v1alpha1.CompletedJob, id int64, now time.Time) []v1alpha1.CompletedJob {
+	var remembered []v1alpha1.CompletedJob
+
+	for _, j := range jobs {
+		if j.JobID != id && now.Sub(j.CompletionTime.Time) < completedJobRetention {
+			remembered = append(remembered, j)
+		}
+	}
+
+	remembered = append(remembered, v1alpha1.CompletedJob{JobID: id, CompletionTime: metav1.Time{Time: now}})
+
+	if len(remembered) > maxCompletedJobs {
+		remembered = remembered[len(remembered)-maxCompletedJobs:]
+	}
+
+	return remembered
+}
+
+// isCompletedJob returns true when the job with the ID is remembered as completed.
+func isCompletedJob(jobs []v1alpha1.CompletedJob, id int64) bool {
+	if id == 0 {
+		return false
+	}
+
+	for _, j := range jobs {
+		if j.JobID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// migrateLegacyCapacityReservations moves the reservations written to the spec by the older versions of the webhook server
+// into the status, so that the completed events and the resync can remove them.
+// Only the reservations that look written by the webhook server are migrated. See isLegacyWebhookCapacityReservation.
+// The other ones are declared by the user and left in the spec.
+// It returns the reservations to be kept in the spec, and true when any reservation is migrated.
+func migrateLegacyCapacityReservations(hra *v1alpha1.HorizontalRunnerAutoscaler) ([]v1alpha1.CapacityReservation, bool) {
+	var (
+		declared []v1alpha1.CapacityReservation
+		migrated bool
+	)
+
+	for _, r := range hra.Spec.CapacityReservations {
+		if !isLegacyWebhookCapacityReservation(hra, r) {
+			declared = append(declared, r)
+			continue
+		}
+
+		migrated = true
+
+		// The reservation is already in the status when we failed to remove it from the spec last time
+		var found bool
+		for _, s := range hra.Status.CapacityReservations {
+			if equality.Semantic.DeepEqual(r, s) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			hra.Status.CapacityReservations = append(hra.Status.CapacityReservations, r)
+		}
+	}
+
+	return declared, migrated
+}
+
+// isLegacyWebhookCapacityReservation returns true when the reservation in the spec is the one the older versions of the webhook server wrote.
+// The webhook server has never named its reservations, and always set the effective time to when it received the event,
+// and the expiration time to the duration of the github event scale up trigger after that.
+func isLegacyWebhookCapacityReservation(hra *v1alpha1.HorizontalRunnerAutoscaler, r v1alpha1.CapacityReservation) bool {
+	if r.Name != "" || r.EffectiveTime.IsZero() || r.ExpirationTime.IsZero() {
+		return false
+	}
+
+	for _, t := range hra.Spec.ScaleUpTriggers {
+		if t.GitHubEvent != nil && r.ExpirationTime.Sub(r.EffectiveTime.Time) == t.Duration.Duration {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeMigratedCapacityReservations removes the reservations migrated to the status from the spec.
+// A failure is only logged, as the reservations are migrated again without being duplicated on the next update.
+func removeMigratedCapacityReservations(ctx context.Context, c client.Client, log logr.Logger, hra *v1alpha1.HorizontalRunnerAutoscaler, declared []v1alpha1.CapacityReservation) {
+	hra.Spec.CapacityReservations = declared
+
+	if err := c.Update(ctx, hra); err != nil {
+		log.Error(err, "Could not remove the capacity reservations migrated to the status from the spec", "hra", hra.Name)
+
+		return
+	}
+
+	log.Info("Migrated the capacity reservations written by an older version from the spec to the status", "hra", hra.Name)
 }
 
 // Add the scale target to the unbounded queue, blocking until the target is successfully added to the queue.
@@ -95,6 +285,8 @@ func (s *batchScaler) Add(st *ScaleTarget) {
 						b.scaleOps = append(b.scaleOps, scaleOperation{
 							log:     *st.log,
 							trigger: st.ScaleUpTrigger,
+							id:      st.operationID,
+							job:     st.job,
 						})
 						batches[nsName] = b
 						ops++
@@ -109,11 +301,19 @@ func (s *batchScaler) Add(st *ScaleTarget) {
 
 					for nsName, b := range batches {
 						b := b
-						if err := s.batchScale(context.Background(), b); err != nil {
+						if err := s.batchScale(context.Background(), b); kerrors.IsNotFound(err) && s.ack != nil {
+							// The HRA has been deleted. Drop the persisted operations so that they aren't replayed forever
+							log.V(1).Info("Dropped scale operations for the missing hra", "hra", b.namespacedName, "ops", len(b.scaleOps))
+							s.ack(context.Background(), b.operationIDs())
+						} else if err != nil {
 							log.V(2).Info("Failed to scale due to error", "error", err)
 							failed[nsName] = b
 						} else {
 							log.V(2).Info("Successfully ran batch scale", "hra", b.namespacedName)
+
+							if s.ack != nil {
+								s.ack(context.Background(), b.operationIDs())
+							}
 						}
 					}
 
@@ -136,6 +336,18 @@ func (s *batchScaler) Add(st *ScaleTarget) {
 	s.queue <- st
 }
 
+func (b batchScaleOperation) operationIDs() []string {
+	var ids []string
+
+	for _, op := range b.scaleOps {
+		if op.id != "" {
+			ids = append(ids, op.id)
+		}
+	}
+
+	return ids
+}
+
 func (s *batchScaler) batchScale(ctx context.Context, batch batchScaleOperation) error {
 	var hra v1alpha1.HorizontalRunnerAutoscaler
 
@@ -145,7 +357,9 @@ func (s *batchScaler) batchScale(ctx context.Context, batch batchScaleOperation)
 
 	copy := hra.DeepCopy()
 
-	copy.Spec.CapacityReservations = getValidCapacityReservations(copy)
+	declared, migrated := migrateLegacyCapacityReservations(copy)
+
+	copy.Status.CapacityReservations = getValidCapacityReservations(copy)
 
 	var added, completed int
 
@@ -159,36 +373,65 @@ func (s *batchScaler) batchScale(ctx context.Context, batch batchScaleOperation)
 		scale.log.V(2).Info("Adding capacity reservation", "amount", amount)
 
 		if amount > 0 {
+			if scale.job != nil && hasCapacityReservationForJob(copy.Status.CapacityReservations, *scale.job) {
+				// The same queued event was delivered twice
+				scale.log.V(1).Info("Skipped adding capacity reservation as the job already has one", "job_id", scale.job.ID, "run_id", scale.job.RunID, "job_name", scale.job.Name)
+
+				continue
+			}
+
+			if scale.job != nil && isCompletedJob(copy.Status.CompletedJobs, scale.job.ID) {
+				// The queued event was delivered after the completed event
+				scale.log.V(1).Info("Skipped adding capacity reservation as the job has already completed", "job_id", scale.job.ID)
+
+				continue
+			}
+
 			now := time.Now()
-			copy.Spec.CapacityReservations = append(copy.Spec.CapacityReservations, v1alpha1.CapacityReservation{
+			reservation := v1alpha1.CapacityReservation{
 				EffectiveTime:  metav1.Time{Time: now},
 				ExpirationTime: metav1.Time{Time: now.Add(scale.trigger.Duration.Duration)},
 				Replicas:       amount,
-			})
+			}
+
+			if scale.job != nil {
+				reservation.JobID = scale.job.ID
+				reservation.RunID = scale.job.RunID
+				reservation.JobName = scale.job.Name
+				reservation.Repository = scale.job.Repository
+			}
+
+			copy.Status.CapacityReservations = append(copy.Status.CapacityReservations, reservation)
 
 			added += amount
 		} else if amount < 0 {
-			var reservations []v1alpha1.CapacityReservation
+			if scale.job != nil && isCompletedJob(copy.Status.CompletedJobs, scale.job.ID) {
+				// The same completed event was delivered twice. Falling through would remove an untagged reservation
+				// of the same amount that isn't for this job
+				scale.log.V(1).Info("Skipped removing capacity reservation as the job has already completed", "job_id", scale.job.ID)
+
+				continue
+			}
 
 			var found bool
 
-			for _, r := range copy.Spec.CapacityReservations {
-				if !found && r.Replicas+amount == 0 {
-					found = true
-				} else {
-					reservations = append(reservations, r)
-				}
+			copy.Status.CapacityReservations, found = removeCapacityReservation(copy.Status.CapacityReservations, amount, scale.job)
+
+			if !found {
+				scale.log.V(1).Info("No capacity reservation found to remove. It has probably expired already")
 			}
 
-			copy.Spec.CapacityReservations = reservations
+			if scale.job != nil && scale.job.ID != 0 {
+				copy.Status.CompletedJobs = addCompletedJob(copy.Status.CompletedJobs, scale.job.ID, time.Now())
+			}
 
 			completed += amount
 		}
 	}
 
-	before := len(hra.Spec.CapacityReservations)
-	expired := before - len(copy.Spec.CapacityReservations)
-	after := len(copy.Spec.CapacityReservations)
+	before := len(hra.Status.CapacityReservations)
+	expired := before - len(copy.Status.CapacityReservations)
+	after := len(copy.Status.CapacityReservations)
 
 	s.Log.V(1).Info(
 		fmt.Sprintf("Updating hra %s for capacityReservations update", hra.Name),
@@ -199,8 +442,23 @@ func (s *batchScaler) batchScale(ctx context.Context, batch batchScaleOperation)
 		"after", after,
 	)
 
-	if err := s.Client.Update(ctx, copy); err != nil {
-		return fmt.Errorf("updating horizontalrunnerautoscaler to add capacity reservation: %w", err)
+	// The reservations are recorded in the status, so that we don't fight with whatever manages the spec, like GitOps tools
+	if err := s.Client.Status().Update(ctx, copy); err != nil {
+		return fmt.Errorf("updating horizontalrunnerautoscaler status to add capacity reservation: %w", err)
+	}
+
+	if migrated {
+		removeMigratedCapacityReservations(ctx, s.Client, s.Log, copy, declared)
+	}
+
+	// A scale target scaled to zero is woken up right away, rather than on the next reconciliation of the HRA,
+	// to shorten the cold start of the first queued workflow job
+	if added > 0 && hra.Spec.ScaleToZero != nil {
+		if woken, err := wakeUpScaleTarget(ctx, s.Client, hra); err != nil {
+			s.Log.Error(err, "Could not wake up the scale target", "hra", hra.Name)
+		} else if woken {
+			s.Log.Info("Woke up the scale target scaled to zero", "hra", hra.Name, "kind", hra.Spec.ScaleTargetRef.Kind, "name", hra.Spec.ScaleTargetRef.Name)
+		}
 	}
 
 	return nil
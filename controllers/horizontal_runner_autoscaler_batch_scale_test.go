@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRemoveCapacityReservation(t *testing.T) {
+	untagged := v1alpha1.CapacityReservation{Name: "untagged", Replicas: 1}
+	job1 := v1alpha1.CapacityReservation{Name: "job1", Replicas: 1, JobID: 1}
+	job2 := v1alpha1.CapacityReservation{Name: "job2", Replicas: 1, JobID: 2}
+	run3 := v1alpha1.CapacityReservation{Name: "run3", Replicas: 1, RunID: 3, JobName: "build"}
+
+	reservations := []v1alpha1.CapacityReservation{job1, untagged, job2, run3}
+
+	names := func(rs []v1alpha1.CapacityReservation) []string {
+		var ns []string
+		for _, r := range rs {
+			ns = append(ns, r.Name)
+		}
+		return ns
+	}
+
+	testcases := []struct {
+		name      string
+		job       *WorkflowJobRef
+		want      []string
+		wantFound bool
+	}{
+		{
+			name:      "job id",
+			job:       &WorkflowJobRef{ID: 2, RunID: 3, Name: "build"},
+			want:      []string{"job1", "untagged", "run3"},
+			wantFound: true,
+		},
+		{
+			name:      "run id and job name",
+			job:       &WorkflowJobRef{RunID: 3, Name: "build"},
+			want:      []string{"job1", "untagged", "job2"},
+			wantFound: true,
+		},
+		{
+			name:      "unknown job falls back to the untagged one",
+			job:       &WorkflowJobRef{ID: 4},
+			want:      []string{"job1", "job2", "run3"},
+			wantFound: true,
+		},
+		{
+			name:      "no job",
+			want:      []string{"untagged", "job2", "run3"},
+			wantFound: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, found := removeCapacityReservation(reservations, -1, tc.job)
+
+			require.Equal(t, tc.wantFound, found)
+			require.Equal(t, tc.want, names(got))
+			require.Len(t, reservations, 4, "the original reservations must not be modified")
+		})
+	}
+
+	_, found := removeCapacityReservation([]v1alpha1.CapacityReservation{job1}, -1, &WorkflowJobRef{ID: 4})
+	require.False(t, found, "a reservation for another job must not be removed")
+}
+
+func TestBatchScale_JobReservations(t *testing.T) {
+	ctx := context.Background()
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hra"},
+	}
+
+	c := fake.NewFakeClientWithScheme(sc, hra)
+
+	s := newBatchScaler(ctx, c, logr.Discard())
+
+	nsName := types.NamespacedName{Namespace: "default", Name: "hra"}
+
+	op := func(amount int, jobID int64) scaleOperation {
+		return scaleOperation{
+			trigger: v1alpha1.ScaleUpTrigger{Amount: amount, Duration: metav1.Duration{Duration: time.Hour}},
+			log:     logr.Discard(),
+			job:     &WorkflowJobRef{ID: jobID, Repository: "owner/repo"},
+		}
+	}
+
+	require.NoError(t, s.batchScale(ctx, batchScaleOperation{
+		namespacedName: nsName,
+		scaleOps:       []scaleOperation{op(1, 1), op(1, 2), op(1, 1)},
+	}))
+
+	var updated v1alpha1.HorizontalRunnerAutoscaler
+	require.NoError(t, c.Get(ctx, nsName, &updated))
+	require.Len(t, updated.Status.CapacityReservations, 2, "a duplicate queued event must not add a reservation")
+	require.Equal(t, int64(1), updated.Status.CapacityReservations[0].JobID)
+	require.Equal(t, "owner/repo", updated.Status.CapacityReservations[0].Repository)
+
+	require.NoError(t, s.batchScale(ctx, batchScaleOperation{
+		namespacedName: nsName,
+		scaleOps:       []scaleOperation{op(-1, 2)},
+	}))
+
+	require.NoError(t, c.Get(ctx, nsName, &updated))
+	require.Len(t, updated.Status.CapacityReservations, 1)
+	require.Equal(t, int64(1), updated.Status.CapacityReservations[0].JobID, "the completion must remove its own reservation")
+}
+
+func TestBatchScale_DuplicateCompletion(t *testing.T) {
+	ctx := context.Background()
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hra"},
+		Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+			CapacityReservations: []v1alpha1.CapacityReservation{
+				{Replicas: 1, ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)}, JobID: 1},
+				// Made by a trigger other than workflow_job, which isn't for any job
+				{Replicas: 1, ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)}},
+			},
+		},
+	}
+
+	c := fake.NewFakeClientWithScheme(sc, hra)
+
+	s := newBatchScaler(ctx, c, logr.Discard())
+
+	nsName := types.NamespacedName{Namespace: "default", Name: "hra"}
+
+	completion := batchScaleOperation{
+		namespacedName: nsName,
+		scaleOps: []scaleOperation{{
+			trigger: v1alpha1.ScaleUpTrigger{Amount: -1},
+			log:     logr.Discard(),
+			job:     &WorkflowJobRef{ID: 1},
+		}},
+	}
+
+	require.NoError(t, s.batchScale(ctx, completion))
+	require.NoError(t, s.batchScale(ctx, completion))
+
+	var updated v1alpha1.HorizontalRunnerAutoscaler
+	require.NoError(t, c.Get(ctx, nsName, &updated))
+	require.Len(t, updated.Status.CapacityReservations, 1, "a duplicate completed event must not remove the reservation of another job")
+	require.Zero(t, updated.Status.CapacityReservations[0].JobID)
+	require.True(t, isCompletedJob(updated.Status.CompletedJobs, 1))
+}
+
+func TestBatchScale_MigrateLegacyReservations(t *testing.T) {
+	ctx := context.Background()
+
+	effective := metav1.Time{Time: time.Now().Truncate(time.Second)}
+	expiration := metav1.Time{Time: effective.Add(time.Hour)}
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hra"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			ScaleUpTriggers: []v1alpha1.ScaleUpTrigger{{
+				GitHubEvent: &v1alpha1.GitHubEventScaleUpTriggerSpec{WorkflowJob: &v1alpha1.WorkflowJobSpec{}},
+				Amount:      1,
+				Duration:    metav1.Duration{Duration: time.Hour},
+			}},
+			CapacityReservations: []v1alpha1.CapacityReservation{
+				{Name: "declared", Replicas: 1, ExpirationTime: expiration},
+				// Declared by the user without a name
+				{Replicas: 2, ExpirationTime: expiration},
+				{Replicas: 3, EffectiveTime: effective, ExpirationTime: metav1.Time{Time: effective.Add(2 * time.Hour)}},
+				// Written by an older version of the webhook server
+				{Replicas: 1, EffectiveTime: effective, ExpirationTime: expiration},
+				{Replicas: 1, EffectiveTime: metav1.Time{Time: effective.Add(time.Second)}, ExpirationTime: metav1.Time{Time: expiration.Add(time.Second)}},
+			},
+		},
+		Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+			CapacityReservations: []v1alpha1.CapacityReservation{
+				// Migrated already but failed to be removed from the spec
+				{Replicas: 1, EffectiveTime: metav1.Time{Time: effective.Add(time.Second)}, ExpirationTime: metav1.Time{Time: expiration.Add(time.Second)}},
+			},
+		},
+	}
+
+	c := fake.NewFakeClientWithScheme(sc, hra)
+
+	s := newBatchScaler(ctx, c, logr.Discard())
+
+	nsName := types.NamespacedName{Namespace: "default", Name: "hra"}
+
+	require.NoError(t, s.batchScale(ctx, batchScaleOperation{
+		namespacedName: nsName,
+		scaleOps: []scaleOperation{{
+			trigger: v1alpha1.ScaleUpTrigger{Amount: -1},
+			log:     logr.Discard(),
+		}},
+	}))
+
+	var updated v1alpha1.HorizontalRunnerAutoscaler
+	require.NoError(t, c.Get(ctx, nsName, &updated))
+	require.Len(t, updated.Spec.CapacityReservations, 3, "only the reservations written by older versions must be removed from the spec")
+	require.Equal(t, "declared", updated.Spec.CapacityReservations[0].Name)
+	require.Equal(t, 2, updated.Spec.CapacityReservations[1].Replicas)
+	require.Equal(t, 3, updated.Spec.CapacityReservations[2].Replicas)
+	require.Len(t, updated.Status.CapacityReservations, 1, "the completion must remove a migrated reservation without duplicating the others")
+	require.True(t, expiration.Equal(&updated.Status.CapacityReservations[0].ExpirationTime))
+
+	// The reservations declared by the user are left in the spec, and the new ones are written to the status
+	declared := updated.Spec.CapacityReservations
+
+	require.NoError(t, s.batchScale(ctx, batchScaleOperation{
+		namespacedName: nsName,
+		scaleOps: []scaleOperation{{
+			trigger: v1alpha1.ScaleUpTrigger{Amount: 1, Duration: metav1.Duration{Duration: time.Hour}},
+			log:     logr.Discard(),
+		}},
+	}))
+
+	require.NoError(t, c.Get(ctx, nsName, &updated))
+	require.Equal(t, declared, updated.Spec.CapacityReservations)
+	require.Len(t, updated.Status.CapacityReservations, 2)
+}
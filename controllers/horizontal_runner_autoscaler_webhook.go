@@ -35,8 +35,10 @@ import (
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/actions-runner-controller/actions-runner-controller/controllers/metrics"
 	"github.com/actions-runner-controller/actions-runner-controller/github"
 	"github.com/actions-runner-controller/actions-runner-controller/simulator"
 )
@@ -65,6 +67,10 @@ type HorizontalRunnerAutoscalerGitHubWebhook struct {
 	// GitHub Client to discover runner groups assigned to a repository
 	GitHubClient *github.Client
 
+	// GitHubClients provides the GitHub API clients for the HRAs and the runners with their own credentials,
+	// which are used to resync the capacity reservations
+	GitHubClients *MultiGitHubClient
+
 	// Namespace is the namespace to watch for HorizontalRunnerAutoscaler's to be
 	// scaled on Webhook.
 	// Set to empty for letting it watch for all namespaces.
@@ -75,6 +81,45 @@ type HorizontalRunnerAutoscalerGitHubWebhook struct {
 	// A scale target is enqueued on each retrieval of each eligible webhook event, so that it is processed asynchronously.
 	QueueLimit int
 
+	// QueueStore persists the scale operations until they're applied, so that the operations received but not applied
+	// before a restart are replayed on the next start. When nil, the operations are kept only in memory.
+	QueueStore ScaleOperationStore
+
+	// QueueReplayInterval is how often the pending operations in QueueStore that are not in the in-memory queue are enqueued again.
+	// Defaults to DefaultQueueReplayInterval.
+	QueueReplayInterval time.Duration
+
+	// ReservationResyncInterval is how often the capacity reservations made by workflow_job events are corrected against
+	// the queued and in_progress workflow jobs on GitHub. Requires GitHubClient. Zero disables it.
+	ReservationResyncInterval time.Duration
+
+	// ScaleTargetSelectionPolicy is how one HRA is selected when two or more HRAs match the same webhook event.
+	// Either Specificity, Priority, RoundRobin, or Spillover. When empty, the first HRA is selected for a workflow_job event,
+	// and the other events are ignored.
+	ScaleTargetSelectionPolicy string
+
+	// DeliveryStore remembers the X-GitHub-Delivery IDs of the webhook deliveries already processed,
+	// so that a redelivery of the same event doesn't scale the target twice. When nil, no deliveries are deduplicated.
+	DeliveryStore DeliveryStore
+
+	// WorkflowJobRecordNamespace is the namespace to create the WorkflowJobRecords of the workflow jobs seen in workflow_job events in.
+	// When empty, no workflow jobs are recorded.
+	WorkflowJobRecordNamespace string
+
+	// RunnerPodReader finds the runner pods that have no Runner, like the ones managed by RunnerSets, to correlate workflow jobs with them.
+	// It's expected to read the API server directly, so that all the pods in the cluster aren't cached. When nil, no runner pods are looked up.
+	RunnerPodReader client.Reader
+
+	queue *persistentScaleQueue
+
+	// jobTrackingQueue is the bounded queue of the workflow_job events to be tracked in the background
+	jobTrackingQueue chan workflowJobTracking
+	jobTrackingInit  sync.Once
+
+	// roundRobin is the number of selections made so far per set of candidate HRAs
+	roundRobin   map[string]int
+	roundRobinMu sync.Mutex
+
 	worker      *worker
 	workerInit  sync.Once
 	workerStart sync.Once
@@ -88,6 +133,11 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Reconcile(_ context.C
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers/finalizers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners,verbs=get;list;watch
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=workflowjobrecords,verbs=get;list;watch;create;patch;delete
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=workflowjobrecords/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.ResponseWriter, r *http.Request) {
@@ -154,6 +204,26 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 		return
 	}
 
+	// Most events have the action, but there's no common accessor to it in go-github
+	var actionEvent struct {
+		Action string `json:"action,omitempty"`
+	}
+	if err := json.Unmarshal(payload, &actionEvent); err != nil {
+		autoscaler.Log.Error(err, "could not parse webhook payload for extracting action", "webhookType", webhookType)
+	}
+	eventAction := actionEvent.Action
+
+	metrics.IncGitHubWebhookEventsReceived(webhookType, eventAction)
+
+	// result is the result of processing the event, recorded to the metrics unless the delivery is a duplicate
+	var result string
+
+	defer func() {
+		if result != "" {
+			metrics.IncGitHubWebhookEventsProcessed(webhookType, eventAction, result)
+		}
+	}()
+
 	var target *ScaleTarget
 
 	log := autoscaler.Log.WithValues(
@@ -162,6 +232,43 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 		"delivery", r.Header.Get("X-GitHub-Delivery"),
 	)
 
+	// Only the events that add or remove capacity reservations are deduplicated, as processing the other events twice is harmless
+	// and recording every delivery would make the store a bottleneck
+	if delivery := r.Header.Get("X-GitHub-Delivery"); delivery != "" && autoscaler.DeliveryStore != nil && changesCapacityReservations(webhookType, eventAction) {
+		added, err := autoscaler.DeliveryStore.Add(context.TODO(), delivery)
+		if err != nil {
+			// Processing a duplicate is better than dropping a delivery only because the store is unavailable
+			log.Error(err, "Could not check if the delivery is a duplicate. Processing it anyway")
+		} else if !added {
+			ok = true
+
+			metrics.IncGitHubWebhookDuplicateDeliveries(webhookType)
+
+			w.WriteHeader(http.StatusOK)
+
+			msg := "ignored duplicate delivery"
+
+			log.V(1).Info(msg)
+
+			if written, err := w.Write([]byte(msg)); err != nil {
+				log.Error(err, "failed writing http response", "msg", msg, "written", written)
+			}
+
+			return
+		} else {
+			defer func() {
+				if ok {
+					return
+				}
+
+				// Let GitHub redeliver it
+				if err := autoscaler.DeliveryStore.Remove(context.TODO(), delivery); err != nil {
+					log.Error(err, "Could not forget the failed delivery")
+				}
+			}()
+		}
+	}
+
 	var enterpriseEvent struct {
 		Enterprise struct {
 			Slug string `json:"slug,omitempty"`
@@ -242,9 +349,30 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 
 		labels := e.WorkflowJob.Labels
 
-		switch action := e.GetAction(); action {
-		case "queued", "completed":
-			target, err = autoscaler.getJobScaleUpTargetForRepoOrOrg(
+		// go-github's WorkflowJob type doesn't contain workflow_name and head_branch fields
+		// we need for filtering, so we parse them by ourselves.
+		var workflowJobEvent struct {
+			WorkflowJob struct {
+				WorkflowName string    `json:"workflow_name,omitempty"`
+				HeadBranch   string    `json:"head_branch,omitempty"`
+				CreatedAt    time.Time `json:"created_at,omitempty"`
+			} `json:"workflow_job,omitempty"`
+		}
+		if err := json.Unmarshal(payload, &workflowJobEvent); err != nil {
+			autoscaler.Log.Error(err, "could not parse webhook payload for extracting workflow name and head branch", "webhookType", webhookType)
+		}
+
+		autoscaler.enqueueWorkflowJobTracking(log, e, workflowJobEvent.WorkflowJob.WorkflowName, enterpriseSlug, workflowJobEvent.WorkflowJob.CreatedAt)
+
+		job := &WorkflowJobRef{
+			ID:         e.WorkflowJob.GetID(),
+			RunID:      e.WorkflowJob.GetRunID(),
+			Name:       e.WorkflowJob.GetName(),
+			Repository: fmt.Sprintf("%s/%s", e.Repo.Owner.GetLogin(), e.Repo.GetName()),
+		}
+
+		getJobScaleUpTarget := func(queued bool) (*ScaleTarget, error) {
+			return autoscaler.getJobScaleUpTargetForRepoOrOrg(
 				context.TODO(),
 				log,
 				e.Repo.GetName(),
@@ -252,18 +380,33 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 				e.Repo.Owner.GetType(),
 				enterpriseSlug,
 				labels,
+				job,
+				queued,
+				autoscaler.MatchWorkflowJobEvent(e, workflowJobEvent.WorkflowJob.WorkflowName, workflowJobEvent.WorkflowJob.HeadBranch),
 			)
+		}
+
+		switch action := e.GetAction(); action {
+		case "queued", "completed":
+			target, err = getJobScaleUpTarget(action == "queued")
+
+			if action == "completed" {
+				observeWorkflowJobRunDuration(e, target)
+			}
+
 			if target == nil {
 				break
 			}
 
+			target.job = job
+
 			if e.GetAction() == "queued" {
 				target.Amount = 1
 				break
 			} else if e.GetAction() == "completed" && e.GetWorkflowJob().GetConclusion() != "skipped" {
-				// A nagative amount is processed in the tryScale func as a scale-down request,
-				// that erasese the oldest CapacityReservation with the same amount.
-				// If the first CapacityReservation was with Replicas=1, this negative scale target erases that,
+				// A nagative amount is processed in the batchScale func as a scale-down request,
+				// that erases the CapacityReservation made for the same workflow job.
+				// If there was none, it erases the oldest CapacityReservation with the same amount that isn't for any job,
 				// so that the resulting desired replicas decreases by 1.
 				target.Amount = -1
 				break
@@ -271,7 +414,18 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 			// If the conclusion is "skipped", we will ignore it and fallthrough to the default case.
 			fallthrough
 		default:
+			if action == "in_progress" {
+				// The event triggers no scale, but the queue duration is observed with the HRA holding the reservation for the job
+				target, err := getJobScaleUpTarget(false)
+				if err != nil {
+					log.Error(err, "could not find the scale target for observing the queue duration of the workflow job")
+				}
+
+				observeWorkflowJobQueueDuration(e, workflowJobEvent.WorkflowJob.CreatedAt, target)
+			}
+
 			ok = true
+			result = metrics.WebhookEventResultIgnored
 
 			w.WriteHeader(http.StatusOK)
 
@@ -281,6 +435,7 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 		}
 	case *gogithub.PingEvent:
 		ok = true
+		result = metrics.WebhookEventResultIgnored
 
 		w.WriteHeader(http.StatusOK)
 
@@ -294,12 +449,16 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 
 		return
 	default:
+		result = metrics.WebhookEventResultIgnored
+
 		log.Info("unknown event type", "eventType", webhookType)
 
 		return
 	}
 
 	if err != nil {
+		result = metrics.WebhookEventResultFailed
+
 		log.Error(err, "handling check_run event")
 
 		return
@@ -313,6 +472,7 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 		msg := "no horizontalrunnerautoscaler to scale for this github event"
 
 		ok = true
+		result = metrics.WebhookEventResultUnmatched
 
 		w.WriteHeader(http.StatusOK)
 
@@ -323,23 +483,29 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 		return
 	}
 
-	autoscaler.workerInit.Do(func() {
-		batchScaler := newBatchScaler(context.Background(), autoscaler.Client, autoscaler.Log)
+	autoscaler.workerInit.Do(autoscaler.initWorker)
 
-		queueLimit := autoscaler.QueueLimit
-		if queueLimit == 0 {
-			queueLimit = DefaultQueueLimit
+	target.log = &log
+
+	if autoscaler.queue != nil {
+		if err = autoscaler.queue.save(context.TODO(), target); err != nil {
+			result = metrics.WebhookEventResultFailed
+			log.Error(err, "Could not scale up due to the queue store error")
+			return
 		}
-		autoscaler.worker = newWorker(context.Background(), queueLimit, batchScaler.Add)
-	})
 
-	target.log = &log
-	if ok := autoscaler.worker.Add(target); !ok {
+		// The operation is persisted, so that it's applied on a later replay even if the in-memory queue is full now
+		if ok := autoscaler.queue.enqueue(target, autoscaler.worker.Add); !ok {
+			log.Info("Deferred scale up as the queue is full", "operation", target.operationID)
+		}
+	} else if ok := autoscaler.worker.Add(target); !ok {
+		result = metrics.WebhookEventResultFailed
 		log.Error(err, "Could not scale up due to queue full")
 		return
 	}
 
 	ok = true
+	result = metrics.WebhookEventResultMatched
 
 	w.WriteHeader(http.StatusOK)
 
@@ -352,6 +518,40 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 	}
 }
 
+// replayQueue replays the pending scale operations persisted in QueueStore on start and periodically,
+// until the context is canceled.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) replayQueue(ctx context.Context) error {
+	autoscaler.workerInit.Do(autoscaler.initWorker)
+
+	autoscaler.queue.runReplay(ctx, autoscaler.queueReplayInterval(), autoscaler.worker.Add)
+
+	return nil
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) queueReplayInterval() time.Duration {
+	if autoscaler.QueueReplayInterval == 0 {
+		return DefaultQueueReplayInterval
+	}
+
+	return autoscaler.QueueReplayInterval
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) initWorker() {
+	batchScaler := newBatchScaler(context.Background(), autoscaler.Client, autoscaler.Log)
+
+	if autoscaler.QueueStore != nil {
+		lease := queueClaimLeaseIntervals * autoscaler.queueReplayInterval()
+		autoscaler.queue = newPersistentScaleQueue(autoscaler.QueueStore, scaleQueueOwner(), lease, autoscaler.Log.WithName("queue"))
+		batchScaler.ack = autoscaler.queue.ack
+	}
+
+	queueLimit := autoscaler.QueueLimit
+	if queueLimit == 0 {
+		queueLimit = DefaultQueueLimit
+	}
+	autoscaler.worker = newWorker(context.Background(), queueLimit, batchScaler.Add)
+}
+
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) findHRAsByKey(ctx context.Context, value string) ([]v1alpha1.HorizontalRunnerAutoscaler, error) {
 	ns := autoscaler.Namespace
 
@@ -405,6 +605,12 @@ type ScaleTarget struct {
 	v1alpha1.HorizontalRunnerAutoscaler
 	v1alpha1.ScaleUpTrigger
 
+	// operationID is the ID of the operation in the queue store, if any
+	operationID string
+
+	// job is the workflow job that triggered the scale, if any
+	job *WorkflowJobRef
+
 	log *logr.Logger
 }
 
@@ -447,7 +653,7 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleTarget(ctx co
 		return nil, nil
 	}
 
-	if n > 1 {
+	if n > 1 && autoscaler.ScaleTargetSelectionPolicy == "" {
 		var scaleTargetIDs []string
 
 		for _, t := range targets {
@@ -458,13 +664,20 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleTarget(ctx co
 			"Found too many scale targets: "+
 				"It must be exactly one to avoid ambiguity. "+
 				"Either set Namespace for the webhook-based autoscaler to let it only find HRAs in the namespace, "+
-				"or update Repository, Organization, or Enterprise fields in your RunnerDeployment resources to fix the ambiguity.",
+				"update Repository, Organization, or Enterprise fields in your RunnerDeployment resources to fix the ambiguity, "+
+				"or set the scale target selection policy to select one of them.",
 			"scaleTargets", strings.Join(scaleTargetIDs, ","))
 
 		return nil, nil
 	}
 
-	return &targets[0], nil
+	var candidates []scaleTargetCandidate
+
+	for _, t := range targets {
+		candidates = append(candidates, scaleTargetCandidate{target: t})
+	}
+
+	return autoscaler.selectScaleTarget(nil, candidates, true), nil
 }
 
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTarget(ctx context.Context, log logr.Logger, repo, owner, ownerType, enterprise string, f func(v1alpha1.ScaleUpTrigger) bool) (*ScaleTarget, error) {
@@ -474,14 +687,34 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTarget(ctx
 	return autoscaler.getScaleUpTargetWithFunction(ctx, log, repo, owner, ownerType, enterprise, scaleTarget)
 }
 
+// getJobScaleUpTargetForRepoOrOrg finds the HRA to scale for the workflow job.
+// job is the workflow job the reservation is added or removed for, and queued is true for the queued event.
+// See selectJobScaleTarget for how one of the matching HRAs is selected.
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleUpTargetForRepoOrOrg(
-	ctx context.Context, log logr.Logger, repo, owner, ownerType, enterprise string, labels []string,
+	ctx context.Context, log logr.Logger, repo, owner, ownerType, enterprise string, labels []string, job *WorkflowJobRef, queued bool, f func(v1alpha1.ScaleUpTrigger) bool,
 ) (*ScaleTarget, error) {
+	var sets [][]scaleTargetCandidate
 
+	// The candidates for all the repository, organization and enterprise runners are collected,
+	// so that the HRA holding the reservation for the job and the HRA to spill over to can be found across them
 	scaleTarget := func(value string) (*ScaleTarget, error) {
-		return autoscaler.getJobScaleTarget(ctx, value, labels)
+		candidates, err := autoscaler.getJobScaleTargetCandidates(ctx, value, labels, f)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(candidates) > 0 {
+			sets = append(sets, candidates)
+		}
+
+		return nil, nil
 	}
-	return autoscaler.getScaleUpTargetWithFunction(ctx, log, repo, owner, ownerType, enterprise, scaleTarget)
+
+	if _, err := autoscaler.getScaleUpTargetWithFunction(ctx, log, repo, owner, ownerType, enterprise, scaleTarget); err != nil {
+		return nil, err
+	}
+
+	return autoscaler.selectJobScaleTarget(labels, job, queued, sets), nil
 }
 
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTargetWithFunction(
@@ -586,7 +819,7 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTargetWithF
 	})
 
 	if traverseErr != nil {
-		return nil, err
+		return nil, traverseErr
 	}
 
 	if t == nil {
@@ -675,7 +908,8 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getManagedRunnerGroup
 	return groups, nil
 }
 
-func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleTarget(ctx context.Context, name string, labels []string) (*ScaleTarget, error) {
+// getJobScaleTargetCandidates returns the HRAs found by the key whose runners can run the workflow job with the labels.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleTargetCandidates(ctx context.Context, name string, labels []string, f func(v1alpha1.ScaleUpTrigger) bool) ([]scaleTargetCandidate, error) {
 	hras, err := autoscaler.findHRAsByKey(ctx, name)
 	if err != nil {
 		return nil, err
@@ -683,6 +917,8 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleTarget(ctx
 
 	autoscaler.Log.V(1).Info(fmt.Sprintf("Found %d HRAs by key", len(hras)), "key", name)
 
+	var candidates []scaleTargetCandidate
+
 HRA:
 	for _, hra := range hras {
 		if !hra.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -713,6 +949,12 @@ HRA:
 			continue
 		}
 
+		if f != nil && !f(scaleUpTrigger) {
+			autoscaler.Log.V(1).Info("Skipping this HRA as the workflow_job event doesn't match `githubEvent.workflowJob` filters", "hra", hra.Name)
+
+			continue
+		}
+
 		duration := scaleUpTrigger.Duration
 		if duration.Duration <= 0 {
 			// Try to release the reserved capacity after at least 10 minutes by default,
@@ -735,14 +977,15 @@ HRA:
 				var matched bool
 
 				// ignore "self-hosted" label as all instance here are self-hosted
-				if l == "self-hosted" {
+				if strings.EqualFold(l, "self-hosted") {
 					continue
 				}
 
 				// TODO labels related to OS and architecture needs to be explicitly declared or the current implementation will not be able to find them.
 
 				for _, l2 := range rs.Spec.Labels {
-					if l == l2 {
+					// Runner labels are case-insensitive
+					if strings.EqualFold(l, l2) {
 						matched = true
 						break
 					}
@@ -753,7 +996,10 @@ HRA:
 				}
 			}
 
-			return &ScaleTarget{HorizontalRunnerAutoscaler: hra, ScaleUpTrigger: v1alpha1.ScaleUpTrigger{Duration: duration}}, nil
+			candidates = append(candidates, scaleTargetCandidate{
+				target:       ScaleTarget{HorizontalRunnerAutoscaler: hra, ScaleUpTrigger: v1alpha1.ScaleUpTrigger{Duration: duration}},
+				runnerLabels: rs.Spec.Labels,
+			})
 		case "RunnerDeployment", "":
 			var rd v1alpha1.RunnerDeployment
 
@@ -766,14 +1012,15 @@ HRA:
 				var matched bool
 
 				// ignore "self-hosted" label as all instance here are self-hosted
-				if l == "self-hosted" {
+				if strings.EqualFold(l, "self-hosted") {
 					continue
 				}
 
 				// TODO labels related to OS and architecture needs to be explicitly declared or the current implementation will not be able to find them.
 
 				for _, l2 := range rd.Spec.Template.Spec.Labels {
-					if l == l2 {
+					// Runner labels are case-insensitive
+					if strings.EqualFold(l, l2) {
 						matched = true
 						break
 					}
@@ -784,21 +1031,40 @@ HRA:
 				}
 			}
 
-			return &ScaleTarget{HorizontalRunnerAutoscaler: hra, ScaleUpTrigger: v1alpha1.ScaleUpTrigger{Duration: duration}}, nil
+			candidates = append(candidates, scaleTargetCandidate{
+				target:       ScaleTarget{HorizontalRunnerAutoscaler: hra, ScaleUpTrigger: v1alpha1.ScaleUpTrigger{Duration: duration}},
+				runnerLabels: rd.Spec.Template.Spec.Labels,
+			})
 		default:
 			return nil, fmt.Errorf("unsupported scaleTargetRef.kind: %v", hra.Spec.ScaleTargetRef.Kind)
 		}
+
+		if autoscaler.ScaleTargetSelectionPolicy == "" {
+			// The first matching HRA wins, as before the selection policies were introduced
+			break
+		}
 	}
 
-	return nil, nil
+	return candidates, nil
 }
 
+// capacityReservations returns both the reservations declared in the spec and the ones made by the webhook-based autoscaler.
+func capacityReservations(hra v1alpha1.HorizontalRunnerAutoscaler) []v1alpha1.CapacityReservation {
+	var reservations []v1alpha1.CapacityReservation
+
+	reservations = append(reservations, hra.Spec.CapacityReservations...)
+	reservations = append(reservations, hra.Status.CapacityReservations...)
+
+	return reservations
+}
+
+// getValidCapacityReservations returns the unexpired reservations made by the webhook-based autoscaler.
 func getValidCapacityReservations(autoscaler *v1alpha1.HorizontalRunnerAutoscaler) []v1alpha1.CapacityReservation {
 	var capacityReservations []v1alpha1.CapacityReservation
 
 	now := time.Now()
 
-	for _, reservation := range autoscaler.Spec.CapacityReservations {
+	for _, reservation := range autoscaler.Status.CapacityReservations {
 		if reservation.ExpirationTime.Time.After(now) {
 			capacityReservations = append(capacityReservations, reservation)
 		}
@@ -883,6 +1149,23 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) SetupWithManager(mgr
 		return err
 	}
 
+	if autoscaler.QueueStore != nil {
+		// This runs after the cache is started, so that the replayed operations can find their HRAs
+		if err := mgr.Add(manager.RunnableFunc(autoscaler.replayQueue)); err != nil {
+			return err
+		}
+	}
+
+	if autoscaler.ReservationResyncInterval > 0 {
+		if autoscaler.GitHubClient == nil {
+			return fmt.Errorf("resyncing capacity reservations requires GitHub API credentials")
+		}
+
+		if err := mgr.Add(manager.RunnableFunc(autoscaler.runReservationResync)); err != nil {
+			return err
+		}
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.HorizontalRunnerAutoscaler{}).
 		Named(name).
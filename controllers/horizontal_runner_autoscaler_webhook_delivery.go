@@ -0,0 +1,292 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultDeliveryDeduplicationWindow is how long a webhook delivery ID is remembered.
+	// GitHub doesn't redeliver automatically, but manual and forwarded redeliveries usually happen within it.
+	DefaultDeliveryDeduplicationWindow = time.Hour
+
+	// DefaultMaxDeliveries is the maximum number of webhook delivery IDs remembered.
+	// The oldest ones are forgotten first when exceeded.
+	DefaultMaxDeliveries = 1000
+
+	// DefaultDeliveryStoreShards is the number of ConfigMaps the delivery IDs are spread over,
+	// so that concurrent deliveries rarely conflict on updating the same ConfigMap.
+	DefaultDeliveryStoreShards = 16
+
+	deliveryStoreMemory          = "memory"
+	deliveryStoreConfigMapPrefix = "configmap:"
+)
+
+// DeliveryStore remembers the IDs of the GitHub webhook deliveries seen within a time window,
+// so that the same delivery redelivered by GitHub or forwarded twice is processed only once.
+type DeliveryStore interface {
+	// Add records the delivery ID, returning false when it has already been recorded within the window.
+	Add(ctx context.Context, id string) (bool, error)
+	// Remove forgets the delivery ID, so that the delivery failed to be processed can be redelivered.
+	Remove(ctx context.Context, id string) error
+}
+
+// changesCapacityReservations returns true when the event with the action adds or removes a capacity reservation,
+// in which case processing its delivery twice would scale the HRA twice.
+func changesCapacityReservations(event, action string) bool {
+	return event == "workflow_job" && (action == "queued" || action == "completed")
+}
+
+// NewDeliveryStore creates the DeliveryStore from the spec, which is either "memory" or "configmap:<namespace>/<name>".
+// The memory store isn't shared across replicas of the webhook server.
+// The configmap store spreads the deliveries over the ConfigMaps named "<name>-0" to "<name>-15".
+func NewDeliveryStore(spec string, c client.Client, window time.Duration, max int) (DeliveryStore, error) {
+	if window <= 0 {
+		window = DefaultDeliveryDeduplicationWindow
+	}
+
+	if max <= 0 {
+		max = DefaultMaxDeliveries
+	}
+
+	switch {
+	case spec == deliveryStoreMemory:
+		return NewMemoryDeliveryStore(window, max), nil
+	case strings.HasPrefix(spec, deliveryStoreConfigMapPrefix):
+		nsName := strings.Split(strings.TrimPrefix(spec, deliveryStoreConfigMapPrefix), "/")
+		if len(nsName) != 2 || nsName[0] == "" || nsName[1] == "" {
+			return nil, fmt.Errorf("delivery store %q: configmap must be specified as <namespace>/<name>", spec)
+		}
+
+		return &ConfigMapDeliveryStore{
+			Client:         c,
+			NamespacedName: types.NamespacedName{Namespace: nsName[0], Name: nsName[1]},
+			Window:         window,
+			Max:            max,
+			Shards:         DefaultDeliveryStoreShards,
+		}, nil
+	default:
+		return nil, fmt.Errorf("delivery store %q: must be either %q or start with %q", spec, deliveryStoreMemory, deliveryStoreConfigMapPrefix)
+	}
+}
+
+// deliveries is a set of delivery IDs and the times they were seen, bounded in both time and size.
+type deliveries map[string]time.Time
+
+// prune removes the deliveries seen before the window, and then the oldest ones until the number of deliveries is below max.
+func (d deliveries) prune(now time.Time, window time.Duration, max int) {
+	for id, seen := range d {
+		if now.Sub(seen) >= window {
+			delete(d, id)
+		}
+	}
+
+	if len(d) < max {
+		return
+	}
+
+	ids := make([]string, 0, len(d))
+	for id := range d {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return d[ids[i]].Before(d[ids[j]])
+	})
+
+	for _, id := range ids[:len(ids)-max+1] {
+		delete(d, id)
+	}
+}
+
+// MemoryDeliveryStore is a DeliveryStore local to the process.
+type MemoryDeliveryStore struct {
+	window time.Duration
+	max    int
+
+	mu   sync.Mutex
+	seen deliveries
+
+	now func() time.Time
+}
+
+func NewMemoryDeliveryStore(window time.Duration, max int) *MemoryDeliveryStore {
+	return &MemoryDeliveryStore{
+		window: window,
+		max:    max,
+		seen:   deliveries{},
+		now:    time.Now,
+	}
+}
+
+func (s *MemoryDeliveryStore) Add(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	if seen, ok := s.seen[id]; ok && now.Sub(seen) < s.window {
+		return false, nil
+	}
+
+	s.seen.prune(now, s.window, s.max)
+	s.seen[id] = now
+
+	return true, nil
+}
+
+func (s *MemoryDeliveryStore) Remove(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.seen, id)
+
+	return nil
+}
+
+// ConfigMapDeliveryStore is a DeliveryStore shared across replicas of the webhook server.
+// It keeps the delivery IDs as the keys of ConfigMaps, and relies on the optimistic concurrency of the ConfigMap updates
+// to let only one of the replicas that received the same delivery record it.
+// The client should not be cached, so that every addition sees the latest deliveries.
+type ConfigMapDeliveryStore struct {
+	Client         client.Client
+	NamespacedName types.NamespacedName
+	Window         time.Duration
+	// Max is the maximum number of delivery IDs remembered across all the shards
+	Max int
+	// Shards is the number of ConfigMaps the delivery IDs are spread over by their hashes.
+	// Each shard is named "<name>-<index>". The single ConfigMap of the name is used when it's 0 or 1.
+	Shards int
+
+	now func() time.Time
+}
+
+// shard returns the ConfigMap the delivery ID is recorded to, and the maximum number of delivery IDs in it.
+func (s *ConfigMapDeliveryStore) shard(id string) (types.NamespacedName, int) {
+	if s.Shards <= 1 {
+		return s.NamespacedName, s.Max
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(id))
+
+	nsName := types.NamespacedName{
+		Namespace: s.NamespacedName.Namespace,
+		Name:      fmt.Sprintf("%s-%d", s.NamespacedName.Name, h.Sum32()%uint32(s.Shards)),
+	}
+
+	max := (s.Max + s.Shards - 1) / s.Shards
+
+	return nsName, max
+}
+
+func (s *ConfigMapDeliveryStore) timeNow() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+func (s *ConfigMapDeliveryStore) Add(ctx context.Context, id string) (bool, error) {
+	var added bool
+
+	nsName, max := s.shard(id)
+
+	// DefaultBackoff waits longer than DefaultRetry between attempts, so that a burst of deliveries
+	// to the same shard doesn't run out of the retries
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		added = false
+
+		now := s.timeNow()
+
+		var cm corev1.ConfigMap
+
+		if err := s.Client.Get(ctx, nsName, &cm); kerrors.IsNotFound(err) {
+			cm = corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: nsName.Namespace,
+					Name:      nsName.Name,
+				},
+				Data: map[string]string{id: now.Format(time.RFC3339Nano)},
+			}
+
+			err := s.Client.Create(ctx, &cm)
+			if kerrors.IsAlreadyExists(err) {
+				// Another replica created it concurrently. Retry adding to it
+				return kerrors.NewConflict(corev1.Resource("configmaps"), nsName.Name, err)
+			} else if err != nil {
+				return err
+			}
+
+			added = true
+
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		d := deliveries{}
+		for k, v := range cm.Data {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				// Drop the broken entry rather than failing forever
+				continue
+			}
+			d[k] = t
+		}
+
+		if seen, ok := d[id]; ok && now.Sub(seen) < s.Window {
+			return nil
+		}
+
+		d.prune(now, s.Window, max)
+		d[id] = now
+
+		cm.Data = map[string]string{}
+		for k, v := range d {
+			cm.Data[k] = v.Format(time.RFC3339Nano)
+		}
+
+		if err := s.Client.Update(ctx, &cm); err != nil {
+			return err
+		}
+
+		added = true
+
+		return nil
+	})
+
+	return added, err
+}
+
+func (s *ConfigMapDeliveryStore) Remove(ctx context.Context, id string) error {
+	nsName, _ := s.shard(id)
+
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		var cm corev1.ConfigMap
+
+		if err := s.Client.Get(ctx, nsName, &cm); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+
+		if _, ok := cm.Data[id]; !ok {
+			return nil
+		}
+
+		delete(cm.Data, id)
+
+		return s.Client.Update(ctx, &cm)
+	})
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testDeliveryStore(t *testing.T, store DeliveryStore, setNow func(time.Time)) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	now := time.Now()
+	setNow(now)
+
+	added, err := store.Add(ctx, "1")
+	require.NoError(t, err)
+	require.True(t, added)
+
+	added, err = store.Add(ctx, "1")
+	require.NoError(t, err)
+	require.False(t, added, "the same delivery must be added only once")
+
+	// A failed delivery is forgotten so that it can be redelivered
+	require.NoError(t, store.Remove(ctx, "1"))
+	require.NoError(t, store.Remove(ctx, "unknown"))
+
+	added, err = store.Add(ctx, "1")
+	require.NoError(t, err)
+	require.True(t, added)
+
+	now = now.Add(time.Second)
+	setNow(now)
+
+	for _, id := range []string{"2", "3"} {
+		added, err = store.Add(ctx, id)
+		require.NoError(t, err)
+		require.True(t, added)
+	}
+
+	// The oldest delivery is forgotten as the store can remember only 3 deliveries
+	added, err = store.Add(ctx, "4")
+	require.NoError(t, err)
+	require.True(t, added)
+
+	added, err = store.Add(ctx, "1")
+	require.NoError(t, err)
+	require.True(t, added)
+
+	// Every delivery seen before the window is forgotten
+	setNow(now.Add(time.Hour))
+
+	added, err = store.Add(ctx, "2")
+	require.NoError(t, err)
+	require.True(t, added)
+}
+
+func TestMemoryDeliveryStore(t *testing.T) {
+	store := NewMemoryDeliveryStore(time.Hour, 3)
+
+	testDeliveryStore(t, store, func(now time.Time) {
+		store.now = func() time.Time { return now }
+	})
+}
+
+func TestConfigMapDeliveryStore(t *testing.T) {
+	c := fake.NewFakeClientWithScheme(sc)
+
+	s, err := NewDeliveryStore("configmap:default/deliveries", c, time.Hour, 3)
+	require.NoError(t, err)
+
+	store := s.(*ConfigMapDeliveryStore)
+	store.Shards = 1
+
+	testDeliveryStore(t, store, func(now time.Time) {
+		store.now = func() time.Time { return now }
+	})
+
+	var cm corev1.ConfigMap
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "deliveries"}, &cm))
+	require.Len(t, cm.Data, 1)
+	require.Contains(t, cm.Data, "2")
+
+	// Another replica sharing the same configmap sees the deliveries
+	another := &ConfigMapDeliveryStore{Client: c, NamespacedName: store.NamespacedName, Window: time.Hour, Max: 3}
+
+	added, err := another.Add(context.Background(), "2")
+	require.NoError(t, err)
+	require.False(t, added)
+}
+
+func TestConfigMapDeliveryStore_Shards(t *testing.T) {
+	ctx := context.Background()
+
+	c := fake.NewFakeClientWithScheme(sc)
+
+	store, err := NewDeliveryStore("configmap:default/deliveries", c, time.Hour, 0)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		added, err := store.Add(ctx, fmt.Sprintf("delivery-%d", i))
+		require.NoError(t, err)
+		require.True(t, added)
+	}
+
+	var cms corev1.ConfigMapList
+	require.NoError(t, c.List(ctx, &cms))
+	require.Greater(t, len(cms.Items), 1, "deliveries must be spread over two or more configmaps")
+
+	for _, cm := range cms.Items {
+		require.Regexp(t, `^deliveries-\d+$`, cm.Name)
+	}
+
+	// Another replica sharing the same configmaps sees the deliveries
+	another, err := NewDeliveryStore("configmap:default/deliveries", c, time.Hour, 0)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		added, err := another.Add(ctx, fmt.Sprintf("delivery-%d", i))
+		require.NoError(t, err)
+		require.False(t, added)
+	}
+}
+
+func TestNewDeliveryStore_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "file:/tmp", "configmap:deliveries", "configmap:default/"} {
+		_, err := NewDeliveryStore(spec, nil, 0, 0)
+		require.Error(t, err, spec)
+	}
+}
+
+// failingListClient fails listing objects, so that the webhook server fails to find HRAs
+type failingListClient struct {
+	client.Client
+	fail bool
+}
+
+func (c *failingListClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if c.fail {
+		return errors.New("injected error")
+	}
+
+	return c.Client.List(ctx, list, opts...)
+}
+
+func TestWebhookDuplicateDelivery(t *testing.T) {
+	c := &failingListClient{Client: fake.NewFakeClientWithScheme(sc)}
+
+	hraWebhook := &HorizontalRunnerAutoscalerGitHubWebhook{
+		Client:        c,
+		Log:           logr.Discard(),
+		DeliveryStore: NewMemoryDeliveryStore(time.Hour, 10),
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(hraWebhook.Handle))
+	defer server.Close()
+
+	workflowJob := func(action string) string {
+		return fmt.Sprintf(`{"action": %q, "workflow_job": {"id": 1, "labels": ["self-hosted"]}, "repository": {"name": "myrepo", "owner": {"login": "myorg", "type": "Organization"}}}`, action)
+	}
+
+	send := func(eventType, delivery, payload string) (int, string) {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodPost, server.URL, bytes.NewBufferString(payload))
+		require.NoError(t, err)
+
+		req.Header.Set("X-GitHub-Event", eventType)
+		req.Header.Set("X-GitHub-Delivery", delivery)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := send("workflow_job", "a", workflowJob("queued"))
+	require.Equal(t, 200, code)
+	require.Equal(t, "no horizontalrunnerautoscaler to scale for this github event", body)
+
+	code, body = send("workflow_job", "a", workflowJob("queued"))
+	require.Equal(t, 200, code)
+	require.Equal(t, "ignored duplicate delivery", body)
+
+	// The events that don't change capacity reservations aren't deduplicated
+	for i := 0; i < 2; i++ {
+		code, body = send("ping", "b", `{"zen": "zen"}`)
+		require.Equal(t, 200, code)
+		require.Equal(t, "pong", body, fmt.Sprintf("attempt %d", i))
+	}
+
+	// The failed delivery must not be ignored on redelivery
+	c.fail = true
+	for i := 0; i < 2; i++ {
+		code, _ = send("workflow_job", "c", workflowJob("completed"))
+		require.Equal(t, 500, code, fmt.Sprintf("attempt %d", i))
+	}
+}
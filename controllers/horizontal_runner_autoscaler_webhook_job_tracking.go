@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v45/github"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/actions-runner-controller/actions-runner-controller/controllers/metrics"
+)
+
+const (
+	// LabelKeyWorkflowJobRunnerName is the label of a WorkflowJobRecord to select the job history of a runner
+	LabelKeyWorkflowJobRunnerName = "actions-runner-controller/runner-name"
+
+	EventReasonWorkflowJobStarted   = "WorkflowJobStarted"
+	EventReasonWorkflowJobCompleted = "WorkflowJobCompleted"
+)
+
+// workflowJobTracking is a workflow_job event enqueued to be tracked asynchronously.
+type workflowJobTracking struct {
+	log          logr.Logger
+	event        *gogithub.WorkflowJobEvent
+	workflowName string
+	enterprise   string
+	createdAt    time.Time
+}
+
+func workflowJobRecordName(jobID int64) string {
+	return "job-" + strconv.FormatInt(jobID, 10)
+}
+
+// enqueueWorkflowJobTracking enqueues the workflow_job event to be tracked by trackWorkflowJob in the background,
+// so that looking up the runner and updating the Runner and the WorkflowJobRecord don't delay the webhook response.
+// The event is dropped when the queue is full, as job tracking is informational.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) enqueueWorkflowJobTracking(log logr.Logger, e *gogithub.WorkflowJobEvent, workflowName, enterprise string, createdAt time.Time) {
+	autoscaler.jobTrackingInit.Do(autoscaler.initJobTracking)
+
+	select {
+	case autoscaler.jobTrackingQueue <- workflowJobTracking{log: log, event: e, workflowName: workflowName, enterprise: enterprise, createdAt: createdAt}:
+	default:
+		log.Info("Workflow job tracking queue is full. Skipped tracking the workflow job", "jobID", e.GetWorkflowJob().GetID())
+	}
+}
+
+// initJobTracking starts the goroutine that tracks the enqueued workflow_job events one by one,
+// so that the events of the same job are applied in the order they were received.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) initJobTracking() {
+	queueLimit := autoscaler.QueueLimit
+	if queueLimit == 0 {
+		queueLimit = DefaultQueueLimit
+	}
+
+	autoscaler.jobTrackingQueue = make(chan workflowJobTracking, queueLimit)
+
+	go func() {
+		for t := range autoscaler.jobTrackingQueue {
+			autoscaler.trackWorkflowJob(context.Background(), t.log, t.event, t.workflowName, t.enterprise, t.createdAt)
+		}
+	}()
+}
+
+// trackWorkflowJob correlates the workflow_job event with the Runner or the runner pod that picked up the job,
+// records the current job of the Runner, emits an event on the Runner or the pod, and records the lifecycle of the job
+// to its WorkflowJobRecord. createdAt is the created_at of the job in the event, which go-github doesn't parse.
+// Job tracking is informational, so the errors are logged rather than failing the webhook delivery.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) trackWorkflowJob(ctx context.Context, log logr.Logger, e *gogithub.WorkflowJobEvent, workflowName, enterprise string, createdAt time.Time) {
+	job := e.GetWorkflowJob()
+	action := e.GetAction()
+	owner := e.GetRepo().GetOwner().GetLogin()
+	repository := fmt.Sprintf("%s/%s", owner, e.GetRepo().GetName())
+
+	var runnerRef *v1alpha1.WorkflowJobRunnerRef
+
+	if runnerName := job.GetRunnerName(); runnerName != "" && (action == "in_progress" || action == "completed") {
+		var (
+			obj  client.Object
+			kind string
+		)
+
+		runner, err := autoscaler.findRunnerForJob(ctx, runnerName, repository, owner, enterprise)
+		if err != nil {
+			log.Error(err, "Failed to find the runner of the workflow job", "runner", runnerName)
+		} else if runner != nil {
+			autoscaler.updateRunnerCurrentJob(ctx, log, runner, e, workflowName)
+
+			obj, kind = runner, "Runner"
+		} else if pod, podErr := autoscaler.findRunnerPodForJob(ctx, runnerName, repository, owner, enterprise); podErr != nil {
+			err = podErr
+			log.Error(err, "Failed to find the runner pod of the workflow job", "runner", runnerName)
+		} else if pod != nil {
+			obj, kind = pod, "Pod"
+		}
+
+		if obj != nil {
+			autoscaler.recordWorkflowJobEvent(obj, e, workflowName, repository)
+
+			runnerRef = &v1alpha1.WorkflowJobRunnerRef{Kind: kind, Namespace: obj.GetNamespace(), Name: obj.GetName()}
+		} else if err == nil {
+			log.V(2).Info("Runner for the workflow job not found. It's either not managed by this controller or already deleted", "runner", runnerName)
+		}
+	}
+
+	if autoscaler.WorkflowJobRecordNamespace == "" {
+		return
+	}
+
+	if err := autoscaler.updateWorkflowJobRecord(ctx, e, workflowName, repository, runnerRef, workflowJobEventTime(e, createdAt, metav1.Now())); err != nil {
+		log.Error(err, "Failed to update the workflow job record", "jobID", job.GetID())
+	}
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) recordWorkflowJobEvent(obj runtime.Object, e *gogithub.WorkflowJobEvent, workflowName, repository string) {
+	job := e.GetWorkflowJob()
+
+	desc := fmt.Sprintf("workflow job %q of workflow %q in %s (run ID %d, job ID %d)", job.GetName(), workflowName, repository, job.GetRunID(), job.GetID())
+
+	switch e.GetAction() {
+	case "in_progress":
+		autoscaler.Recorder.Event(obj, corev1.EventTypeNormal, EventReasonWorkflowJobStarted, "Started "+desc)
+	case "completed":
+		eventType := corev1.EventTypeNormal
+		if job.GetConclusion() == "failure" {
+			eventType = corev1.EventTypeWarning
+		}
+
+		autoscaler.Recorder.Event(obj, eventType, EventReasonWorkflowJobCompleted, fmt.Sprintf("Completed %s with conclusion %s", desc, job.GetConclusion()))
+	}
+}
+
+// workflowJobEventTime returns the time GitHub set to the workflow job for the event, which is created_at for queued,
+// started_at for in_progress, and completed_at for completed. It returns now when the event has no such time.
+func workflowJobEventTime(e *gogithub.WorkflowJobEvent, createdAt time.Time, now metav1.Time) metav1.Time {
+	job := e.GetWorkflowJob()
+
+	var t time.Time
+
+	switch e.GetAction() {
+	case v1alpha1.WorkflowJobStatusQueued:
+		t = createdAt
+	case v1alpha1.WorkflowJobStatusInProgress:
+		t = job.GetStartedAt().Time
+	case v1alpha1.WorkflowJobStatusCompleted:
+		t = job.GetCompletedAt().Time
+	}
+
+	if t.IsZero() {
+		return now
+	}
+
+	return metav1.NewTime(t)
+}
+
+// updateWorkflowJobRecord creates the WorkflowJobRecord of the job on the first event of the job, and records the time
+// of each event to it. An event redelivered or delivered out of order never moves the status of the record backwards.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) updateWorkflowJobRecord(ctx context.Context, e *gogithub.WorkflowJobEvent, workflowName, repository string, runnerRef *v1alpha1.WorkflowJobRunnerRef, at metav1.Time) error {
+	job := e.GetWorkflowJob()
+	action := e.GetAction()
+
+	if action != v1alpha1.WorkflowJobStatusQueued && action != v1alpha1.WorkflowJobStatusInProgress && action != v1alpha1.WorkflowJobStatusCompleted {
+		return nil
+	}
+
+	key := types.NamespacedName{Namespace: autoscaler.WorkflowJobRecordNamespace, Name: workflowJobRecordName(job.GetID())}
+
+	var record v1alpha1.WorkflowJobRecord
+
+	if err := autoscaler.Get(ctx, key, &record); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+
+		record = v1alpha1.WorkflowJobRecord{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: key.Namespace,
+				Name:      key.Name,
+			},
+			Spec: v1alpha1.WorkflowJobRecordSpec{
+				Repository: repository,
+				Workflow:   workflowName,
+				RunID:      job.GetRunID(),
+				JobID:      job.GetID(),
+				JobName:    job.GetName(),
+				Labels:     job.Labels,
+			},
+		}
+
+		if err := autoscaler.Create(ctx, &record); kerrors.IsAlreadyExists(err) {
+			// Another event of the same job, e.g. one delivered to another replica, created it concurrently
+			if err := autoscaler.Get(ctx, key, &record); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return fmt.Errorf("creating workflow job record: %w", err)
+		}
+	}
+
+	runnerName := job.GetRunnerName()
+
+	if runnerName != "" && record.Labels[LabelKeyWorkflowJobRunnerName] != runnerName && len(validation.IsValidLabelValue(runnerName)) == 0 {
+		labeled := record.DeepCopy()
+		labeled.Labels = CloneAndAddLabel(labeled.Labels, LabelKeyWorkflowJobRunnerName, runnerName)
+
+		if err := autoscaler.Patch(ctx, labeled, client.MergeFrom(&record)); err != nil {
+			return fmt.Errorf("labeling workflow job record with the runner name: %w", err)
+		}
+
+		record = *labeled
+	}
+
+	updated := record.DeepCopy()
+	status := &updated.Status
+
+	switch action {
+	case v1alpha1.WorkflowJobStatusQueued:
+		if status.QueuedAt == nil {
+			status.QueuedAt = &at
+		}
+	case v1alpha1.WorkflowJobStatusInProgress:
+		if status.StartedAt == nil {
+			status.StartedAt = &at
+		}
+	case v1alpha1.WorkflowJobStatusCompleted:
+		if status.CompletedAt == nil {
+			status.CompletedAt = &at
+		}
+
+		status.Conclusion = job.GetConclusion()
+	}
+
+	if workflowJobStatusOrder(action) > workflowJobStatusOrder(status.Status) {
+		status.Status = action
+	}
+
+	if runnerName != "" {
+		status.RunnerName = runnerName
+	}
+
+	if runnerRef != nil {
+		status.RunnerRef = runnerRef
+	}
+
+	if status.QueuedAt != nil && status.StartedAt != nil {
+		status.QueueDuration = &metav1.Duration{Duration: status.StartedAt.Sub(status.QueuedAt.Time)}
+	}
+
+	if status.StartedAt != nil && status.CompletedAt != nil {
+		status.Duration = &metav1.Duration{Duration: status.CompletedAt.Sub(status.StartedAt.Time)}
+	}
+
+	if equality.Semantic.DeepEqual(record.Status, updated.Status) {
+		return nil
+	}
+
+	if err := autoscaler.Status().Patch(ctx, updated, client.MergeFrom(&record)); err != nil {
+		return fmt.Errorf("updating workflow job record status: %w", err)
+	}
+
+	return nil
+}
+
+func workflowJobStatusOrder(status string) int {
+	switch status {
+	case v1alpha1.WorkflowJobStatusQueued:
+		return 1
+	case v1alpha1.WorkflowJobStatusInProgress:
+		return 2
+	case v1alpha1.WorkflowJobStatusCompleted:
+		return 3
+	}
+
+	return 0
+}
+
+// observeWorkflowJobQueueDuration observes the queue duration of the workflow job picked up by a runner, on the in_progress event.
+// The durations are calculated from the times GitHub set to the job, so that they don't depend on the delivery delays of the webhook events.
+func observeWorkflowJobQueueDuration(e *gogithub.WorkflowJobEvent, createdAt time.Time, target *ScaleTarget) {
+	job := e.GetWorkflowJob()
+
+	if job.GetRunnerName() == "" || createdAt.IsZero() || job.StartedAt == nil {
+		return
+	}
+
+	if d := job.StartedAt.Sub(createdAt); d >= 0 {
+		metrics.ObserveWorkflowJobQueueDuration(workflowJobMetrics(e, target), d)
+	}
+}
+
+// observeWorkflowJobRunDuration observes the run duration of the completed workflow job, including the one canceled after it started.
+// The jobs never picked up by runners, like the ones skipped or canceled while queued, aren't observed.
+func observeWorkflowJobRunDuration(e *gogithub.WorkflowJobEvent, target *ScaleTarget) {
+	job := e.GetWorkflowJob()
+
+	if job.GetRunnerName() == "" || job.StartedAt == nil || job.CompletedAt == nil {
+		return
+	}
+
+	if d := job.CompletedAt.Sub(job.StartedAt.Time); d >= 0 {
+		metrics.ObserveWorkflowJobRunDuration(workflowJobMetrics(e, target), d)
+	}
+}
+
+func workflowJobMetrics(e *gogithub.WorkflowJobEvent, target *ScaleTarget) metrics.WorkflowJob {
+	job := e.GetWorkflowJob()
+
+	// Runner labels are case-insensitive
+	var labels []string
+	for _, l := range job.Labels {
+		labels = append(labels, strings.ToLower(l))
+	}
+	sort.Strings(labels)
+
+	m := metrics.WorkflowJob{
+		Repository:   fmt.Sprintf("%s/%s", e.GetRepo().GetOwner().GetLogin(), e.GetRepo().GetName()),
+		RunnerLabels: strings.Join(labels, ","),
+		Conclusion:   job.GetConclusion(),
+	}
+
+	if target != nil {
+		m.HRAName = target.HorizontalRunnerAutoscaler.Name
+		m.HRANamespace = target.HorizontalRunnerAutoscaler.Namespace
+	}
+
+	return m
+}
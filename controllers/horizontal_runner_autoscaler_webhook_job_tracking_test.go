@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v45/github"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+)
+
+func TestWebhookTrackWorkflowJob(t *testing.T) {
+	ctx := context.Background()
+
+	// A runner pod of a RunnerSet, that has no Runner
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "example-runnerset-0",
+			Labels:    map[string]string{LabelKeyRunnerSetName: "example-runnerset"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: containerName, Env: []corev1.EnvVar{{Name: EnvVarOrg, Value: "myorg"}}},
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(sc).WithObjects(pod).Build()
+
+	recorder := record.NewFakeRecorder(10)
+
+	webhook := &HorizontalRunnerAutoscalerGitHubWebhook{
+		Client:                     c,
+		Log:                        logr.Discard(),
+		Recorder:                   recorder,
+		WorkflowJobRecordNamespace: "arc-system",
+		RunnerPodReader:            c,
+	}
+
+	createdAt := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+	startedAt := createdAt.Add(time.Minute)
+	completedAt := startedAt.Add(10 * time.Minute)
+
+	event := func(action, runnerName, conclusion string) *github.WorkflowJobEvent {
+		job := &github.WorkflowJob{
+			ID:     github.Int64(1),
+			RunID:  github.Int64(100),
+			Name:   github.String("build"),
+			Labels: []string{"self-hosted", "linux"},
+		}
+
+		if runnerName != "" {
+			job.RunnerName = github.String(runnerName)
+			job.StartedAt = &github.Timestamp{Time: startedAt}
+		}
+
+		if action == "completed" {
+			job.CompletedAt = &github.Timestamp{Time: completedAt}
+		}
+
+		if conclusion != "" {
+			job.Conclusion = github.String(conclusion)
+		}
+
+		return &github.WorkflowJobEvent{
+			Action:      github.String(action),
+			WorkflowJob: job,
+			Repo: &github.Repository{
+				Name:  github.String("myrepo"),
+				Owner: &github.User{Login: github.String("myorg")},
+			},
+		}
+	}
+
+	getRecord := func() v1alpha1.WorkflowJobRecord {
+		var got v1alpha1.WorkflowJobRecord
+		require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "arc-system", Name: "job-1"}, &got))
+		return got
+	}
+
+	webhook.trackWorkflowJob(ctx, logr.Discard(), event("queued", "", ""), "CI", "", createdAt)
+
+	queued := getRecord()
+	require.Equal(t, v1alpha1.WorkflowJobRecordSpec{
+		Repository: "myorg/myrepo",
+		Workflow:   "CI",
+		RunID:      100,
+		JobID:      1,
+		JobName:    "build",
+		Labels:     []string{"self-hosted", "linux"},
+	}, queued.Spec)
+	require.Equal(t, v1alpha1.WorkflowJobStatusQueued, queued.Status.Status)
+	require.Equal(t, createdAt, queued.Status.QueuedAt.UTC())
+
+	webhook.trackWorkflowJob(ctx, logr.Discard(), event("in_progress", "example-runnerset-0", ""), "CI", "", createdAt)
+
+	started := getRecord()
+	require.Equal(t, v1alpha1.WorkflowJobStatusInProgress, started.Status.Status)
+	require.Equal(t, "example-runnerset-0", started.Status.RunnerName)
+	require.Equal(t, "example-runnerset-0", started.Labels[LabelKeyWorkflowJobRunnerName])
+	require.Equal(t, &v1alpha1.WorkflowJobRunnerRef{Kind: "Pod", Namespace: "default", Name: "example-runnerset-0"}, started.Status.RunnerRef)
+	require.Equal(t, startedAt, started.Status.StartedAt.UTC())
+	require.Equal(t, &metav1.Duration{Duration: time.Minute}, started.Status.QueueDuration)
+	require.Contains(t, <-recorder.Events, EventReasonWorkflowJobStarted)
+
+	webhook.trackWorkflowJob(ctx, logr.Discard(), event("completed", "example-runnerset-0", "failure"), "CI", "", createdAt)
+
+	completed := getRecord()
+	require.Equal(t, v1alpha1.WorkflowJobStatusCompleted, completed.Status.Status)
+	require.Equal(t, "failure", completed.Status.Conclusion)
+	require.Equal(t, completedAt, completed.Status.CompletedAt.UTC())
+	require.Equal(t, &metav1.Duration{Duration: 10 * time.Minute}, completed.Status.Duration)
+	require.Equal(t, "Warning "+EventReasonWorkflowJobCompleted+` Completed workflow job "build" of workflow "CI" in myorg/myrepo (run ID 100, job ID 1) with conclusion failure`, <-recorder.Events)
+
+	// A redelivered in_progress event doesn't move the record backwards
+	webhook.trackWorkflowJob(ctx, logr.Discard(), event("in_progress", "example-runnerset-0", ""), "CI", "", createdAt)
+
+	redelivered := getRecord()
+	require.Equal(t, v1alpha1.WorkflowJobStatusCompleted, redelivered.Status.Status)
+	require.Equal(t, completed.Status.StartedAt, redelivered.Status.StartedAt)
+}
+
+// racingRecordClient misses the WorkflowJobRecord on the first get, as if another event of the same job created it concurrently
+type racingRecordClient struct {
+	client.Client
+	missed bool
+}
+
+func (c *racingRecordClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if _, ok := obj.(*v1alpha1.WorkflowJobRecord); ok && !c.missed {
+		c.missed = true
+		return kerrors.NewNotFound(schema.GroupResource{Group: v1alpha1.GroupVersion.Group, Resource: "workflowjobrecords"}, key.Name)
+	}
+
+	return c.Client.Get(ctx, key, obj)
+}
+
+func TestUpdateWorkflowJobRecord_AlreadyExists(t *testing.T) {
+	ctx := context.Background()
+
+	existing := &v1alpha1.WorkflowJobRecord{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "arc-system", Name: "job-1"},
+		Spec:       v1alpha1.WorkflowJobRecordSpec{Repository: "myorg/myrepo", RunID: 100, JobID: 1, JobName: "build"},
+	}
+
+	c := &racingRecordClient{Client: fake.NewClientBuilder().WithScheme(sc).WithObjects(existing).Build()}
+
+	webhook := &HorizontalRunnerAutoscalerGitHubWebhook{
+		Client:                     c,
+		Log:                        logr.Discard(),
+		WorkflowJobRecordNamespace: "arc-system",
+	}
+
+	e := &github.WorkflowJobEvent{
+		Action:      github.String("queued"),
+		WorkflowJob: &github.WorkflowJob{ID: github.Int64(1), RunID: github.Int64(100), Name: github.String("build")},
+	}
+
+	queuedAt := metav1.NewTime(time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	require.NoError(t, webhook.updateWorkflowJobRecord(ctx, e, "CI", "myorg/myrepo", nil, queuedAt))
+
+	var got v1alpha1.WorkflowJobRecord
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "arc-system", Name: "job-1"}, &got))
+	require.Equal(t, v1alpha1.WorkflowJobStatusQueued, got.Status.Status)
+	require.True(t, queuedAt.Equal(got.Status.QueuedAt))
+}
+
+func TestWebhookEnqueueWorkflowJobTracking(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(sc).Build()
+
+	webhook := &HorizontalRunnerAutoscalerGitHubWebhook{
+		Client:                     c,
+		Log:                        logr.Discard(),
+		Recorder:                   record.NewFakeRecorder(10),
+		WorkflowJobRecordNamespace: "arc-system",
+	}
+
+	e := &github.WorkflowJobEvent{
+		Action:      github.String("queued"),
+		WorkflowJob: &github.WorkflowJob{ID: github.Int64(1), RunID: github.Int64(100), Name: github.String("build")},
+		Repo: &github.Repository{
+			Name:  github.String("myrepo"),
+			Owner: &github.User{Login: github.String("myorg")},
+		},
+	}
+
+	webhook.enqueueWorkflowJobTracking(logr.Discard(), e, "CI", "", time.Time{})
+
+	require.Eventually(t, func() bool {
+		var got v1alpha1.WorkflowJobRecord
+		return c.Get(context.Background(), types.NamespacedName{Namespace: "arc-system", Name: "job-1"}, &got) == nil
+	}, 5*time.Second, 10*time.Millisecond, "the workflow job must be tracked in the background")
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	actionsv1alpha1 "github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+)
+
+func TestWebhookWorkflowJobMetrics(t *testing.T) {
+	hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "metrics-hra", Namespace: "default"},
+		Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+			ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{Name: "metrics-rd"},
+			ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+				{
+					GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+						WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{},
+					},
+				},
+			},
+		},
+	}
+
+	rd := &actionsv1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "metrics-rd", Namespace: "default"},
+		Spec: actionsv1alpha1.RunnerDeploymentSpec{
+			Template: actionsv1alpha1.RunnerTemplate{
+				Spec: actionsv1alpha1.RunnerSpec{
+					RunnerConfig: actionsv1alpha1.RunnerConfig{
+						Repository: "myorg/metrics-repo",
+						Labels:     []string{"Linux"},
+					},
+				},
+			},
+		},
+	}
+
+	// created_at isn't in go-github's WorkflowJob, so the payload is given as is
+	event := func(id int, action, conclusion, completedAt string) map[string]interface{} {
+		job := map[string]interface{}{
+			"id":          id,
+			"run_id":      100,
+			"name":        "build",
+			"status":      action,
+			"labels":      []string{"self-hosted", "Linux"},
+			"runner_name": "metrics-rd-abcde",
+			"created_at":  "2022-06-01T00:00:00Z",
+			"started_at":  "2022-06-01T00:00:30Z",
+		}
+
+		if action == "completed" {
+			job["conclusion"] = conclusion
+			job["completed_at"] = completedAt
+		}
+
+		return map[string]interface{}{
+			"action":       action,
+			"workflow_job": job,
+			"repository": map[string]interface{}{
+				"name": "metrics-repo",
+				"owner": map[string]interface{}{
+					"login": "myorg",
+					"type":  "Organization",
+				},
+			},
+		}
+	}
+
+	labels := map[string]string{
+		"repository":                 "myorg/metrics-repo",
+		"runner_labels":              "linux,self-hosted",
+		"horizontalrunnerautoscaler": "metrics-hra",
+		"namespace":                  "default",
+	}
+
+	withConclusion := func(conclusion string) map[string]string {
+		m := map[string]string{"conclusion": conclusion}
+		for k, v := range labels {
+			m[k] = v
+		}
+		return m
+	}
+
+	// The queue duration is observed as soon as a runner picks up the job
+	testServerWithInitObjs(t, "workflow_job", event(1, "in_progress", "", ""), 200, "", []runtime.Object{hra, rd})
+
+	count, sum, _ := gatherMetric(t, "github_workflow_job_queue_duration_seconds", labels)
+	require.Equal(t, uint64(1), count)
+	require.Equal(t, float64(30), sum)
+
+	testServerWithInitObjs(t, "workflow_job", event(1, "completed", "success", "2022-06-01T00:05:30Z"), 200, "scaled metrics-hra by -1", []runtime.Object{hra, rd})
+
+	count, _, _ = gatherMetric(t, "github_workflow_job_queue_duration_seconds", labels)
+	require.Equal(t, uint64(1), count, "the queue duration must not be observed again on completion")
+
+	count, sum, _ = gatherMetric(t, "github_workflow_job_run_duration_seconds", withConclusion("success"))
+	require.Equal(t, uint64(1), count)
+	require.Equal(t, float64(300), sum)
+
+	// The job canceled after it started is observed too
+	testServerWithInitObjs(t, "workflow_job", event(2, "completed", "cancelled", "2022-06-01T00:01:30Z"), 200, "scaled metrics-hra by -1", []runtime.Object{hra, rd})
+
+	count, sum, _ = gatherMetric(t, "github_workflow_job_run_duration_seconds", withConclusion("cancelled"))
+	require.Equal(t, uint64(1), count)
+	require.Equal(t, float64(60), sum)
+
+	_, _, received := gatherMetric(t, "github_webhook_events_received_total", map[string]string{"event": "workflow_job", "action": "completed"})
+	require.GreaterOrEqual(t, received, float64(1))
+
+	_, _, matched := gatherMetric(t, "github_webhook_events_processed_total", map[string]string{"event": "workflow_job", "action": "completed", "result": "matched"})
+	require.GreaterOrEqual(t, matched, float64(1))
+}
+
+// gatherMetric returns the sample count and sum of the histogram, or the value of the counter, with the labels
+func gatherMetric(t *testing.T, name string, labels map[string]string) (uint64, float64, float64) {
+	t.Helper()
+
+	families, err := ctrlmetrics.Registry.Gather()
+	require.NoError(t, err)
+
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+
+	metrics:
+		for _, m := range f.GetMetric() {
+			got := map[string]string{}
+			for _, l := range m.GetLabel() {
+				got[l.GetName()] = l.GetValue()
+			}
+
+			for k, v := range labels {
+				if got[k] != v {
+					continue metrics
+				}
+			}
+
+			return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum(), m.GetCounter().GetValue()
+		}
+	}
+
+	t.Fatalf("metric %s with labels %v not found", name, labels)
+
+	return 0, 0, 0
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/actions-runner-controller/actions-runner-controller/pkg/actionsglob"
+	"github.com/google/go-github/v45/github"
+)
+
+// MatchWorkflowJobEvent returns a function that checks if the workflow_job event satisfies the filters
+// specified in the `githubEvent.workflowJob` scale trigger.
+// workflowName and headBranch are passed separately because go-github's WorkflowJob type lacks the corresponding fields.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) MatchWorkflowJobEvent(event *github.WorkflowJobEvent, workflowName, headBranch string) func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+	return func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+		g := scaleUpTrigger.GitHubEvent
+
+		if g == nil {
+			return false
+		}
+
+		wj := g.WorkflowJob
+
+		if wj == nil {
+			return false
+		}
+
+		if len(wj.Repositories) > 0 {
+			var matched bool
+
+			for _, repository := range wj.Repositories {
+				if repository == event.Repo.GetName() || repository == event.Repo.GetFullName() {
+					matched = true
+					break
+				}
+			}
+
+			if !matched {
+				return false
+			}
+		}
+
+		if !matchGlobPatterns(wj.WorkflowNames, workflowName) {
+			return false
+		}
+
+		job := event.GetWorkflowJob()
+
+		if !matchGlobPatterns(wj.Names, job.GetName()) {
+			return false
+		}
+
+		if !matchGlobPatterns(wj.Branches, headBranch) {
+			return false
+		}
+
+		// Runner labels are case-insensitive
+		jobLabels := make(map[string]struct{}, len(job.Labels))
+		for _, l := range job.Labels {
+			jobLabels[strings.ToLower(l)] = struct{}{}
+		}
+
+		for _, l := range wj.Labels {
+			if _, ok := jobLabels[strings.ToLower(l)]; !ok {
+				return false
+			}
+		}
+
+		for _, l := range wj.ExcludedLabels {
+			if _, ok := jobLabels[strings.ToLower(l)]; ok {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// matchGlobPatterns returns true when patterns is empty, or the value matches any of the GitHub Actions glob patterns.
+func matchGlobPatterns(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, pat := range patterns {
+		// actionsglob.Match panics on an empty pattern.
+		// The HRA validation webhook rejects empty patterns, but the ones created before the webhook existed can remain.
+		if pat == "" {
+			continue
+		}
+
+		if actionsglob.Match(pat, value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,720 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultQueueReplayInterval is how often the pending scale operations in the persistent queue
+	// that aren't being processed are enqueued again.
+	DefaultQueueReplayInterval = 30 * time.Second
+
+	// queueClaimLeaseIntervals is the number of replay intervals a claim on a pending scale operation lasts.
+	// Each replay renews the claims of the process, so that a claim expires only when the process stops replaying.
+	queueClaimLeaseIntervals = 10
+
+	// DefaultPendingScaleOperationTTL is how long a pending scale operation is kept in the store.
+	// An operation not applied within it, like the one for an HRA that no longer exists, is dropped.
+	// An operation with a longer duration is kept until its capacity reservation would have expired.
+	DefaultPendingScaleOperationTTL = time.Hour
+
+	// DefaultMaxPendingScaleOperations is the maximum number of pending scale operations kept in a ConfigMap store,
+	// which keeps the ConfigMap well below the size limit of an object. The oldest ones are dropped first when exceeded.
+	DefaultMaxPendingScaleOperations = 1000
+
+	queueStoreFilePrefix      = "file:"
+	queueStoreConfigMapPrefix = "configmap:"
+)
+
+// PendingScaleOperation is a scale operation received by the webhook server but not applied to the HRA yet.
+type PendingScaleOperation struct {
+	// ID is unique to the operation. IDs sort in the order the operations were received.
+	ID string `json:"id"`
+
+	HorizontalRunnerAutoscaler types.NamespacedName `json:"horizontalRunnerAutoscaler"`
+
+	Amount   int             `json:"amount,omitempty"`
+	Duration metav1.Duration `json:"duration,omitempty"`
+
+	// Job is the workflow job that triggered the operation, if any
+	Job *WorkflowJobRef `json:"job,omitempty"`
+
+	ReceivedAt metav1.Time `json:"receivedAt"`
+
+	// ClaimedBy is the webhook server process that is applying the operation, and ClaimExpiresAt is when the claim expires.
+	// An operation claimed by another process is applied only after the claim expires, so that two or more webhook server
+	// replicas sharing the store never apply the same operation twice.
+	ClaimedBy      string       `json:"claimedBy,omitempty"`
+	ClaimExpiresAt *metav1.Time `json:"claimExpiresAt,omitempty"`
+}
+
+// claimableBy returns true when the operation is either unclaimed, claimed by the owner, or claimed by another owner
+// but the claim has expired.
+func (op PendingScaleOperation) claimableBy(owner string, now time.Time) bool {
+	return op.ClaimedBy == "" || op.ClaimedBy == owner || op.ClaimExpiresAt == nil || !now.Before(op.ClaimExpiresAt.Time)
+}
+
+// expired returns true when the operation has been pending longer than the TTL and its duration.
+func (op PendingScaleOperation) expired(now time.Time, ttl time.Duration) bool {
+	if op.Duration.Duration > ttl {
+		ttl = op.Duration.Duration
+	}
+
+	return now.Sub(op.ReceivedAt.Time) >= ttl
+}
+
+// claim claims the operation for the owner until now plus the lease.
+func (op *PendingScaleOperation) claim(owner string, now time.Time, lease time.Duration) {
+	op.ClaimedBy = owner
+	op.ClaimExpiresAt = &metav1.Time{Time: now.Add(lease)}
+}
+
+// ScaleOperationStore persists the pending scale operations of the webhook server,
+// so that the operations received but not applied before a restart are replayed on the next start.
+type ScaleOperationStore interface {
+	// Save persists the operation.
+	Save(ctx context.Context, op PendingScaleOperation) error
+	// Ack removes the operations that have been applied.
+	Ack(ctx context.Context, ids ...string) error
+	// List returns all the pending operations, the oldest first.
+	List(ctx context.Context) ([]PendingScaleOperation, error)
+	// Claim claims the operations for the owner until the lease expires, and returns the IDs of the claimed ones.
+	// An operation claimed by another owner is skipped until its claim expires,
+	// and claiming an operation already claimed by the owner renews the claim.
+	Claim(ctx context.Context, owner string, lease time.Duration, ids ...string) ([]string, error)
+}
+
+// NewScaleOperationStore creates the ScaleOperationStore from the spec,
+// which is either "file:<directory>" or "configmap:<namespace>/<name>".
+func NewScaleOperationStore(spec string, c client.Client) (ScaleOperationStore, error) {
+	switch {
+	case strings.HasPrefix(spec, queueStoreFilePrefix):
+		dir := strings.TrimPrefix(spec, queueStoreFilePrefix)
+		if dir == "" {
+			return nil, fmt.Errorf("queue store %q: directory must not be empty", spec)
+		}
+
+		return NewFileScaleOperationStore(dir)
+	case strings.HasPrefix(spec, queueStoreConfigMapPrefix):
+		nsName := strings.Split(strings.TrimPrefix(spec, queueStoreConfigMapPrefix), "/")
+		if len(nsName) != 2 || nsName[0] == "" || nsName[1] == "" {
+			return nil, fmt.Errorf("queue store %q: configmap must be specified as <namespace>/<name>", spec)
+		}
+
+		return &ConfigMapScaleOperationStore{
+			Client:         c,
+			NamespacedName: types.NamespacedName{Namespace: nsName[0], Name: nsName[1]},
+			TTL:            DefaultPendingScaleOperationTTL,
+			Max:            DefaultMaxPendingScaleOperations,
+		}, nil
+	default:
+		return nil, fmt.Errorf("queue store %q: must start with either %q or %q", spec, queueStoreFilePrefix, queueStoreConfigMapPrefix)
+	}
+}
+
+func newPendingScaleOperationID(now time.Time) string {
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+
+	return fmt.Sprintf("%019d-%s", now.UnixNano(), hex.EncodeToString(b[:]))
+}
+
+// FileScaleOperationStore stores each pending scale operation as a JSON file in the directory,
+// which is usually backed by a persistent volume.
+// Claims on the operations aren't atomic across processes, so the directory must not be shared among webhook server replicas.
+type FileScaleOperationStore struct {
+	Dir string
+
+	// mu prevents a claim from writing back an operation being removed by an ack
+	mu sync.Mutex
+}
+
+func NewFileScaleOperationStore(dir string) (*FileScaleOperationStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating queue directory %s: %w", dir, err)
+	}
+
+	return &FileScaleOperationStore{Dir: dir}, nil
+}
+
+func (s *FileScaleOperationStore) Save(_ context.Context, op PendingScaleOperation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.save(op)
+}
+
+func (s *FileScaleOperationStore) save(op PendingScaleOperation) error {
+	data, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename it, so that a crash never leaves a partially written operation
+	tmp := filepath.Join(s.Dir, "."+op.ID+".tmp")
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing scale operation %s: %w", op.ID, err)
+	}
+
+	if err := os.Rename(tmp, filepath.Join(s.Dir, op.ID+".json")); err != nil {
+		return fmt.Errorf("writing scale operation %s: %w", op.ID, err)
+	}
+
+	return nil
+}
+
+func (s *FileScaleOperationStore) Ack(_ context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		if err := os.Remove(filepath.Join(s.Dir, id+".json")); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing scale operation %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *FileScaleOperationStore) Claim(_ context.Context, owner string, lease time.Duration, ids ...string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	var claimed []string
+
+	for _, id := range ids {
+		data, err := ioutil.ReadFile(filepath.Join(s.Dir, id+".json"))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return claimed, fmt.Errorf("reading scale operation %s: %w", id, err)
+		}
+
+		var op PendingScaleOperation
+		if err := json.Unmarshal(data, &op); err != nil {
+			return claimed, fmt.Errorf("decoding scale operation %s: %w", id, err)
+		}
+
+		if !op.claimableBy(owner, now) {
+			continue
+		}
+
+		op.claim(owner, now, lease)
+
+		if err := s.save(op); err != nil {
+			return claimed, err
+		}
+
+		claimed = append(claimed, id)
+	}
+
+	return claimed, nil
+}
+
+func (s *FileScaleOperationStore) List(_ context.Context) ([]PendingScaleOperation, error) {
+	entries, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading queue directory %s: %w", s.Dir, err)
+	}
+
+	var ops []PendingScaleOperation
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(s.Dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading scale operation %s: %w", e.Name(), err)
+		}
+
+		var op PendingScaleOperation
+		if err := json.Unmarshal(data, &op); err != nil {
+			return nil, fmt.Errorf("decoding scale operation %s: %w", e.Name(), err)
+		}
+
+		ops = append(ops, op)
+	}
+
+	sortPendingScaleOperations(ops)
+
+	return ops, nil
+}
+
+// ConfigMapScaleOperationStore stores the pending scale operations in a ConfigMap, one operation per key.
+// The client should not be cached, so that the operations saved by the previous process are seen on startup.
+// It can be shared among webhook server replicas, as claims are made with updates guarded by the resource version of the ConfigMap.
+type ConfigMapScaleOperationStore struct {
+	Client         client.Client
+	NamespacedName types.NamespacedName
+
+	// TTL is how long an operation is kept. The expired operations are dropped on each save.
+	TTL time.Duration
+	// Max is the maximum number of operations kept. The oldest ones are dropped on save when exceeded.
+	Max int
+}
+
+// prune drops the expired operations, and then the oldest ones until there's room for one more operation.
+// The operations that can't be decoded are dropped too, as they can never be applied.
+func (s *ConfigMapScaleOperationStore) prune(data map[string]string, now time.Time) {
+	for k, v := range data {
+		var op PendingScaleOperation
+		if err := json.Unmarshal([]byte(v), &op); err != nil || (s.TTL > 0 && op.expired(now, s.TTL)) {
+			delete(data, k)
+		}
+	}
+
+	if s.Max <= 0 || len(data) < s.Max {
+		return
+	}
+
+	// IDs sort in the order the operations were received
+	ids := make([]string, 0, len(data))
+	for id := range data {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids[:len(ids)-s.Max+1] {
+		delete(data, id)
+	}
+}
+
+func (s *ConfigMapScaleOperationStore) Save(ctx context.Context, op PendingScaleOperation) error {
+	data, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var cm corev1.ConfigMap
+
+		if err := s.Client.Get(ctx, s.NamespacedName, &cm); kerrors.IsNotFound(err) {
+			cm = corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: s.NamespacedName.Namespace,
+					Name:      s.NamespacedName.Name,
+				},
+				Data: map[string]string{op.ID: string(data)},
+			}
+
+			err := s.Client.Create(ctx, &cm)
+			if kerrors.IsAlreadyExists(err) {
+				// Another save created it concurrently. Retry updating it
+				return kerrors.NewConflict(corev1.Resource("configmaps"), s.NamespacedName.Name, err)
+			}
+
+			return err
+		} else if err != nil {
+			return err
+		}
+
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		s.prune(cm.Data, time.Now())
+		cm.Data[op.ID] = string(data)
+
+		return s.Client.Update(ctx, &cm)
+	})
+}
+
+func (s *ConfigMapScaleOperationStore) Ack(ctx context.Context, ids ...string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var cm corev1.ConfigMap
+
+		if err := s.Client.Get(ctx, s.NamespacedName, &cm); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+
+		var removed bool
+		for _, id := range ids {
+			if _, ok := cm.Data[id]; ok {
+				delete(cm.Data, id)
+				removed = true
+			}
+		}
+
+		if !removed {
+			return nil
+		}
+
+		return s.Client.Update(ctx, &cm)
+	})
+}
+
+func (s *ConfigMapScaleOperationStore) Claim(ctx context.Context, owner string, lease time.Duration, ids ...string) ([]string, error) {
+	var claimed []string
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		claimed = nil
+
+		var cm corev1.ConfigMap
+
+		if err := s.Client.Get(ctx, s.NamespacedName, &cm); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+
+		now := time.Now()
+
+		for _, id := range ids {
+			v, ok := cm.Data[id]
+			if !ok {
+				continue
+			}
+
+			var op PendingScaleOperation
+			if err := json.Unmarshal([]byte(v), &op); err != nil {
+				return fmt.Errorf("decoding scale operation %s: %w", id, err)
+			}
+
+			if !op.claimableBy(owner, now) {
+				continue
+			}
+
+			op.claim(owner, now, lease)
+
+			data, err := json.Marshal(op)
+			if err != nil {
+				return err
+			}
+
+			cm.Data[id] = string(data)
+			claimed = append(claimed, id)
+		}
+
+		if len(claimed) == 0 {
+			return nil
+		}
+
+		// The update fails with a conflict when another replica has updated the ConfigMap since the Get,
+		// in which case the claims are made again against the latest operations
+		return s.Client.Update(ctx, &cm)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+func (s *ConfigMapScaleOperationStore) List(ctx context.Context) ([]PendingScaleOperation, error) {
+	var cm corev1.ConfigMap
+
+	if err := s.Client.Get(ctx, s.NamespacedName, &cm); kerrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var ops []PendingScaleOperation
+
+	for k, v := range cm.Data {
+		var op PendingScaleOperation
+		if err := json.Unmarshal([]byte(v), &op); err != nil {
+			return nil, fmt.Errorf("decoding scale operation %s: %w", k, err)
+		}
+
+		ops = append(ops, op)
+	}
+
+	sortPendingScaleOperations(ops)
+
+	return ops, nil
+}
+
+func sortPendingScaleOperations(ops []PendingScaleOperation) {
+	sort.SliceStable(ops, func(i, j int) bool {
+		return ops[i].ID < ops[j].ID
+	})
+}
+
+// persistentScaleQueue tracks the scale operations persisted in the store until they're acknowledged.
+// An operation is either in flight, that is, in the in-memory queues of the worker and the batch scaler,
+// or pending in the store, in which case it's enqueued again on the next replay.
+// Only the operations claimed by the owner are enqueued, so that the replicas sharing the store don't apply the same operation.
+type persistentScaleQueue struct {
+	store ScaleOperationStore
+	log   logr.Logger
+
+	// owner identifies this process in the claims on the operations
+	owner string
+	lease time.Duration
+
+	// ttl is how long an operation is replayed before it's dropped from the store
+	ttl time.Duration
+
+	mu       sync.Mutex
+	inFlight map[string]struct{}
+	// unacked is the set of operations that have been applied but failed to be removed from the store
+	unacked map[string]struct{}
+}
+
+func newPersistentScaleQueue(store ScaleOperationStore, owner string, lease time.Duration, log logr.Logger) *persistentScaleQueue {
+	return &persistentScaleQueue{
+		store:    store,
+		log:      log,
+		owner:    owner,
+		lease:    lease,
+		ttl:      DefaultPendingScaleOperationTTL,
+		inFlight: map[string]struct{}{},
+		unacked:  map[string]struct{}{},
+	}
+}
+
+// scaleQueueOwner returns the name of this webhook server process in the claims on the pending scale operations.
+// It's the hostname, that is the pod name, so that a restarted container resumes the operations claimed before the restart.
+func scaleQueueOwner() string {
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return hostname
+	}
+
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+
+	return hex.EncodeToString(b[:])
+}
+
+// save persists the scale target as a pending operation and sets the operation ID to it.
+func (q *persistentScaleQueue) save(ctx context.Context, target *ScaleTarget) error {
+	now := time.Now()
+
+	op := PendingScaleOperation{
+		ID: newPendingScaleOperationID(now),
+		HorizontalRunnerAutoscaler: types.NamespacedName{
+			Namespace: target.HorizontalRunnerAutoscaler.Namespace,
+			Name:      target.HorizontalRunnerAutoscaler.Name,
+		},
+		Amount:     target.ScaleUpTrigger.Amount,
+		Duration:   target.ScaleUpTrigger.Duration,
+		Job:        target.job,
+		ReceivedAt: metav1.Time{Time: now},
+	}
+
+	// The operation is claimed on save, as this process applies it right away
+	op.claim(q.owner, now, q.lease)
+
+	if err := q.store.Save(ctx, op); err != nil {
+		return fmt.Errorf("persisting scale operation: %w", err)
+	}
+
+	target.operationID = op.ID
+
+	return nil
+}
+
+// enqueue tries to add the persisted scale target to the in-memory queue.
+// It returns false when the queue is full, in which case the target is left pending in the store and enqueued on a later replay.
+func (q *persistentScaleQueue) enqueue(target *ScaleTarget, add func(*ScaleTarget) bool) bool {
+	q.mu.Lock()
+	if _, ok := q.inFlight[target.operationID]; ok {
+		q.mu.Unlock()
+		return true
+	}
+	q.inFlight[target.operationID] = struct{}{}
+	q.mu.Unlock()
+
+	if add(target) {
+		return true
+	}
+
+	q.mu.Lock()
+	delete(q.inFlight, target.operationID)
+	q.mu.Unlock()
+
+	return false
+}
+
+// ack removes the applied operations from the store.
+func (q *persistentScaleQueue) ack(ctx context.Context, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+
+	err := q.store.Ack(ctx, ids...)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, id := range ids {
+		delete(q.inFlight, id)
+
+		if err != nil {
+			q.unacked[id] = struct{}{}
+		}
+	}
+
+	if err != nil {
+		q.log.Error(err, "Failed to remove applied scale operations from the queue store. Retrying on the next replay", "ids", ids)
+	}
+}
+
+// replay claims the pending operations in the store that aren't claimed by other processes, renewing the claims
+// on the ones in flight, and enqueues the claimed ones that aren't in flight, the oldest first,
+// until the in-memory queue becomes full.
+func (q *persistentScaleQueue) replay(ctx context.Context, add func(*ScaleTarget) bool) error {
+	q.mu.Lock()
+	var unacked []string
+	for id := range q.unacked {
+		unacked = append(unacked, id)
+	}
+	q.mu.Unlock()
+
+	if len(unacked) > 0 {
+		if err := q.store.Ack(ctx, unacked...); err != nil {
+			// The claims are still renewed below, so that no other process applies them again in the meantime
+			q.log.Error(err, "Failed to remove applied scale operations from the queue store. Retrying on the next replay", "ids", unacked)
+		} else {
+			q.mu.Lock()
+			for _, id := range unacked {
+				delete(q.unacked, id)
+			}
+			q.mu.Unlock()
+		}
+	}
+
+	ops, err := q.store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("listing pending scale operations: %w", err)
+	}
+
+	now := time.Now()
+
+	ops, err = q.dropExpired(ctx, ops, now)
+	if err != nil {
+		return err
+	}
+
+	var claimable []string
+	for _, op := range ops {
+		if op.claimableBy(q.owner, now) {
+			claimable = append(claimable, op.ID)
+		}
+	}
+
+	claimedIDs, err := q.store.Claim(ctx, q.owner, q.lease, claimable...)
+	if err != nil {
+		return fmt.Errorf("claiming pending scale operations: %w", err)
+	}
+
+	claimed := make(map[string]struct{}, len(claimedIDs))
+	for _, id := range claimedIDs {
+		claimed[id] = struct{}{}
+	}
+
+	var replayed int
+
+	for _, op := range ops {
+		if _, ok := claimed[op.ID]; !ok {
+			continue
+		}
+
+		q.mu.Lock()
+		_, unacked := q.unacked[op.ID]
+		_, inFlight := q.inFlight[op.ID]
+		q.mu.Unlock()
+
+		if unacked || inFlight {
+			continue
+		}
+
+		log := q.log.WithValues("operation", op.ID, "horizontal_runner_autoscaler", op.HorizontalRunnerAutoscaler, "received_at", op.ReceivedAt)
+
+		target := &ScaleTarget{
+			HorizontalRunnerAutoscaler: v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: op.HorizontalRunnerAutoscaler.Namespace,
+					Name:      op.HorizontalRunnerAutoscaler.Name,
+				},
+			},
+			ScaleUpTrigger: v1alpha1.ScaleUpTrigger{
+				Amount:   op.Amount,
+				Duration: op.Duration,
+			},
+			operationID: op.ID,
+			job:         op.Job,
+			log:         &log,
+		}
+
+		if !q.enqueue(target, add) {
+			q.log.V(1).Info("Stopped replaying pending scale operations as the queue is full", "replayed", replayed, "pending", len(ops))
+			break
+		}
+
+		replayed++
+	}
+
+	if replayed > 0 {
+		q.log.Info("Replayed pending scale operations", "replayed", replayed, "pending", len(ops), "claimed_by_others", len(ops)-len(claimed))
+	}
+
+	return nil
+}
+
+// dropExpired removes the operations pending longer than the TTL from the store, so that the operations that keep failing,
+// like the ones for an HRA that no longer exists, don't pile up in the store. It returns the remaining operations.
+func (q *persistentScaleQueue) dropExpired(ctx context.Context, ops []PendingScaleOperation, now time.Time) ([]PendingScaleOperation, error) {
+	var (
+		remaining []PendingScaleOperation
+		expired   []string
+	)
+
+	for _, op := range ops {
+		if !op.expired(now, q.ttl) {
+			remaining = append(remaining, op)
+			continue
+		}
+
+		expired = append(expired, op.ID)
+
+		q.log.Info("Dropping pending scale operation not applied within its TTL", "operation", op.ID, "horizontal_runner_autoscaler", op.HorizontalRunnerAutoscaler, "amount", op.Amount, "received_at", op.ReceivedAt, "job", op.Job)
+	}
+
+	if len(expired) == 0 {
+		return ops, nil
+	}
+
+	if err := q.store.Ack(ctx, expired...); err != nil {
+		return nil, fmt.Errorf("dropping expired scale operations: %w", err)
+	}
+
+	return remaining, nil
+}
+
+// runReplay replays the pending operations on start and every interval until the context is canceled.
+func (q *persistentScaleQueue) runReplay(ctx context.Context, interval time.Duration, add func(*ScaleTarget) bool) {
+	for {
+		if err := q.replay(ctx, add); err != nil {
+			q.log.Error(err, "Failed to replay pending scale operations")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testScaleOperationStore(t *testing.T, store ScaleOperationStore) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	ops, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Empty(t, ops)
+
+	now := time.Now()
+	hra := types.NamespacedName{Namespace: "default", Name: "hra"}
+
+	first := PendingScaleOperation{ID: newPendingScaleOperationID(now), HorizontalRunnerAutoscaler: hra, Amount: 1, Duration: metav1.Duration{Duration: time.Minute}, ReceivedAt: metav1.Time{Time: now}}
+	second := PendingScaleOperation{ID: newPendingScaleOperationID(now.Add(time.Second)), HorizontalRunnerAutoscaler: hra, Amount: -1, ReceivedAt: metav1.Time{Time: now.Add(time.Second)}}
+
+	require.NoError(t, store.Save(ctx, second))
+	require.NoError(t, store.Save(ctx, first))
+
+	ops, err = store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, ops, 2)
+	require.Equal(t, first.ID, ops[0].ID, "operations must be listed in the received order")
+	require.Equal(t, hra, ops[0].HorizontalRunnerAutoscaler)
+	require.Equal(t, 1, ops[0].Amount)
+	require.Equal(t, time.Minute, ops[0].Duration.Duration)
+	require.Equal(t, second.ID, ops[1].ID)
+
+	require.NoError(t, store.Ack(ctx, first.ID, "unknown"))
+
+	ops, err = store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+	require.Equal(t, second.ID, ops[0].ID)
+
+	claimed, err := store.Claim(ctx, "replica-1", time.Minute, second.ID, "unknown")
+	require.NoError(t, err)
+	require.Equal(t, []string{second.ID}, claimed)
+
+	claimed, err = store.Claim(ctx, "replica-2", time.Minute, second.ID)
+	require.NoError(t, err)
+	require.Empty(t, claimed, "an operation claimed by another replica must not be claimed until the claim expires")
+
+	claimed, err = store.Claim(ctx, "replica-1", -time.Minute, second.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{second.ID}, claimed, "the owner must be able to renew its claim")
+
+	claimed, err = store.Claim(ctx, "replica-2", time.Minute, second.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{second.ID}, claimed, "an expired claim must be taken over")
+
+	ops, err = store.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "replica-2", ops[0].ClaimedBy)
+}
+
+func TestFileScaleOperationStore(t *testing.T) {
+	store, err := NewScaleOperationStore("file:"+t.TempDir(), nil)
+	require.NoError(t, err)
+
+	testScaleOperationStore(t, store)
+}
+
+func TestConfigMapScaleOperationStore(t *testing.T) {
+	store, err := NewScaleOperationStore("configmap:default/queue", fake.NewFakeClientWithScheme(sc))
+	require.NoError(t, err)
+
+	testScaleOperationStore(t, store)
+}
+
+func TestConfigMapScaleOperationStore_Prune(t *testing.T) {
+	ctx := context.Background()
+
+	store := &ConfigMapScaleOperationStore{
+		Client:         fake.NewFakeClientWithScheme(sc),
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "queue"},
+		TTL:            time.Hour,
+		Max:            3,
+	}
+
+	now := time.Now()
+	hra := types.NamespacedName{Namespace: "default", Name: "hra"}
+
+	op := func(receivedAt time.Time, duration time.Duration) PendingScaleOperation {
+		return PendingScaleOperation{
+			ID:                         newPendingScaleOperationID(receivedAt),
+			HorizontalRunnerAutoscaler: hra,
+			Amount:                     1,
+			Duration:                   metav1.Duration{Duration: duration},
+			ReceivedAt:                 metav1.Time{Time: receivedAt},
+		}
+	}
+
+	expired := op(now.Add(-2*time.Hour), time.Minute)
+	long := op(now.Add(-2*time.Hour), 3*time.Hour)
+
+	require.NoError(t, store.Save(ctx, expired))
+	require.NoError(t, store.Save(ctx, long))
+
+	var saved []string
+	for i := 0; i < 3; i++ {
+		o := op(now.Add(time.Duration(i)*time.Second), time.Minute)
+		require.NoError(t, store.Save(ctx, o))
+		saved = append(saved, o.ID)
+	}
+
+	ops, err := store.List(ctx)
+	require.NoError(t, err)
+
+	var ids []string
+	for _, o := range ops {
+		ids = append(ids, o.ID)
+	}
+
+	// The expired one is dropped, and then the oldest one to keep at most 3 operations
+	require.Equal(t, saved, ids)
+}
+
+func TestNewScaleOperationStore_Invalid(t *testing.T) {
+	for _, spec := range []string{"file:", "configmap:queue", "configmap:/queue", "memory"} {
+		_, err := NewScaleOperationStore(spec, nil)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestPersistentScaleQueue(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewFileScaleOperationStore(t.TempDir())
+	require.NoError(t, err)
+
+	q := newPersistentScaleQueue(store, "replica-1", time.Minute, logr.Discard())
+
+	var queued []*ScaleTarget
+	limit := 1
+	add := func(st *ScaleTarget) bool {
+		if len(queued) >= limit {
+			return false
+		}
+		queued = append(queued, st)
+		return true
+	}
+
+	newTarget := func(amount int) *ScaleTarget {
+		return &ScaleTarget{
+			HorizontalRunnerAutoscaler: v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hra"},
+			},
+			ScaleUpTrigger: v1alpha1.ScaleUpTrigger{Amount: amount},
+		}
+	}
+
+	first, second := newTarget(1), newTarget(2)
+
+	require.NoError(t, q.save(ctx, first))
+	require.True(t, q.enqueue(first, add))
+
+	// The second one is persisted but doesn't fit into the full queue
+	require.NoError(t, q.save(ctx, second))
+	require.False(t, q.enqueue(second, add))
+
+	// The first one is in flight, so that only the second one is expected to be replayed once the queue has room
+	limit = 2
+	require.NoError(t, q.replay(ctx, add))
+	require.Len(t, queued, 2)
+	require.Equal(t, second.operationID, queued[1].operationID)
+	require.Equal(t, 2, queued[1].Amount)
+	require.NotNil(t, queued[1].log)
+
+	require.NoError(t, q.replay(ctx, add))
+	require.Len(t, queued, 2, "in-flight operations must not be replayed")
+
+	q.ack(ctx, []string{first.operationID, second.operationID})
+
+	ops, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Empty(t, ops)
+
+	// A restarted server replays whatever left in the store
+	third := newTarget(3)
+	require.NoError(t, q.save(ctx, third))
+
+	restarted := newPersistentScaleQueue(store, "replica-1", time.Minute, logr.Discard())
+	queued = nil
+	require.NoError(t, restarted.replay(ctx, add))
+	require.Len(t, queued, 1)
+	require.Equal(t, third.operationID, queued[0].operationID)
+}
+
+func TestPersistentScaleQueue_DropExpired(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewFileScaleOperationStore(t.TempDir())
+	require.NoError(t, err)
+
+	q := newPersistentScaleQueue(store, "replica-1", time.Minute, logr.Discard())
+
+	now := time.Now()
+	hra := types.NamespacedName{Namespace: "default", Name: "hra"}
+
+	// Received before the TTL, e.g. for an HRA that no longer exists and failed to be applied on every replay
+	stale := PendingScaleOperation{ID: newPendingScaleOperationID(now.Add(-2 * q.ttl)), HorizontalRunnerAutoscaler: hra, Amount: 1, ReceivedAt: metav1.Time{Time: now.Add(-2 * q.ttl)}}
+	fresh := PendingScaleOperation{ID: newPendingScaleOperationID(now), HorizontalRunnerAutoscaler: hra, Amount: 1, ReceivedAt: metav1.Time{Time: now}}
+
+	require.NoError(t, store.Save(ctx, stale))
+	require.NoError(t, store.Save(ctx, fresh))
+
+	var queued []*ScaleTarget
+	require.NoError(t, q.replay(ctx, func(st *ScaleTarget) bool {
+		queued = append(queued, st)
+		return true
+	}))
+
+	require.Len(t, queued, 1)
+	require.Equal(t, fresh.ID, queued[0].operationID)
+
+	ops, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, ops, 1, "the expired operation must be removed from the store")
+	require.Equal(t, fresh.ID, ops[0].ID)
+}
+
+func TestPersistentScaleQueue_SharedStore(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewScaleOperationStore("configmap:default/queue", fake.NewFakeClientWithScheme(sc))
+	require.NoError(t, err)
+
+	replica1 := newPersistentScaleQueue(store, "replica-1", time.Minute, logr.Discard())
+	replica2 := newPersistentScaleQueue(store, "replica-2", time.Minute, logr.Discard())
+
+	var queued1, queued2 []*ScaleTarget
+	add := func(queued *[]*ScaleTarget, limit int) func(*ScaleTarget) bool {
+		return func(st *ScaleTarget) bool {
+			if len(*queued) >= limit {
+				return false
+			}
+			*queued = append(*queued, st)
+			return true
+		}
+	}
+
+	target := &ScaleTarget{
+		HorizontalRunnerAutoscaler: v1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hra"},
+		},
+		ScaleUpTrigger: v1alpha1.ScaleUpTrigger{Amount: 1},
+	}
+
+	// The operation received by replica-1 doesn't fit into its full queue
+	require.NoError(t, replica1.save(ctx, target))
+	require.False(t, replica1.enqueue(target, add(&queued1, 0)))
+
+	// replica-2 must not apply the operation claimed by replica-1
+	require.NoError(t, replica2.replay(ctx, add(&queued2, 10)))
+	require.Empty(t, queued2)
+
+	require.NoError(t, replica1.replay(ctx, add(&queued1, 10)))
+	require.Len(t, queued1, 1)
+	require.Equal(t, target.operationID, queued1[0].operationID)
+
+	// Once replica-1 stops renewing its claim, replica-2 takes the operation over
+	expired := newPersistentScaleQueue(store, "replica-1", -time.Minute, logr.Discard())
+	require.NoError(t, expired.replay(ctx, func(*ScaleTarget) bool { return true }))
+
+	require.NoError(t, replica2.replay(ctx, add(&queued2, 10)))
+	require.Len(t, queued2, 1)
+	require.Equal(t, target.operationID, queued2[0].operationID)
+}
+
+func TestBatchScaler_Ack(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hra"},
+	}
+
+	c := fake.NewFakeClientWithScheme(sc, hra)
+
+	acked := make(chan []string, 2)
+
+	s := newBatchScaler(ctx, c, logr.Discard())
+	s.interval = 10 * time.Millisecond
+	s.ack = func(_ context.Context, ids []string) {
+		acked <- ids
+	}
+
+	log := logr.Discard()
+
+	s.Add(&ScaleTarget{
+		HorizontalRunnerAutoscaler: *hra,
+		ScaleUpTrigger:             v1alpha1.ScaleUpTrigger{Amount: 1, Duration: metav1.Duration{Duration: time.Minute}},
+		operationID:                "1",
+		log:                        &log,
+	})
+	s.Add(&ScaleTarget{
+		HorizontalRunnerAutoscaler: v1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "deleted"},
+		},
+		ScaleUpTrigger: v1alpha1.ScaleUpTrigger{Amount: 1},
+		operationID:    "2",
+		log:            &log,
+	})
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		select {
+		case got := <-acked:
+			ids = append(ids, got...)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for acks. got %v", ids)
+		}
+	}
+
+	sort.Strings(ids)
+	require.Equal(t, []string{"1", "2"}, ids)
+
+	var updated v1alpha1.HorizontalRunnerAutoscaler
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "hra"}, &updated))
+	require.Len(t, updated.Status.CapacityReservations, 1)
+}
@@ -0,0 +1,467 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v45/github"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/actions-runner-controller/actions-runner-controller/github"
+)
+
+// activeJob is a queued or in_progress workflow job on GitHub that a HRA should have a capacity reservation for.
+type activeJob struct {
+	ref      WorkflowJobRef
+	duration time.Duration
+}
+
+// resyncScope is how the workflow jobs of a repository are fetched and matched against the HRAs,
+// which is resolved from the scale targets of the HRAs whose runners can run the jobs.
+type resyncScope struct {
+	// ownerType is the type of the repository owner, which is empty when unknown
+	ownerType string
+	// enterprise is the slug of the enterprise the repository belongs to, which is empty when unknown
+	enterprise string
+	// client is the GitHub API client used to fetch the workflow jobs
+	client *github.Client
+}
+
+// merge fills the unknown fields of the scope with the other's
+func (s *resyncScope) merge(other resyncScope) {
+	if s.ownerType == "" {
+		s.ownerType = other.ownerType
+	}
+
+	if s.enterprise == "" {
+		s.enterprise = other.enterprise
+	}
+
+	if s.client == nil {
+		s.client = other.client
+	}
+}
+
+// runReservationResync periodically corrects the capacity reservations of the HRAs with workflowJob triggers
+// against the queued and in_progress workflow jobs on GitHub, until the context is canceled.
+// It adds the reservations missing due to lost "queued" events, and removes the ones left due to lost "completed" events.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) runReservationResync(ctx context.Context) error {
+	log := autoscaler.Log.WithName("resync")
+
+	interval := autoscaler.ReservationResyncInterval
+
+	// Each repository is fetched once per resync, even if it's scanned for two or more HRAs.
+	// The HRAs with their own GitHub API credentials have their own caches
+	caches := map[*github.Client]*github.JobStateCache{}
+	jobStates := func(c *github.Client) *github.JobStateCache {
+		cache, ok := caches[c]
+		if !ok {
+			cache = github.NewJobStateCache(c, interval/2, log)
+			caches[c] = cache
+		}
+		return cache
+	}
+
+	log.Info("Starting capacity reservation resync", "interval", interval)
+	defer log.Info("Stopped capacity reservation resync")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := autoscaler.resyncReservations(ctx, log, jobStates); err != nil {
+			log.Error(err, "Failed to resync capacity reservations")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) resyncReservations(ctx context.Context, log logr.Logger, jobStates func(*github.Client) *github.JobStateCache) error {
+	var opts []client.ListOption
+
+	if autoscaler.Namespace != "" {
+		opts = append(opts, client.InNamespace(autoscaler.Namespace))
+	}
+
+	var hraList v1alpha1.HorizontalRunnerAutoscalerList
+
+	if err := autoscaler.List(ctx, &hraList, opts...); err != nil {
+		return fmt.Errorf("listing horizontalrunnerautoscalers: %w", err)
+	}
+
+	var hras []v1alpha1.HorizontalRunnerAutoscaler
+
+	repos := map[string]*resyncScope{}
+
+	addRepo := func(repo string, scope resyncScope) {
+		if s, ok := repos[repo]; ok {
+			s.merge(scope)
+		} else {
+			repos[repo] = &scope
+		}
+	}
+
+	for _, hra := range hraList.Items {
+		if !hra.ObjectMeta.DeletionTimestamp.IsZero() || !hasWorkflowJobTrigger(hra) {
+			continue
+		}
+
+		rs, scope, err := autoscaler.repositoriesToResync(ctx, hra)
+		if err != nil {
+			log.Error(err, "Failed to find repositories to resync", "hra", hra.Name, "namespace", hra.Namespace)
+			continue
+		}
+
+		for _, r := range rs {
+			addRepo(r, scope)
+		}
+
+		// The repositories of the existing reservations are scanned as well, so that the stale ones can be removed
+		for _, r := range hra.Status.CapacityReservations {
+			if r.Repository != "" {
+				addRepo(r.Repository, scope)
+			}
+		}
+
+		hras = append(hras, hra)
+	}
+
+	var repoNames []string
+	for r := range repos {
+		repoNames = append(repoNames, r)
+	}
+	sort.Strings(repoNames)
+
+	states := map[string]*github.RepositoryJobState{}
+	jobs := map[types.NamespacedName][]activeJob{}
+	targets := map[string]*ScaleTarget{}
+
+	for _, repo := range repoNames {
+		ownerAndName := strings.Split(repo, "/")
+		if len(ownerAndName) != 2 {
+			continue
+		}
+
+		owner, name := ownerAndName[0], ownerAndName[1]
+
+		scope := repos[repo]
+
+		state, err := jobStates(scope.client).Get(ctx, owner, name)
+		if err != nil {
+			log.Error(err, "Failed to get workflow jobs", "repository", repo)
+			continue
+		}
+
+		states[repo] = state
+
+		for _, run := range state.Runs {
+			for _, job := range run.Jobs {
+				if status := job.GetStatus(); status != "queued" && status != "in_progress" {
+					continue
+				}
+
+				ref := WorkflowJobRef{
+					ID:         job.GetID(),
+					RunID:      run.ID,
+					Name:       job.GetName(),
+					Repository: repo,
+				}
+
+				// The job stays with the HRA that already has the reservation for it,
+				// so that another HRA matching the job doesn't add one more reservation for it
+				if holder := findReservationHolder(hras, ref); holder != nil {
+					nsName := types.NamespacedName{Namespace: holder.Namespace, Name: holder.Name}
+
+					duration := holder.Spec.ScaleUpTriggers[0].Duration.Duration
+					if duration <= 0 {
+						duration = 10 * time.Minute
+					}
+
+					jobs[nsName] = append(jobs[nsName], activeJob{ref: ref, duration: duration})
+
+					continue
+				}
+
+				key := strings.Join([]string{repo, run.Name, run.HeadBranch, job.GetName(), strings.Join(job.Labels, ",")}, "\x00")
+
+				target, ok := targets[key]
+				if !ok {
+					target, err = autoscaler.getActiveJobScaleTarget(ctx, log, owner, name, *scope, run, job)
+					if err != nil {
+						log.Error(err, "Failed to find scale target for workflow job", "repository", repo, "job_id", job.GetID())
+					}
+
+					targets[key] = target
+				}
+
+				if target == nil {
+					continue
+				}
+
+				nsName := types.NamespacedName{Namespace: target.HorizontalRunnerAutoscaler.Namespace, Name: target.HorizontalRunnerAutoscaler.Name}
+
+				jobs[nsName] = append(jobs[nsName], activeJob{
+					ref:      ref,
+					duration: target.Duration.Duration,
+				})
+			}
+		}
+	}
+
+	for _, hra := range hras {
+		nsName := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}
+
+		if err := autoscaler.correctReservations(ctx, log, nsName, jobs[nsName], states); err != nil {
+			log.Error(err, "Failed to correct capacity reservations", "hra", hra.Name, "namespace", hra.Namespace)
+		}
+	}
+
+	return nil
+}
+
+// findReservationHolder returns the HRA that has the capacity reservation for the job, if any.
+func findReservationHolder(hras []v1alpha1.HorizontalRunnerAutoscaler, job WorkflowJobRef) *v1alpha1.HorizontalRunnerAutoscaler {
+	for i := range hras {
+		if hasCapacityReservationForJob(hras[i].Status.CapacityReservations, job) {
+			return &hras[i]
+		}
+	}
+
+	return nil
+}
+
+// hasWorkflowJobTrigger returns true when the HRA can be scaled by workflow_job events.
+func hasWorkflowJobTrigger(hra v1alpha1.HorizontalRunnerAutoscaler) bool {
+	if len(hra.Spec.ScaleUpTriggers) != 1 {
+		return false
+	}
+
+	g := hra.Spec.ScaleUpTriggers[0].GitHubEvent
+
+	return g != nil && g.WorkflowJob != nil
+}
+
+// repositoriesToResync returns the repositories whose workflow jobs can be run by the runners of the HRA, like "owner/name",
+// along with the scope the jobs are fetched and matched in.
+// It returns the repositories in the workflowJob trigger's repositories filter, or the recently active repositories
+// of the organization for organizational runners. Enterprise runners have only the repositories in the filter
+// specified along with their owners to resync, as the organizations of the enterprise are unknown.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) repositoriesToResync(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler) ([]string, resyncScope, error) {
+	var (
+		repository, organization, enterprise string
+		credsFrom                            *v1alpha1.GitHubAPICredentialsFrom
+		scope                                resyncScope
+	)
+
+	nsName := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Spec.ScaleTargetRef.Name}
+
+	switch hra.Spec.ScaleTargetRef.Kind {
+	case "RunnerSet":
+		var rs v1alpha1.RunnerSet
+
+		if err := autoscaler.Client.Get(ctx, nsName, &rs); err != nil {
+			return nil, scope, err
+		}
+
+		repository, organization, enterprise = rs.Spec.Repository, rs.Spec.Organization, rs.Spec.Enterprise
+		credsFrom = rs.Spec.RunnerConfig.GitHubAPICredentialsFrom
+	case "RunnerDeployment", "":
+		var rd v1alpha1.RunnerDeployment
+
+		if err := autoscaler.Client.Get(ctx, nsName, &rd); err != nil {
+			return nil, scope, err
+		}
+
+		repository, organization, enterprise = rd.Spec.Template.Spec.Repository, rd.Spec.Template.Spec.Organization, rd.Spec.Template.Spec.Enterprise
+		credsFrom = rd.Spec.Template.Spec.GitHubAPICredentialsFrom
+	default:
+		return nil, scope, fmt.Errorf("unsupported scaleTargetRef.kind: %v", hra.Spec.ScaleTargetRef.Kind)
+	}
+
+	// The same credentials as the ones used for polling metrics of the HRA
+	if hra.Spec.GitHubAPICredentialsFrom != nil {
+		credsFrom = hra.Spec.GitHubAPICredentialsFrom
+	}
+
+	ghc, err := autoscaler.GitHubClients.ClientFor(ctx, autoscaler.GitHubClient, hra.Namespace, credsFrom)
+	if err != nil {
+		return nil, scope, err
+	}
+
+	scope.client = ghc
+
+	if repository != "" {
+		return []string{repository}, scope, nil
+	}
+
+	// The repositories of both organizational and enterprise runners are owned by organizations
+	scope.ownerType = "Organization"
+	scope.enterprise = enterprise
+
+	if organization == "" && enterprise == "" {
+		return nil, scope, nil
+	}
+
+	var repos []string
+
+	if filter := hra.Spec.ScaleUpTriggers[0].GitHubEvent.WorkflowJob.Repositories; len(filter) > 0 {
+		for _, r := range filter {
+			if !strings.Contains(r, "/") {
+				if organization == "" {
+					continue
+				}
+
+				r = organization + "/" + r
+			}
+
+			repos = append(repos, r)
+		}
+
+		return repos, scope, nil
+	}
+
+	if organization == "" {
+		return nil, scope, nil
+	}
+
+	active, err := ghc.ListActiveOrganizationRepositories(ctx, organization, defaultMaxRepositoriesPerSync)
+	if err != nil {
+		return nil, scope, fmt.Errorf("discovering repositories of organization %s: %w", organization, err)
+	}
+
+	for _, r := range active {
+		repos = append(repos, organization+"/"+r.GetName())
+	}
+
+	return repos, scope, nil
+}
+
+// getActiveJobScaleTarget finds the HRA that would have been scaled by the "queued" workflow_job event of the job.
+// The type of the owner and the enterprise are resolved from the scale targets, as they would be from the event payload.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getActiveJobScaleTarget(ctx context.Context, log logr.Logger, owner, repo string, scope resyncScope, run github.WorkflowRunState, job *gogithub.WorkflowJob) (*ScaleTarget, error) {
+	event := &gogithub.WorkflowJobEvent{
+		WorkflowJob: job,
+		Repo: &gogithub.Repository{
+			Name:     gogithub.String(repo),
+			FullName: gogithub.String(owner + "/" + repo),
+			Owner:    &gogithub.User{Login: gogithub.String(owner), Type: gogithub.String(scope.ownerType)},
+		},
+	}
+
+	return autoscaler.getJobScaleUpTargetForRepoOrOrg(ctx, log, repo, owner, scope.ownerType, scope.enterprise, job.Labels, nil, false, autoscaler.MatchWorkflowJobEvent(event, run.Name, run.HeadBranch))
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) correctReservations(ctx context.Context, log logr.Logger, nsName types.NamespacedName, jobs []activeJob, states map[string]*github.RepositoryJobState) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var hra v1alpha1.HorizontalRunnerAutoscaler
+
+		if err := autoscaler.Client.Get(ctx, nsName, &hra); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+
+		copy := hra.DeepCopy()
+
+		declared, migrated := migrateLegacyCapacityReservations(copy)
+
+		reservations, added, removed := correctCapacityReservations(getValidCapacityReservations(copy), copy.Status.CompletedJobs, jobs, states, time.Now())
+		if added == 0 && removed == 0 && !migrated {
+			return nil
+		}
+
+		copy.Status.CapacityReservations = reservations
+
+		if err := autoscaler.Client.Status().Update(ctx, copy); err != nil {
+			return err
+		}
+
+		if migrated {
+			removeMigratedCapacityReservations(ctx, autoscaler.Client, log, copy, declared)
+		}
+
+		log.Info("Corrected capacity reservations against workflow jobs on GitHub", "hra", nsName.Name, "namespace", nsName.Namespace, "added", added, "removed", removed, "after", len(reservations))
+
+		return nil
+	})
+}
+
+// correctCapacityReservations removes the reservations for the jobs that are no longer queued or in_progress,
+// and adds the ones for the active jobs that have no reservation.
+// A reservation is removed only when its repository was scanned after the reservation was made,
+// so that a reservation for a job queued after the scan isn't removed.
+// A reservation is never added for a completed job, as the job may have completed after the scan.
+func correctCapacityReservations(reservations []v1alpha1.CapacityReservation, completed []v1alpha1.CompletedJob, jobs []activeJob, states map[string]*github.RepositoryJobState, now time.Time) ([]v1alpha1.CapacityReservation, int, int) {
+	var (
+		corrected      []v1alpha1.CapacityReservation
+		added, removed int
+	)
+
+	for _, r := range reservations {
+		if isForJob(r) {
+			if state, ok := states[r.Repository]; ok && r.EffectiveTime.Time.Before(state.FetchedAt) && !isActiveJob(state, r) {
+				removed++
+				continue
+			}
+		}
+
+		corrected = append(corrected, r)
+	}
+
+	for _, j := range jobs {
+		if hasCapacityReservationForJob(corrected, j.ref) || isCompletedJob(completed, j.ref.ID) {
+			continue
+		}
+
+		corrected = append(corrected, v1alpha1.CapacityReservation{
+			EffectiveTime:  metav1.Time{Time: now},
+			ExpirationTime: metav1.Time{Time: now.Add(j.duration)},
+			Replicas:       1,
+			JobID:          j.ref.ID,
+			RunID:          j.ref.RunID,
+			JobName:        j.ref.Name,
+			Repository:     j.ref.Repository,
+		})
+
+		added++
+	}
+
+	return corrected, added, removed
+}
+
+// isActiveJob returns true when the job the reservation is made for is still queued or in_progress.
+// The job of a run whose jobs are unknown is considered active.
+func isActiveJob(state *github.RepositoryJobState, r v1alpha1.CapacityReservation) bool {
+	for _, run := range state.Runs {
+		if !run.JobsListed || len(run.Jobs) == 0 {
+			if run.ID != 0 && run.ID == r.RunID {
+				return true
+			}
+
+			continue
+		}
+
+		for _, job := range run.Jobs {
+			if status := job.GetStatus(); status != "queued" && status != "in_progress" {
+				continue
+			}
+
+			if (WorkflowJobRef{ID: job.GetID(), RunID: run.ID, Name: job.GetName()}).matches(r) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/actions-runner-controller/actions-runner-controller/github"
+	gogithub "github.com/google/go-github/v45/github"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCorrectCapacityReservations(t *testing.T) {
+	now := time.Now()
+	fetchedAt := now.Add(-time.Minute)
+
+	job := func(id int64, status string) *gogithub.WorkflowJob {
+		return &gogithub.WorkflowJob{ID: gogithub.Int64(id), Name: gogithub.String("build"), Status: gogithub.String(status)}
+	}
+
+	states := map[string]*github.RepositoryJobState{
+		"owner/repo": {
+			Owner:      "owner",
+			Repository: "repo",
+			FetchedAt:  fetchedAt,
+			Runs: []github.WorkflowRunState{
+				{ID: 10, Status: "in_progress", JobsListed: true, Jobs: []*gogithub.WorkflowJob{job(1, "in_progress"), job(2, "completed")}},
+				// The jobs of the run couldn't be listed
+				{ID: 20, Status: "queued"},
+			},
+		},
+	}
+
+	reservation := func(name string, jobID, runID int64, repo string, effective time.Time) v1alpha1.CapacityReservation {
+		return v1alpha1.CapacityReservation{
+			Name:           name,
+			EffectiveTime:  metav1.Time{Time: effective},
+			ExpirationTime: metav1.Time{Time: effective.Add(time.Hour)},
+			Replicas:       1,
+			JobID:          jobID,
+			RunID:          runID,
+			JobName:        "build",
+			Repository:     repo,
+		}
+	}
+
+	before := fetchedAt.Add(-time.Minute)
+
+	reservations := []v1alpha1.CapacityReservation{
+		reservation("active", 1, 10, "owner/repo", before),
+		reservation("completed", 2, 10, "owner/repo", before),
+		reservation("unknown-jobs", 3, 20, "owner/repo", before),
+		reservation("after-fetch", 4, 30, "owner/repo", now),
+		reservation("unscanned", 5, 40, "owner/another", before),
+		{Name: "untagged", Replicas: 1},
+	}
+
+	jobs := []activeJob{
+		{ref: WorkflowJobRef{ID: 1, RunID: 10, Name: "build", Repository: "owner/repo"}, duration: 10 * time.Minute},
+		{ref: WorkflowJobRef{ID: 6, RunID: 10, Name: "test", Repository: "owner/repo"}, duration: 10 * time.Minute},
+	}
+
+	corrected, added, removed := correctCapacityReservations(reservations, nil, jobs, states, now)
+
+	require.Equal(t, 1, added)
+	require.Equal(t, 1, removed)
+
+	var names []string
+	for _, r := range corrected {
+		names = append(names, r.Name)
+	}
+	require.Equal(t, []string{"active", "unknown-jobs", "after-fetch", "unscanned", "untagged", ""}, names)
+
+	missing := corrected[len(corrected)-1]
+	require.Equal(t, int64(6), missing.JobID)
+	require.Equal(t, "test", missing.JobName)
+	require.Equal(t, "owner/repo", missing.Repository)
+	require.Equal(t, 1, missing.Replicas)
+	require.Equal(t, now.Add(10*time.Minute), missing.ExpirationTime.Time)
+
+	_, added, removed = correctCapacityReservations(corrected, nil, jobs, states, now)
+	require.Zero(t, added, "the correction must be idempotent")
+	require.Zero(t, removed, "the correction must be idempotent")
+
+	// The job completed after the scan, and its completed event removed the reservation already
+	completed := []v1alpha1.CompletedJob{{JobID: 6, CompletionTime: metav1.Time{Time: now}}}
+
+	corrected, added, _ = correctCapacityReservations(corrected[:len(corrected)-1], completed, jobs, states, now)
+	require.Zero(t, added, "the reservation for a completed job must not be added back")
+	require.Len(t, corrected, 5)
+}
+
+func TestAddCompletedJob(t *testing.T) {
+	now := time.Now()
+
+	jobs := []v1alpha1.CompletedJob{
+		{JobID: 1, CompletionTime: metav1.Time{Time: now.Add(-2 * completedJobRetention)}},
+		{JobID: 2, CompletionTime: metav1.Time{Time: now.Add(-time.Minute)}},
+	}
+
+	jobs = addCompletedJob(jobs, 3, now)
+	require.Len(t, jobs, 2, "the jobs completed before the retention period must be forgotten")
+	require.False(t, isCompletedJob(jobs, 1))
+	require.True(t, isCompletedJob(jobs, 2))
+	require.True(t, isCompletedJob(jobs, 3))
+	require.False(t, isCompletedJob(jobs, 0))
+
+	for i := int64(0); i < maxCompletedJobs; i++ {
+		jobs = addCompletedJob(jobs, 100+i, now)
+	}
+	require.Len(t, jobs, maxCompletedJobs)
+	require.False(t, isCompletedJob(jobs, 2), "the oldest jobs must be forgotten first")
+}
+
+func TestRepositoriesToResync_Enterprise(t *testing.T) {
+	rd := &v1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "rd"},
+		Spec: v1alpha1.RunnerDeploymentSpec{
+			Template: v1alpha1.RunnerTemplate{
+				Spec: v1alpha1.RunnerSpec{
+					RunnerConfig: v1alpha1.RunnerConfig{Enterprise: "ent"},
+				},
+			},
+		},
+	}
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hra"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			ScaleTargetRef: v1alpha1.ScaleTargetRef{Name: "rd"},
+			ScaleUpTriggers: []v1alpha1.ScaleUpTrigger{{
+				GitHubEvent: &v1alpha1.GitHubEventScaleUpTriggerSpec{
+					WorkflowJob: &v1alpha1.WorkflowJobSpec{Repositories: []string{"org/repo", "unqualified"}},
+				},
+			}},
+		},
+	}
+
+	autoscaler := &HorizontalRunnerAutoscalerGitHubWebhook{Client: fake.NewFakeClientWithScheme(sc, rd)}
+
+	repos, scope, err := autoscaler.repositoriesToResync(context.Background(), hra)
+	require.NoError(t, err)
+	require.Equal(t, []string{"org/repo"}, repos, "the repositories of enterprise runners must be specified along with their owners")
+	require.Equal(t, "ent", scope.enterprise)
+	require.Equal(t, "Organization", scope.ownerType)
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v45/github"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+)
+
+// updateRunnerCurrentJob records the workflow job assigned to the runner to its status, and clears it once the job completes.
+// The runner status is informational, so the errors are logged rather than failing the webhook delivery.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) updateRunnerCurrentJob(ctx context.Context, log logr.Logger, runner *v1alpha1.Runner, e *gogithub.WorkflowJobEvent, workflowName string) {
+	job := e.GetWorkflowJob()
+	action := e.GetAction()
+	repository := fmt.Sprintf("%s/%s", e.GetRepo().GetOwner().GetLogin(), e.GetRepo().GetName())
+
+	updated := runner.DeepCopy()
+
+	switch action {
+	case "in_progress":
+		startedAt := metav1.Now()
+		if t := job.StartedAt; t != nil {
+			startedAt = metav1.NewTime(t.Time)
+		}
+
+		updated.Status.CurrentJob = &v1alpha1.RunnerCurrentJob{
+			Repository: repository,
+			Workflow:   workflowName,
+			RunID:      job.GetRunID(),
+			JobID:      job.GetID(),
+			JobName:    job.GetName(),
+			StartedAt:  &startedAt,
+		}
+	case "completed":
+		// The completed event can arrive after the in_progress event of the next job on a non-ephemeral runner
+		if current := runner.Status.CurrentJob; current == nil || current.JobID != job.GetID() {
+			return
+		}
+
+		updated.Status.CurrentJob = nil
+	}
+
+	if err := autoscaler.Status().Patch(ctx, updated, client.MergeFrom(runner)); err != nil {
+		log.Error(err, "Failed to update the current job of the runner", "runner", runner.Name)
+		return
+	}
+
+	log.V(1).Info("Updated the current job of the runner", "runner", runner.Name, "action", action)
+}
+
+// findRunnerForJob returns the Runner with the name whose scope covers the repository of the job, or nil if there's none.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) findRunnerForJob(ctx context.Context, name, repository, owner, enterprise string) (*v1alpha1.Runner, error) {
+	var opts []client.ListOption
+
+	if autoscaler.Namespace != "" {
+		opts = append(opts, client.InNamespace(autoscaler.Namespace))
+	}
+
+	var runners v1alpha1.RunnerList
+
+	if err := autoscaler.List(ctx, &runners, opts...); err != nil {
+		return nil, err
+	}
+
+	for i := range runners.Items {
+		r := &runners.Items[i]
+
+		if r.Name == name && runnerScopeCovers(r.Spec.Enterprise, r.Spec.Organization, r.Spec.Repository, repository, owner, enterprise) {
+			return r, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// findRunnerPodForJob returns the runner pod with the name whose scope covers the repository of the job, or nil if there's none.
+// It's used for the runners that have no Runner, like the ones managed by RunnerSets.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) findRunnerPodForJob(ctx context.Context, name, repository, owner, enterprise string) (*corev1.Pod, error) {
+	if autoscaler.RunnerPodReader == nil {
+		return nil, nil
+	}
+
+	opts := []client.ListOption{
+		client.MatchingFields{"metadata.name": name},
+		client.HasLabels{LabelKeyRunnerSetName},
+	}
+
+	if autoscaler.Namespace != "" {
+		opts = append(opts, client.InNamespace(autoscaler.Namespace))
+	}
+
+	var pods corev1.PodList
+
+	if err := autoscaler.RunnerPodReader.List(ctx, &pods, opts...); err != nil {
+		return nil, err
+	}
+
+	for i := range pods.Items {
+		p := &pods.Items[i]
+
+		if p.Name == name && runnerScopeCovers(getRunnerEnv(p, EnvVarEnterprise), getRunnerEnv(p, EnvVarOrg), getRunnerEnv(p, EnvVarRepo), repository, owner, enterprise) {
+			return p, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// runnerScopeCovers returns true when the runner registered to the enterprise, organization, or repository
+// can run the jobs of the repository.
+func runnerScopeCovers(runnerEnterprise, runnerOrg, runnerRepo, repository, owner, enterprise string) bool {
+	switch {
+	case runnerRepo != "":
+		return strings.EqualFold(runnerRepo, repository)
+	case runnerOrg != "":
+		return strings.EqualFold(runnerOrg, owner)
+	case runnerEnterprise != "":
+		return enterprise != "" && strings.EqualFold(runnerEnterprise, enterprise)
+	}
+
+	return false
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v45/github"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+)
+
+func TestWebhookUpdateRunnerCurrentJob(t *testing.T) {
+	ctx := context.Background()
+
+	runner := &v1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example-runner"},
+		Spec: v1alpha1.RunnerSpec{
+			RunnerConfig: v1alpha1.RunnerConfig{Organization: "MyOrg"},
+		},
+	}
+
+	otherOrgRunner := &v1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "example-runner"},
+		Spec: v1alpha1.RunnerSpec{
+			RunnerConfig: v1alpha1.RunnerConfig{Organization: "other"},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(sc).WithObjects(runner, otherOrgRunner).Build()
+
+	webhook := &HorizontalRunnerAutoscalerGitHubWebhook{
+		Client:   c,
+		Log:      logr.Discard(),
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	getCurrentJob := func(namespace string) *v1alpha1.RunnerCurrentJob {
+		var got v1alpha1.Runner
+		require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "example-runner"}, &got))
+		return got.Status.CurrentJob
+	}
+
+	event := func(action string, jobID int64) *github.WorkflowJobEvent {
+		return &github.WorkflowJobEvent{
+			Action: github.String(action),
+			WorkflowJob: &github.WorkflowJob{
+				ID:         github.Int64(jobID),
+				RunID:      github.Int64(100),
+				Name:       github.String("build"),
+				RunnerName: github.String("example-runner"),
+			},
+			Repo: &github.Repository{
+				Name:  github.String("myrepo"),
+				Owner: &github.User{Login: github.String("myorg")},
+			},
+		}
+	}
+
+	webhook.trackWorkflowJob(ctx, logr.Discard(), event("in_progress", 1), "CI", "", time.Time{})
+
+	job := getCurrentJob("default")
+	require.NotNil(t, job)
+	require.Equal(t, "myorg/myrepo", job.Repository)
+	require.Equal(t, "CI", job.Workflow)
+	require.Equal(t, int64(100), job.RunID)
+	require.Equal(t, int64(1), job.JobID)
+	require.Equal(t, "build", job.JobName)
+	require.NotNil(t, job.StartedAt)
+
+	require.Nil(t, getCurrentJob("other"), "the runner of the same name in another organization must not be updated")
+
+	// The completed event of another job doesn't clear the current job
+	webhook.trackWorkflowJob(ctx, logr.Discard(), event("completed", 2), "CI", "", time.Time{})
+	require.NotNil(t, getCurrentJob("default"))
+
+	webhook.trackWorkflowJob(ctx, logr.Discard(), event("completed", 1), "CI", "", time.Time{})
+	require.Nil(t, getCurrentJob("default"))
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// ScaleTargetSelectionPolicySpecificity selects the HRA whose runners have the fewest labels not requested by the workflow job,
+	// and then the one with the highest priority.
+	ScaleTargetSelectionPolicySpecificity = "Specificity"
+
+	// ScaleTargetSelectionPolicyPriority selects the HRA with the highest priority.
+	ScaleTargetSelectionPolicyPriority = "Priority"
+
+	// ScaleTargetSelectionPolicyRoundRobin selects each of the HRAs in turn.
+	ScaleTargetSelectionPolicyRoundRobin = "RoundRobin"
+
+	// ScaleTargetSelectionPolicySpillover selects the HRA with the highest priority that has not reached its maxReplicas yet.
+	ScaleTargetSelectionPolicySpillover = "Spillover"
+)
+
+// ValidateScaleTargetSelectionPolicy returns an error when the policy is not empty and not one of the known ones.
+func ValidateScaleTargetSelectionPolicy(policy string) error {
+	switch policy {
+	case "", ScaleTargetSelectionPolicySpecificity, ScaleTargetSelectionPolicyPriority, ScaleTargetSelectionPolicyRoundRobin, ScaleTargetSelectionPolicySpillover:
+		return nil
+	}
+
+	return fmt.Errorf("unsupported scale target selection policy %q: must be one of %s, %s, %s, or %s", policy,
+		ScaleTargetSelectionPolicySpecificity, ScaleTargetSelectionPolicyPriority, ScaleTargetSelectionPolicyRoundRobin, ScaleTargetSelectionPolicySpillover)
+}
+
+// scaleTargetCandidate is one of the scale targets matching a webhook event.
+type scaleTargetCandidate struct {
+	target ScaleTarget
+
+	// runnerLabels is the labels of the runners managed by the target, which is used to rank the targets by specificity
+	runnerLabels []string
+}
+
+func (c scaleTargetCandidate) priority() int {
+	if p := c.target.HorizontalRunnerAutoscaler.Spec.Priority; p != nil {
+		return *p
+	}
+
+	return 0
+}
+
+func (c scaleTargetCandidate) id() string {
+	return c.target.HorizontalRunnerAutoscaler.Namespace + "/" + c.target.HorizontalRunnerAutoscaler.Name
+}
+
+// extraLabels returns the number of the runner labels not requested by the job.
+func (c scaleTargetCandidate) extraLabels(jobLabels []string) int {
+	requested := map[string]struct{}{}
+	for _, l := range jobLabels {
+		requested[l] = struct{}{}
+	}
+
+	var n int
+
+	for _, l := range c.runnerLabels {
+		if _, ok := requested[l]; !ok {
+			n++
+		}
+	}
+
+	return n
+}
+
+// isFull returns true when the target is already scaled to its maxReplicas, or has reserved that much capacity.
+func (c scaleTargetCandidate) isFull() bool {
+	hra := c.target.HorizontalRunnerAutoscaler
+
+	if hra.Spec.MaxReplicas == nil {
+		return false
+	}
+
+	var reserved int
+
+	for _, r := range getValidCapacityReservations(&hra) {
+		reserved += r.Replicas
+	}
+
+	if hra.Status.DesiredReplicas != nil && *hra.Status.DesiredReplicas > reserved {
+		reserved = *hra.Status.DesiredReplicas
+	}
+
+	return reserved >= *hra.Spec.MaxReplicas
+}
+
+// selectJobScaleTarget selects one of the candidate sets found for the repository, organization and enterprise runners
+// the workflow job can run on, in this order.
+// The HRA holding the capacity reservation for the job is selected regardless of the policy,
+// so that e.g. the completed event releases the capacity reserved on the queued event.
+// Otherwise the ScaleTargetSelectionPolicy applies only to the queued event, and Spillover can move to the next set
+// when all the candidates in a set are full. The other events select the highest priority candidate of the first set.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) selectJobScaleTarget(jobLabels []string, job *WorkflowJobRef, queued bool, sets [][]scaleTargetCandidate) *ScaleTarget {
+	if job != nil {
+		for _, candidates := range sets {
+			for i := range candidates {
+				if hasCapacityReservationForJob(candidates[i].target.HorizontalRunnerAutoscaler.Status.CapacityReservations, *job) {
+					return &candidates[i].target
+				}
+			}
+		}
+	}
+
+	if len(sets) == 0 {
+		return nil
+	}
+
+	if !queued {
+		return autoscaler.selectScaleTarget(jobLabels, sets[0], false)
+	}
+
+	if autoscaler.ScaleTargetSelectionPolicy == ScaleTargetSelectionPolicySpillover {
+		for _, candidates := range sets {
+			for _, c := range candidates {
+				if !c.isFull() {
+					return autoscaler.selectScaleTarget(jobLabels, candidates, true)
+				}
+			}
+		}
+	}
+
+	return autoscaler.selectScaleTarget(jobLabels, sets[0], true)
+}
+
+// selectScaleTarget selects one of the candidates according to the ScaleTargetSelectionPolicy,
+// or the one with the highest priority when applyPolicy is false.
+// jobLabels is the labels of the workflow job that triggered the scale, if any.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) selectScaleTarget(jobLabels []string, candidates []scaleTargetCandidate, applyPolicy bool) *ScaleTarget {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	if len(candidates) == 1 {
+		return &candidates[0].target
+	}
+
+	// We deduplicate and sort candidates so that the selection doesn't depend on the order the HRAs are listed
+	seen := map[string]struct{}{}
+
+	var sorted []scaleTargetCandidate
+
+	for _, c := range candidates {
+		if _, ok := seen[c.id()]; ok {
+			continue
+		}
+
+		seen[c.id()] = struct{}{}
+		sorted = append(sorted, c)
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if pi, pj := sorted[i].priority(), sorted[j].priority(); pi != pj {
+			return pi > pj
+		}
+
+		return sorted[i].id() < sorted[j].id()
+	})
+
+	var ids []string
+	for _, c := range sorted {
+		ids = append(ids, c.id())
+	}
+
+	var selected scaleTargetCandidate
+
+	policy := autoscaler.ScaleTargetSelectionPolicy
+	if !applyPolicy {
+		policy = ""
+	}
+
+	switch policy {
+	case ScaleTargetSelectionPolicySpecificity:
+		selected = sorted[0]
+
+		for _, c := range sorted[1:] {
+			if c.extraLabels(jobLabels) < selected.extraLabels(jobLabels) {
+				selected = c
+			}
+		}
+	case ScaleTargetSelectionPolicyRoundRobin:
+		key := strings.Join(ids, ",")
+
+		autoscaler.roundRobinMu.Lock()
+		if autoscaler.roundRobin == nil {
+			autoscaler.roundRobin = map[string]int{}
+		}
+		n := autoscaler.roundRobin[key]
+		autoscaler.roundRobin[key] = n + 1
+		autoscaler.roundRobinMu.Unlock()
+
+		selected = sorted[n%len(sorted)]
+	case ScaleTargetSelectionPolicySpillover:
+		// When all the targets are full, the one with the highest priority is selected,
+		// so that the reservation is there once it has room
+		selected = sorted[0]
+
+		for _, c := range sorted {
+			if !c.isFull() {
+				selected = c
+				break
+			}
+		}
+	default:
+		selected = sorted[0]
+	}
+
+	autoscaler.Log.V(1).Info("Selected one of the matching scale targets", "policy", policy, "selected", selected.id(), "candidates", strings.Join(ids, ","))
+
+	return &selected.target
+}
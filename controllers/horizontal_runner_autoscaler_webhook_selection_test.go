@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSelectScaleTarget(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	candidate := func(name string, priority *int, maxReplicas *int, reserved int, labels ...string) scaleTargetCandidate {
+		hra := v1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+				Priority:    priority,
+				MaxReplicas: maxReplicas,
+			},
+		}
+
+		if reserved > 0 {
+			hra.Status.CapacityReservations = []v1alpha1.CapacityReservation{
+				{Replicas: reserved, ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)}},
+			}
+		}
+
+		return scaleTargetCandidate{
+			target:       ScaleTarget{HorizontalRunnerAutoscaler: hra},
+			runnerLabels: labels,
+		}
+	}
+
+	jobLabels := []string{"self-hosted", "linux"}
+
+	candidates := []scaleTargetCandidate{
+		candidate("c", nil, intPtr(2), 2, "linux", "gpu"),
+		candidate("b", intPtr(1), intPtr(1), 1, "linux", "gpu", "large"),
+		candidate("a", nil, nil, 0, "linux"),
+		candidate("d", intPtr(1), intPtr(5), 0, "linux", "arm64"),
+	}
+
+	testcases := []struct {
+		policy string
+		want   []string
+	}{
+		{policy: ScaleTargetSelectionPolicyPriority, want: []string{"b", "b"}},
+		{policy: ScaleTargetSelectionPolicySpecificity, want: []string{"a", "a"}},
+		{policy: ScaleTargetSelectionPolicySpillover, want: []string{"d", "d"}},
+		{policy: ScaleTargetSelectionPolicyRoundRobin, want: []string{"b", "d", "a", "c", "b"}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.policy, func(t *testing.T) {
+			autoscaler := &HorizontalRunnerAutoscalerGitHubWebhook{
+				Log:                        logr.Discard(),
+				ScaleTargetSelectionPolicy: tc.policy,
+			}
+
+			var got []string
+
+			for range tc.want {
+				got = append(got, autoscaler.selectScaleTarget(jobLabels, candidates, true).HorizontalRunnerAutoscaler.Name)
+			}
+
+			require.Equal(t, tc.want, got)
+		})
+	}
+
+	// All the targets are full
+	autoscaler := &HorizontalRunnerAutoscalerGitHubWebhook{Log: logr.Discard(), ScaleTargetSelectionPolicy: ScaleTargetSelectionPolicySpillover}
+	got := autoscaler.selectScaleTarget(jobLabels, candidates[:2], true)
+	require.Equal(t, "b", got.HorizontalRunnerAutoscaler.Name)
+}
+
+func TestSelectJobScaleTarget(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	candidate := func(name string, priority int, maxReplicas int, reservations ...v1alpha1.CapacityReservation) scaleTargetCandidate {
+		for i := range reservations {
+			reservations[i].Replicas = 1
+			reservations[i].ExpirationTime = metav1.Time{Time: time.Now().Add(time.Hour)}
+		}
+
+		return scaleTargetCandidate{
+			target: ScaleTarget{HorizontalRunnerAutoscaler: v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+				Spec:       v1alpha1.HorizontalRunnerAutoscalerSpec{Priority: intPtr(priority), MaxReplicas: intPtr(maxReplicas)},
+				Status:     v1alpha1.HorizontalRunnerAutoscalerStatus{CapacityReservations: reservations},
+			}},
+		}
+	}
+
+	repoSet := []scaleTargetCandidate{
+		candidate("repo-a", 1, 1, v1alpha1.CapacityReservation{JobID: 1}),
+		candidate("repo-b", 0, 1, v1alpha1.CapacityReservation{RunID: 10, JobName: "build"}),
+	}
+	orgSet := []scaleTargetCandidate{
+		candidate("org-a", 0, 5, v1alpha1.CapacityReservation{JobID: 2}),
+	}
+	sets := [][]scaleTargetCandidate{repoSet, orgSet}
+
+	name := func(st *ScaleTarget) string {
+		if st == nil {
+			return ""
+		}
+		return st.HorizontalRunnerAutoscaler.Name
+	}
+
+	t.Run("holder", func(t *testing.T) {
+		autoscaler := &HorizontalRunnerAutoscalerGitHubWebhook{Log: logr.Discard(), ScaleTargetSelectionPolicy: ScaleTargetSelectionPolicyRoundRobin}
+
+		require.Equal(t, "org-a", name(autoscaler.selectJobScaleTarget(nil, &WorkflowJobRef{ID: 2}, false, sets)), "the completed event must go to the HRA holding the job across the candidate sets")
+		require.Equal(t, "repo-b", name(autoscaler.selectJobScaleTarget(nil, &WorkflowJobRef{ID: 3, RunID: 10, Name: "build"}, false, sets)))
+		require.Equal(t, "org-a", name(autoscaler.selectJobScaleTarget(nil, &WorkflowJobRef{ID: 2}, true, sets)), "a duplicate queued event must go to the HRA holding the job")
+		require.Empty(t, autoscaler.roundRobin, "the routing to the holder must not advance the round-robin counters")
+	})
+
+	t.Run("not queued", func(t *testing.T) {
+		autoscaler := &HorizontalRunnerAutoscalerGitHubWebhook{Log: logr.Discard(), ScaleTargetSelectionPolicy: ScaleTargetSelectionPolicyRoundRobin}
+
+		for i := 0; i < 2; i++ {
+			require.Equal(t, "repo-a", name(autoscaler.selectJobScaleTarget(nil, &WorkflowJobRef{ID: 4}, false, sets)))
+			require.Equal(t, "repo-a", name(autoscaler.selectJobScaleTarget(nil, nil, false, sets)), "the resync must select the highest priority candidate")
+		}
+		require.Empty(t, autoscaler.roundRobin, "the events other than queued must not advance the round-robin counters")
+
+		require.Equal(t, "repo-a", name(autoscaler.selectJobScaleTarget(nil, &WorkflowJobRef{ID: 4}, true, sets)))
+		require.Equal(t, "repo-b", name(autoscaler.selectJobScaleTarget(nil, &WorkflowJobRef{ID: 4}, true, sets)))
+	})
+
+	t.Run("spillover", func(t *testing.T) {
+		autoscaler := &HorizontalRunnerAutoscalerGitHubWebhook{Log: logr.Discard(), ScaleTargetSelectionPolicy: ScaleTargetSelectionPolicySpillover}
+
+		require.Equal(t, "org-a", name(autoscaler.selectJobScaleTarget(nil, &WorkflowJobRef{ID: 4}, true, sets)), "the job must spill over from the full repository runners to the organizational runners")
+		require.Equal(t, "repo-a", name(autoscaler.selectJobScaleTarget(nil, &WorkflowJobRef{ID: 4}, true, sets[:1])), "the highest priority candidate must be selected when all are full")
+		require.Nil(t, autoscaler.selectJobScaleTarget(nil, &WorkflowJobRef{ID: 4}, true, nil))
+	})
+}
+
+func TestValidateScaleTargetSelectionPolicy(t *testing.T) {
+	require.NoError(t, ValidateScaleTargetSelectionPolicy(""))
+	require.NoError(t, ValidateScaleTargetSelectionPolicy(ScaleTargetSelectionPolicySpillover))
+	require.Error(t, ValidateScaleTargetSelectionPolicy("LeastLoaded"))
+}
@@ -434,6 +434,159 @@ func TestWebhookWorkflowJobWithSelfHostedLabel(t *testing.T) {
 	})
 }
 
+func TestWebhookWorkflowJobWithFilters(t *testing.T) {
+	setupTest := func() map[string]interface{} {
+		f, err := os.Open("testdata/org_webhook_workflow_job_payload.json")
+		if err != nil {
+			t.Fatalf("could not open the fixture: %s", err)
+		}
+		defer f.Close()
+		var e map[string]interface{}
+		if err := json.NewDecoder(f).Decode(&e); err != nil {
+			t.Fatalf("invalid json: %s", err)
+		}
+
+		// go-github's WorkflowJob lacks these fields so we add them to the raw payload
+		job := e["workflow_job"].(map[string]interface{})
+		job["workflow_name"] = "CI"
+		job["head_branch"] = "feature/foo"
+
+		return e
+	}
+
+	newInitObjs := func(workflowJob actionsv1alpha1.WorkflowJobSpec) []runtime.Object {
+		hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: "test-name",
+				},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							WorkflowJob: &workflowJob,
+						},
+					},
+				},
+			},
+		}
+
+		rd := &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-name",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						RunnerConfig: actionsv1alpha1.RunnerConfig{
+							Organization: "MYORG",
+							Labels:       []string{"label1"},
+						},
+					},
+				},
+			},
+		}
+
+		return []runtime.Object{hra, rd}
+	}
+
+	testcases := []struct {
+		name        string
+		workflowJob actionsv1alpha1.WorkflowJobSpec
+		want        string
+	}{
+		{
+			name: "MatchingFilters",
+			workflowJob: actionsv1alpha1.WorkflowJobSpec{
+				Repositories:  []string{"MYREPO"},
+				WorkflowNames: []string{"C*"},
+				Names:         []string{"build"},
+				Branches:      []string{"feature/*"},
+				Labels:        []string{"label1"},
+			},
+			want: "scaled test-name by 1",
+		},
+		{
+			name: "MatchingFullRepositoryName",
+			workflowJob: actionsv1alpha1.WorkflowJobSpec{
+				Repositories: []string{"MYORG/MYREPO"},
+			},
+			want: "scaled test-name by 1",
+		},
+		{
+			name: "WrongRepository",
+			workflowJob: actionsv1alpha1.WorkflowJobSpec{
+				Repositories: []string{"OTHERREPO"},
+			},
+			want: "no horizontalrunnerautoscaler to scale for this github event",
+		},
+		{
+			name: "WrongWorkflowName",
+			workflowJob: actionsv1alpha1.WorkflowJobSpec{
+				WorkflowNames: []string{"Release"},
+			},
+			want: "no horizontalrunnerautoscaler to scale for this github event",
+		},
+		{
+			name: "WrongJobName",
+			workflowJob: actionsv1alpha1.WorkflowJobSpec{
+				Names: []string{"test*"},
+			},
+			want: "no horizontalrunnerautoscaler to scale for this github event",
+		},
+		{
+			name: "WrongBranch",
+			workflowJob: actionsv1alpha1.WorkflowJobSpec{
+				Branches: []string{"main"},
+			},
+			want: "no horizontalrunnerautoscaler to scale for this github event",
+		},
+		{
+			name: "MissingRequiredLabel",
+			workflowJob: actionsv1alpha1.WorkflowJobSpec{
+				Labels: []string{"gpu"},
+			},
+			want: "no horizontalrunnerautoscaler to scale for this github event",
+		},
+		{
+			name: "ExcludedLabel",
+			workflowJob: actionsv1alpha1.WorkflowJobSpec{
+				ExcludedLabels: []string{"label1"},
+			},
+			want: "no horizontalrunnerautoscaler to scale for this github event",
+		},
+		{
+			name: "MatchingLabelInDifferentCase",
+			workflowJob: actionsv1alpha1.WorkflowJobSpec{
+				Labels: []string{"LABEL1"},
+			},
+			want: "scaled test-name by 1",
+		},
+		{
+			name: "ExcludedLabelInDifferentCase",
+			workflowJob: actionsv1alpha1.WorkflowJobSpec{
+				ExcludedLabels: []string{"Label1"},
+			},
+			want: "no horizontalrunnerautoscaler to scale for this github event",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			testServerWithInitObjs(t,
+				"workflow_job",
+				setupTest(),
+				200,
+				tc.want,
+				newInitObjs(tc.workflowJob),
+			)
+		})
+	}
+}
+
 func TestGetRequest(t *testing.T) {
 	hra := HorizontalRunnerAutoscalerGitHubWebhook{}
 	request, _ := http.NewRequest(http.MethodGet, "/", nil)
@@ -451,7 +604,7 @@ func TestGetValidCapacityReservations(t *testing.T) {
 	now := time.Now()
 
 	hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
-		Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+		Status: actionsv1alpha1.HorizontalRunnerAutoscalerStatus{
 			CapacityReservations: []actionsv1alpha1.CapacityReservation{
 				{
 					ExpirationTime: metav1.Time{Time: now.Add(-time.Second)},
@@ -0,0 +1,255 @@
+package controllers
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+)
+
+// timestampedReplicas is either a recommendation of the replicas, or a change of the replicas made at the time.
+type timestampedReplicas struct {
+	time     time.Time
+	replicas int
+}
+
+// scalingHistory is the recent recommendations and scale events of a HRA, which is kept in memory like HorizontalPodAutoscaler does.
+type scalingHistory struct {
+	recommendations []timestampedReplicas
+	scaleUpEvents   []timestampedReplicas
+	scaleDownEvents []timestampedReplicas
+}
+
+// scalingHistories is the scaling histories of all the HRAs with behaviors.
+type scalingHistories struct {
+	mu        sync.Mutex
+	histories map[types.NamespacedName]*scalingHistory
+}
+
+// applyBehavior stabilizes and rate-limits the change from the current to the desired replicas according to the behavior,
+// records the recommendation and the resulting change to the HRA's history, and returns the replicas to be applied.
+func (h *scalingHistories) applyBehavior(nsName types.NamespacedName, behavior v1alpha1.HorizontalRunnerAutoscalerBehavior, now time.Time, current, desired int) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.histories == nil {
+		h.histories = map[types.NamespacedName]*scalingHistory{}
+	}
+
+	history, ok := h.histories[nsName]
+	if !ok {
+		history = &scalingHistory{}
+		h.histories[nsName] = history
+	}
+
+	history.prune(behavior, now)
+
+	history.recommendations = append(history.recommendations, timestampedReplicas{time: now, replicas: desired})
+
+	replicas := stabilizeRecommendation(history.recommendations, behavior, now, current, desired)
+
+	// The policies only slow down the change toward the stabilized replicas, which is already within minReplicas and maxReplicas
+	if rules := behavior.ScaleUp; replicas > current && rules != nil {
+		limit := replicas
+
+		if rules.SelectPolicy == v1alpha1.ScalingPolicySelectDisabled {
+			limit = current
+		} else if len(rules.Policies) > 0 {
+			limit = calculateScaleUpLimit(current, history.scaleUpEvents, *rules, now)
+		}
+
+		if limit < current {
+			limit = current
+		}
+
+		if replicas > limit {
+			replicas = limit
+		}
+	} else if rules := behavior.ScaleDown; replicas < current && rules != nil {
+		limit := replicas
+
+		if rules.SelectPolicy == v1alpha1.ScalingPolicySelectDisabled {
+			limit = current
+		} else if len(rules.Policies) > 0 {
+			limit = calculateScaleDownLimit(current, history.scaleDownEvents, *rules, now)
+		}
+
+		if limit > current {
+			limit = current
+		}
+
+		if replicas < limit {
+			replicas = limit
+		}
+	}
+
+	if replicas > current {
+		history.scaleUpEvents = append(history.scaleUpEvents, timestampedReplicas{time: now, replicas: replicas - current})
+	} else if replicas < current {
+		history.scaleDownEvents = append(history.scaleDownEvents, timestampedReplicas{time: now, replicas: current - replicas})
+	}
+
+	return replicas
+}
+
+// prune removes the recommendations and the scale events older than the longest window and period of the behavior.
+func (h *scalingHistory) prune(behavior v1alpha1.HorizontalRunnerAutoscalerBehavior, now time.Time) {
+	var longest int32
+
+	for _, rules := range []*v1alpha1.ScalingRules{behavior.ScaleUp, behavior.ScaleDown} {
+		if rules == nil {
+			continue
+		}
+
+		if w := rules.StabilizationWindowSeconds; w != nil && *w > longest {
+			longest = *w
+		}
+
+		for _, p := range rules.Policies {
+			if p.PeriodSeconds > longest {
+				longest = p.PeriodSeconds
+			}
+		}
+	}
+
+	cutoff := now.Add(-time.Duration(longest) * time.Second)
+
+	prune := func(items []timestampedReplicas) []timestampedReplicas {
+		var kept []timestampedReplicas
+
+		for _, i := range items {
+			if i.time.After(cutoff) {
+				kept = append(kept, i)
+			}
+		}
+
+		return kept
+	}
+
+	h.recommendations = prune(h.recommendations)
+	h.scaleUpEvents = prune(h.scaleUpEvents)
+	h.scaleDownEvents = prune(h.scaleDownEvents)
+}
+
+// stabilizeRecommendation returns the replicas stabilized by the recommendations within the stabilization windows,
+// so that the replicas are scaled up only to the lowest recommendation within the scale-up window,
+// and down only to the highest recommendation within the scale-down window.
+func stabilizeRecommendation(recommendations []timestampedReplicas, behavior v1alpha1.HorizontalRunnerAutoscalerBehavior, now time.Time, current, desired int) int {
+	window := func(rules *v1alpha1.ScalingRules) time.Duration {
+		if rules == nil || rules.StabilizationWindowSeconds == nil {
+			return 0
+		}
+
+		return time.Duration(*rules.StabilizationWindowSeconds) * time.Second
+	}
+
+	upCutoff := now.Add(-window(behavior.ScaleUp))
+	downCutoff := now.Add(-window(behavior.ScaleDown))
+
+	up, down := desired, desired
+
+	for _, r := range recommendations {
+		if r.time.After(upCutoff) && r.replicas < up {
+			up = r.replicas
+		}
+
+		if r.time.After(downCutoff) && r.replicas > down {
+			down = r.replicas
+		}
+	}
+
+	replicas := current
+
+	if replicas < up {
+		replicas = up
+	}
+
+	if replicas > down {
+		replicas = down
+	}
+
+	return replicas
+}
+
+// sumChangesInPeriod returns the sum of the changes made within the period before now.
+func sumChangesInPeriod(events []timestampedReplicas, periodSeconds int32, now time.Time) int {
+	cutoff := now.Add(-time.Duration(periodSeconds) * time.Second)
+
+	var sum int
+
+	for _, e := range events {
+		if e.time.After(cutoff) {
+			sum += e.replicas
+		}
+	}
+
+	return sum
+}
+
+// calculateScaleUpLimit returns the maximum replicas allowed by the scale-up policies.
+func calculateScaleUpLimit(current int, events []timestampedReplicas, rules v1alpha1.ScalingRules, now time.Time) int {
+	selectMin := rules.SelectPolicy == v1alpha1.ScalingPolicySelectMin
+
+	result := math.MinInt32
+	if selectMin {
+		result = math.MaxInt32
+	}
+
+	for _, p := range rules.Policies {
+		periodStartReplicas := current - sumChangesInPeriod(events, p.PeriodSeconds, now)
+
+		var proposed int
+
+		switch p.Type {
+		case v1alpha1.ScalingPolicyTypePercent:
+			proposed = int(math.Ceil(float64(periodStartReplicas) * (1 + float64(p.Value)/100)))
+
+			// Any percent of zero is zero, which would keep the target scaled to zero forever,
+			// and revert the target woken up by the webhook server
+			if p.Value > 0 && proposed < periodStartReplicas+1 {
+				proposed = periodStartReplicas + 1
+			}
+		default:
+			proposed = periodStartReplicas + int(p.Value)
+		}
+
+		if (selectMin && proposed < result) || (!selectMin && proposed > result) {
+			result = proposed
+		}
+	}
+
+	return result
+}
+
+// calculateScaleDownLimit returns the minimum replicas allowed by the scale-down policies.
+func calculateScaleDownLimit(current int, events []timestampedReplicas, rules v1alpha1.ScalingRules, now time.Time) int {
+	// Selecting the policy allowing the smallest change means selecting the highest limit
+	selectMin := rules.SelectPolicy == v1alpha1.ScalingPolicySelectMin
+
+	result := math.MaxInt32
+	if selectMin {
+		result = math.MinInt32
+	}
+
+	for _, p := range rules.Policies {
+		periodStartReplicas := current + sumChangesInPeriod(events, p.PeriodSeconds, now)
+
+		var proposed int
+
+		switch p.Type {
+		case v1alpha1.ScalingPolicyTypePercent:
+			proposed = int(float64(periodStartReplicas) * (1 - float64(p.Value)/100))
+		default:
+			proposed = periodStartReplicas - int(p.Value)
+		}
+
+		if (selectMin && proposed > result) || (!selectMin && proposed < result) {
+			result = proposed
+		}
+	}
+
+	return result
+}
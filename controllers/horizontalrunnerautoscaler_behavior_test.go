@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+)
+
+func TestApplyBehavior(t *testing.T) {
+	int32Ptr := func(v int32) *int32 { return &v }
+
+	nsName := types.NamespacedName{Namespace: "default", Name: "example"}
+	start := time.Date(2022, 3, 7, 9, 0, 0, 0, time.UTC)
+
+	type step struct {
+		after   time.Duration
+		current int
+		desired int
+		want    int
+	}
+
+	testcases := []struct {
+		name     string
+		behavior v1alpha1.HorizontalRunnerAutoscalerBehavior
+		steps    []step
+	}{
+		{
+			name: "at most 2 pods or 50 percent per minute whichever is larger",
+			behavior: v1alpha1.HorizontalRunnerAutoscalerBehavior{
+				ScaleUp: &v1alpha1.ScalingRules{
+					Policies: []v1alpha1.ScalingPolicy{
+						{Type: v1alpha1.ScalingPolicyTypePods, Value: 2, PeriodSeconds: 60},
+						{Type: v1alpha1.ScalingPolicyTypePercent, Value: 50, PeriodSeconds: 60},
+					},
+				},
+			},
+			steps: []step{
+				{after: 0, current: 2, desired: 10, want: 4},
+				// The 2 pods added 30 seconds ago count toward the period
+				{after: 30 * time.Second, current: 4, desired: 10, want: 4},
+				{after: 61 * time.Second, current: 4, desired: 10, want: 6},
+				{after: 122 * time.Second, current: 6, desired: 10, want: 9},
+			},
+		},
+		{
+			name: "at least 1 pod per minute from zero with a percent policy",
+			behavior: v1alpha1.HorizontalRunnerAutoscalerBehavior{
+				ScaleUp: &v1alpha1.ScalingRules{
+					Policies: []v1alpha1.ScalingPolicy{
+						{Type: v1alpha1.ScalingPolicyTypePercent, Value: 100, PeriodSeconds: 60},
+					},
+				},
+			},
+			steps: []step{
+				{after: 0, current: 0, desired: 5, want: 1},
+				{after: 30 * time.Second, current: 1, desired: 5, want: 1},
+				{after: 61 * time.Second, current: 1, desired: 5, want: 2},
+				{after: 122 * time.Second, current: 2, desired: 5, want: 4},
+			},
+		},
+		{
+			name: "the smaller of the policies",
+			behavior: v1alpha1.HorizontalRunnerAutoscalerBehavior{
+				ScaleUp: &v1alpha1.ScalingRules{
+					SelectPolicy: v1alpha1.ScalingPolicySelectMin,
+					Policies: []v1alpha1.ScalingPolicy{
+						{Type: v1alpha1.ScalingPolicyTypePods, Value: 2, PeriodSeconds: 60},
+						{Type: v1alpha1.ScalingPolicyTypePercent, Value: 50, PeriodSeconds: 60},
+					},
+				},
+			},
+			steps: []step{
+				{after: 0, current: 8, desired: 20, want: 10},
+			},
+		},
+		{
+			name: "stabilized scale down",
+			behavior: v1alpha1.HorizontalRunnerAutoscalerBehavior{
+				ScaleDown: &v1alpha1.ScalingRules{
+					StabilizationWindowSeconds: int32Ptr(300),
+				},
+			},
+			steps: []step{
+				{after: 0, current: 10, desired: 10, want: 10},
+				{after: time.Minute, current: 10, desired: 3, want: 10},
+				{after: 2 * time.Minute, current: 10, desired: 5, want: 10},
+				// The recommendation of 10 is out of the window
+				{after: 301 * time.Second, current: 10, desired: 4, want: 5},
+				// Scaling up isn't stabilized
+				{after: 302 * time.Second, current: 5, desired: 12, want: 12},
+			},
+		},
+		{
+			name: "at most 10 percent per minute down",
+			behavior: v1alpha1.HorizontalRunnerAutoscalerBehavior{
+				ScaleDown: &v1alpha1.ScalingRules{
+					Policies: []v1alpha1.ScalingPolicy{
+						{Type: v1alpha1.ScalingPolicyTypePercent, Value: 10, PeriodSeconds: 60},
+					},
+				},
+			},
+			steps: []step{
+				{after: 0, current: 20, desired: 0, want: 18},
+				{after: 30 * time.Second, current: 18, desired: 0, want: 18},
+				{after: 61 * time.Second, current: 18, desired: 0, want: 16},
+			},
+		},
+		{
+			name: "disabled scale down",
+			behavior: v1alpha1.HorizontalRunnerAutoscalerBehavior{
+				ScaleDown: &v1alpha1.ScalingRules{
+					SelectPolicy: v1alpha1.ScalingPolicySelectDisabled,
+				},
+			},
+			steps: []step{
+				{after: 0, current: 5, desired: 1, want: 5},
+				{after: time.Second, current: 5, desired: 7, want: 7},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var h scalingHistories
+
+			for i, s := range tc.steps {
+				got := h.applyBehavior(nsName, tc.behavior, start.Add(s.after), s.current, s.desired)
+				require.Equal(t, s.want, got, "step %d", i)
+			}
+		})
+	}
+}
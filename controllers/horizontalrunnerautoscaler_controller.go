@@ -20,19 +20,26 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strconv"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/actions-runner-controller/actions-runner-controller/github"
+	"github.com/actions-runner-controller/actions-runner-controller/github/ratelimit"
 	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -54,6 +61,37 @@ type HorizontalRunnerAutoscalerReconciler struct {
 	CacheDuration         time.Duration
 	DefaultScaleDownDelay time.Duration
 	Name                  string
+
+	// MetricProviders serves custom HRA metric types in addition to the built-in ones.
+	// The built-in metric providers are registered on the first reconciliation.
+	MetricProviders *MetricProviderRegistry
+
+	// JobStateCache is shared among all the HRAs to read workflow runs and jobs of repositories.
+	// If nil, the reconciler creates one that refreshes each repository at most once per CacheDuration.
+	JobStateCache *github.JobStateCache
+
+	// GitHubClients provides the clients for HRAs and scale targets with their own GitHub API credentials
+	GitHubClients *MultiGitHubClient
+
+	// APIReader reads the demand history ConfigMaps bypassing the cache, so that the controller doesn't cache
+	// all the ConfigMaps in the cluster. If nil, the cached client is used.
+	APIReader client.Reader
+
+	metricProvidersInit sync.Once
+	metricProvidersErr  error
+
+	jobStateCacheInit sync.Once
+
+	// jobStateCaches holds the job state cache for each GitHub client other than the default one
+	jobStateCachesMu sync.Mutex
+	jobStateCaches   map[*github.Client]*github.JobStateCache
+
+	organizationWorkflowRuns organizationWorkflowRunsCache
+
+	// scalingHistories holds the recent recommendations and scale events of the HRAs with behaviors
+	scalingHistories scalingHistories
+
+	placeholderRunnerChecks placeholderRunnerChecks
 }
 
 const defaultReplicas = 1
@@ -63,6 +101,7 @@ const defaultReplicas = 1
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers/finalizers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;create;update
 
 func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("horizontalrunnerautoscaler", req.NamespacedName)
@@ -73,7 +112,7 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 	}
 
 	if !hra.ObjectMeta.DeletionTimestamp.IsZero() {
-		return ctrl.Result{}, nil
+		return r.reconcileDeletion(ctx, log, hra)
 	}
 
 	metrics.SetHorizontalRunnerAutoscalerSpec(hra.ObjectMeta, hra.Spec)
@@ -87,6 +126,8 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 			Namespace: req.Namespace,
 			Name:      hra.Spec.ScaleTargetRef.Name,
 		}, &rd); err != nil {
+			r.updateStatusOnError(ctx, log, hra, v1alpha1.ConditionTypeAbleToScale, reasonFailedGetScaleTarget, fmt.Errorf("getting runnerdeployment %s: %w", hra.Spec.ScaleTargetRef.Name, err))
+
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
 
@@ -103,7 +144,7 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 
 			var effectiveTime *time.Time
 
-			for _, r := range hra.Spec.CapacityReservations {
+			for _, r := range capacityReservations(hra) {
 				t := r.EffectiveTime
 				if effectiveTime == nil || effectiveTime.Before(t.Time) {
 					effectiveTime = &t.Time
@@ -138,6 +179,8 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 			Namespace: req.Namespace,
 			Name:      hra.Spec.ScaleTargetRef.Name,
 		}, &rs); err != nil {
+			r.updateStatusOnError(ctx, log, hra, v1alpha1.ConditionTypeAbleToScale, reasonFailedGetScaleTarget, fmt.Errorf("getting runnerset %s: %w", hra.Spec.ScaleTargetRef.Name, err))
+
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
 
@@ -153,13 +196,15 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 		}
 
 		st := scaleTarget{
-			st:         rs.Name,
-			kind:       "runnerset",
-			enterprise: rs.Spec.Enterprise,
-			org:        rs.Spec.Organization,
-			repo:       rs.Spec.Repository,
-			replicas:   replicas,
-			labels:     rs.Spec.RunnerConfig.Labels,
+			st:                       rs.Name,
+			kind:                     "runnerset",
+			enterprise:               rs.Spec.Enterprise,
+			org:                      rs.Spec.Organization,
+			repo:                     rs.Spec.Repository,
+			replicas:                 replicas,
+			labels:                   rs.Spec.RunnerConfig.Labels,
+			group:                    rs.Spec.RunnerConfig.Group,
+			githubAPICredentialsFrom: rs.Spec.RunnerConfig.GitHubAPICredentialsFrom,
 			getRunnerMap: func() (map[string]struct{}, error) {
 				// return the list of runners in namespace. Horizontal Runner Autoscaler should only be responsible for scaling resources in its own ns.
 				var runnerPodList corev1.PodList
@@ -207,7 +252,7 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 
 			var effectiveTime *time.Time
 
-			for _, r := range hra.Spec.CapacityReservations {
+			for _, r := range capacityReservations(hra) {
 				t := r.EffectiveTime
 				if effectiveTime == nil || effectiveTime.Before(t.Time) {
 					effectiveTime = &t.Time
@@ -241,18 +286,22 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(ctx context.Context, re
 
 	log.Info(fmt.Sprintf("Unsupported scale target %s %s: kind %s is not supported. valid kinds are %s and %s", kind, hra.Spec.ScaleTargetRef.Name, kind, "RunnerDeployment", "RunnerSet"))
 
+	r.updateStatusOnError(ctx, log, hra, v1alpha1.ConditionTypeAbleToScale, reasonUnsupportedScaleTarget, fmt.Errorf("unsupported scaleTargetRef.kind %q: valid kinds are RunnerDeployment and RunnerSet", kind))
+
 	return ctrl.Result{}, nil
 }
 
 func (r *HorizontalRunnerAutoscalerReconciler) scaleTargetFromRD(ctx context.Context, rd v1alpha1.RunnerDeployment) scaleTarget {
 	st := scaleTarget{
-		st:         rd.Name,
-		kind:       "runnerdeployment",
-		enterprise: rd.Spec.Template.Spec.Enterprise,
-		org:        rd.Spec.Template.Spec.Organization,
-		repo:       rd.Spec.Template.Spec.Repository,
-		replicas:   rd.Spec.Replicas,
-		labels:     rd.Spec.Template.Spec.RunnerConfig.Labels,
+		st:                       rd.Name,
+		kind:                     "runnerdeployment",
+		enterprise:               rd.Spec.Template.Spec.Enterprise,
+		org:                      rd.Spec.Template.Spec.Organization,
+		repo:                     rd.Spec.Template.Spec.Repository,
+		replicas:                 rd.Spec.Replicas,
+		labels:                   rd.Spec.Template.Spec.RunnerConfig.Labels,
+		group:                    rd.Spec.Template.Spec.RunnerConfig.Group,
+		githubAPICredentialsFrom: rd.Spec.Template.Spec.GitHubAPICredentialsFrom,
 		getRunnerMap: func() (map[string]struct{}, error) {
 			// return the list of runners in namespace. Horizontal Runner Autoscaler should only be responsible for scaling resources in its own ns.
 			var runnerList v1alpha1.RunnerList
@@ -296,8 +345,32 @@ type scaleTarget struct {
 	enterprise, repo, org string
 	replicas              *int
 	labels                []string
+	group                 string
+
+	// githubAPICredentialsFrom is the GitHub API credentials of the runners, which is used when the HRA has none
+	githubAPICredentialsFrom *v1alpha1.GitHubAPICredentialsFrom
+	githubClient             *github.Client
 
 	getRunnerMap func() (map[string]struct{}, error)
+
+	// metricStatuses collects the values observed for the metrics while computing the desired replicas, if not nil
+	metricStatuses *[]v1alpha1.MetricStatus
+}
+
+func (st scaleTarget) metricTarget() MetricTarget {
+	return MetricTarget{
+		Name:         st.st,
+		Kind:         st.kind,
+		Enterprise:   st.enterprise,
+		Organization: st.org,
+		Repository:   st.repo,
+		Replicas:     st.replicas,
+		Labels:       st.labels,
+		GetRunnerMap: st.getRunnerMap,
+		GitHubClient: st.githubClient,
+
+		metricStatuses: st.metricStatuses,
+	}
 }
 
 func (r *HorizontalRunnerAutoscalerReconciler) reconcile(ctx context.Context, req ctrl.Request, log logr.Logger, hra v1alpha1.HorizontalRunnerAutoscaler, st scaleTarget, updatedDesiredReplicas func(int) error) (ctrl.Result, error) {
@@ -305,26 +378,152 @@ func (r *HorizontalRunnerAutoscalerReconciler) reconcile(ctx context.Context, re
 
 	minReplicas, active, upcoming, err := r.getMinReplicas(log, now, hra)
 	if err != nil {
+		r.updateStatusOnError(ctx, log, hra, v1alpha1.ConditionTypeScalingActive, reasonFailedComputeMin, err)
+
 		log.Error(err, "Could not compute min replicas")
 
 		return ctrl.Result{}, err
 	}
 
-	newDesiredReplicas, err := r.computeReplicasWithCache(log, now, st, hra, minReplicas)
-	if err != nil {
+	var (
+		history    []v1alpha1.DemandHistoryBucket
+		prediction *demandPrediction
+	)
+
+	if hra.Spec.Predictive != nil {
+		history, prediction, err = r.predictDemand(ctx, now, hra)
+		if err != nil {
+			r.Recorder.Event(&hra, corev1.EventTypeWarning, "RunnerAutoscalingFailure", err.Error())
+
+			r.updateStatusOnError(ctx, log, hra, v1alpha1.ConditionTypeScalingActive, reasonFailedPredictDemand, err)
+
+			log.Error(err, "Could not predict demand")
+
+			return ctrl.Result{}, err
+		}
+
+		// The prediction only raises minReplicas, so that the current demand is never ignored
+		if prediction != nil && prediction.replicas <= minReplicas {
+			prediction = nil
+		}
+
+		if prediction != nil {
+			log.V(1).Info("Raising min replicas ahead of a recurring peak", "prediction", prediction.String(), "minReplicas", minReplicas)
+
+			minReplicas = prediction.replicas
+		}
+	}
+
+	// Scheduled overrides and predictions explicitly raising minReplicas take precedence over scaling to zero
+	if hra.Spec.ScaleToZero != nil && prediction == nil && (active == nil || active.ScheduledOverride.MinReplicas == nil) && isIdle(hra, now) {
+		log.V(1).Info("Scaling to zero as the scale target is idle", "lastActiveTime", hra.Status.LastActiveTime, "minReplicas", minReplicas)
+
+		minReplicas = 0
+	}
+
+	credsFrom := hra.Spec.GitHubAPICredentialsFrom
+	if credsFrom == nil {
+		credsFrom = st.githubAPICredentialsFrom
+	}
+
+	if credsFrom != nil {
+		ghc, err := r.GitHubClients.ClientFor(ctx, r.GitHubClient, hra.Namespace, credsFrom)
+		if err != nil {
+			r.Recorder.Event(&hra, corev1.EventTypeWarning, "RunnerAutoscalingFailure", err.Error())
+
+			r.updateStatusOnError(ctx, log, hra, v1alpha1.ConditionTypeScalingActive, reasonFailedGetGitHubClient, err)
+
+			log.Error(err, "Could not get github client")
+
+			return ctrl.Result{}, err
+		}
+
+		st.githubClient = ghc
+	}
+
+	var metricStatuses []v1alpha1.MetricStatus
+
+	st.metricStatuses = &metricStatuses
+
+	decision, err := r.computeScalingDecision(log, now, st, applyScheduledOverride(hra, active), minReplicas)
+	if retryAfter, throttled := ratelimit.IsThrottled(err); throttled {
+		r.Recorder.Event(&hra, corev1.EventTypeWarning, "GitHubAPIThrottled", err.Error())
+
+		r.updateStatusOnError(ctx, log, hra, v1alpha1.ConditionTypeScalingActive, reasonGitHubAPIThrottled, err)
+
+		log.Info("Skipped computing replicas as GitHub API calls are being throttled", "retry_after", retryAfter, "error", err.Error())
+
+		// Keep the current replicas and retry once the budget recovers, instead of retrying with the exponential backoff
+		if retryAfter < time.Second {
+			retryAfter = time.Second
+		}
+
+		return ctrl.Result{RequeueAfter: retryAfter}, nil
+	} else if err != nil {
 		r.Recorder.Event(&hra, corev1.EventTypeNormal, "RunnerAutoscalingFailure", err.Error())
 
+		r.updateStatusOnError(ctx, log, hra, v1alpha1.ConditionTypeScalingActive, reasonFailedGetMetrics, err)
+
 		log.Error(err, "Could not compute replicas")
 
 		return ctrl.Result{}, err
 	}
 
+	newDesiredReplicas := decision.Desired
+
+	var overflow int
+	if decision.Overflow != nil {
+		overflow = *decision.Overflow
+	}
+
 	if err := updatedDesiredReplicas(newDesiredReplicas); err != nil {
+		r.updateStatusOnError(ctx, log, hra, v1alpha1.ConditionTypeAbleToScale, reasonFailedUpdateScale, err)
+
 		return ctrl.Result{}, err
 	}
 
 	updated := hra.DeepCopy()
 
+	// The previous overflow target would otherwise keep the replicas it had when it was replaced or removed
+	if prev := hra.Status.OverflowTargetRef; prev != nil && (hra.Spec.OverflowTargetRef == nil || !prev.RefersTo(*hra.Spec.OverflowTargetRef)) {
+		if err := r.scaleOverflowTarget(ctx, hra.Namespace, *prev, 0); err != nil && !kerrors.IsNotFound(err) {
+			r.Recorder.Event(&hra, corev1.EventTypeWarning, "RunnerAutoscalingFailure", err.Error())
+
+			r.updateStatusOnError(ctx, log, hra, v1alpha1.ConditionTypeAbleToScale, reasonFailedUpdateScale, err)
+
+			log.Error(err, "Could not scale previous overflow target to zero", "kind", prev.Kind, "name", prev.Name)
+
+			return ctrl.Result{}, err
+		}
+
+		log.Info("Scaled previous overflow target to zero", "kind", prev.Kind, "name", prev.Name)
+	}
+
+	updated.Status.OverflowTargetRef = hra.Spec.OverflowTargetRef.DeepCopy()
+
+	if ref := hra.Spec.OverflowTargetRef; ref != nil {
+		if err := r.scaleOverflowTarget(ctx, hra.Namespace, *ref, overflow); err != nil {
+			r.Recorder.Event(&hra, corev1.EventTypeWarning, "RunnerAutoscalingFailure", err.Error())
+
+			r.updateStatusOnError(ctx, log, hra, v1alpha1.ConditionTypeAbleToScale, reasonFailedUpdateScale, err)
+
+			log.Error(err, "Could not scale overflow target")
+
+			return ctrl.Result{}, err
+		}
+
+		if hra.Status.DesiredOverflowReplicas == nil || *hra.Status.DesiredOverflowReplicas != overflow {
+			updated.Status.DesiredOverflowReplicas = &overflow
+		}
+	} else {
+		updated.Status.DesiredOverflowReplicas = nil
+	}
+
+	setScalingConditions(updated, hra.Status.DesiredReplicas, decision)
+
+	updated.Status.LastScalingDecision = &decision
+	updated.Status.LastMetrics = metricStatuses
+
 	if hra.Status.DesiredReplicas == nil || *hra.Status.DesiredReplicas != newDesiredReplicas {
 		if (hra.Status.DesiredReplicas == nil && newDesiredReplicas > 1) ||
 			(hra.Status.DesiredReplicas != nil && newDesiredReplicas > *hra.Status.DesiredReplicas) {
@@ -335,23 +534,30 @@ func (r *HorizontalRunnerAutoscalerReconciler) reconcile(ctx context.Context, re
 		updated.Status.DesiredReplicas = &newDesiredReplicas
 	}
 
-	var overridesSummary string
-
-	if (active != nil && upcoming == nil) || (active != nil && upcoming != nil && active.Period.EndTime.Before(upcoming.Period.StartTime)) {
-		after := defaultReplicas
-		if hra.Spec.MinReplicas != nil && *hra.Spec.MinReplicas >= 0 {
-			after = *hra.Spec.MinReplicas
+	if p := hra.Spec.Predictive; p != nil {
+		if err := r.recordDemandHistory(ctx, log, now, hra, updated, st, history, prediction, newDesiredReplicas); err != nil {
+			log.Error(err, "Could not record demand")
 		}
+	} else {
+		updated.Status.DemandHistory = nil
+	}
 
-		overridesSummary = fmt.Sprintf("min=%d time=%s", after, active.Period.EndTime)
+	if hra.Spec.ScaleToZero != nil {
+		r.updateScaleToZeroStatus(ctx, log, now, hra, updated, st, newDesiredReplicas, minReplicas)
+	} else {
+		updated.Status.LastActiveTime = nil
+
+		r.cleanUpPlaceholderRunner(ctx, log, hra, updated, r.githubClient(st.metricTarget()))
 	}
 
-	if active == nil && upcoming != nil || (active != nil && upcoming != nil && active.Period.EndTime.After(upcoming.Period.StartTime)) {
-		if upcoming.ScheduledOverride.MinReplicas != nil {
-			overridesSummary = fmt.Sprintf("min=%d time=%s", *upcoming.ScheduledOverride.MinReplicas, upcoming.Period.StartTime)
-		}
+	if s := prediction.String(); s != "" {
+		updated.Status.PredictiveSummary = &s
+	} else {
+		updated.Status.PredictiveSummary = nil
 	}
 
+	overridesSummary := scheduledOverridesSummary(hra, active, upcoming)
+
 	if overridesSummary != "" {
 		updated.Status.ScheduledOverridesSummary = &overridesSummary
 	} else {
@@ -369,6 +575,124 @@ func (r *HorizontalRunnerAutoscalerReconciler) reconcile(ctx context.Context, re
 	return ctrl.Result{}, nil
 }
 
+// scheduledOverridesSummary describes the replicas after the active scheduled override ends,
+// or the replicas while the upcoming one is active.
+func scheduledOverridesSummary(hra v1alpha1.HorizontalRunnerAutoscaler, active, upcoming *Override) string {
+	var overridesSummary string
+
+	if (active != nil && upcoming == nil) || (active != nil && upcoming != nil && active.Period.EndTime.Before(upcoming.Period.StartTime)) {
+		after := defaultReplicas
+		if hra.Spec.MinReplicas != nil && *hra.Spec.MinReplicas >= 0 {
+			after = *hra.Spec.MinReplicas
+		}
+
+		overridesSummary = fmt.Sprintf("min=%d time=%s", after, active.Period.EndTime)
+
+		if active.ScheduledOverride.MaxReplicas != nil {
+			// maxReplicas is unbounded again after the override ends when it's omitted
+			max := "unlimited"
+			if hra.Spec.MaxReplicas != nil {
+				max = strconv.Itoa(*hra.Spec.MaxReplicas)
+			}
+
+			overridesSummary = fmt.Sprintf("min=%d max=%s time=%s", after, max, active.Period.EndTime)
+		}
+	}
+
+	if active == nil && upcoming != nil || (active != nil && upcoming != nil && active.Period.EndTime.After(upcoming.Period.StartTime)) {
+		if minR, maxR := upcoming.ScheduledOverride.MinReplicas, upcoming.ScheduledOverride.MaxReplicas; minR != nil && maxR != nil {
+			overridesSummary = fmt.Sprintf("min=%d max=%d time=%s", *minR, *maxR, upcoming.Period.StartTime)
+		} else if minR != nil {
+			overridesSummary = fmt.Sprintf("min=%d time=%s", *minR, upcoming.Period.StartTime)
+		} else if maxR != nil {
+			overridesSummary = fmt.Sprintf("max=%d time=%s", *maxR, upcoming.Period.StartTime)
+		}
+	}
+
+	return overridesSummary
+}
+
+// scaleOverflowTarget updates the replicas of the RunnerDeployment or RunnerSet that receives the replicas above MaxReplicas.
+func (r *HorizontalRunnerAutoscalerReconciler) scaleOverflowTarget(ctx context.Context, namespace string, ref v1alpha1.ScaleTargetRef, replicas int) error {
+	nsName := types.NamespacedName{Namespace: namespace, Name: ref.Name}
+
+	switch ref.Kind {
+	case "", "RunnerDeployment":
+		var rd v1alpha1.RunnerDeployment
+		if err := r.Get(ctx, nsName, &rd); err != nil {
+			return fmt.Errorf("getting overflow runnerdeployment %s: %w", ref.Name, err)
+		}
+
+		if rd.Spec.Replicas != nil && *rd.Spec.Replicas == replicas {
+			return nil
+		}
+
+		copy := rd.DeepCopy()
+		copy.Spec.Replicas = &replicas
+
+		if err := r.Client.Patch(ctx, copy, client.MergeFrom(&rd)); err != nil {
+			return fmt.Errorf("patching overflow runnerdeployment to have %d replicas: %w", replicas, err)
+		}
+	case "RunnerSet":
+		var rs v1alpha1.RunnerSet
+		if err := r.Get(ctx, nsName, &rs); err != nil {
+			return fmt.Errorf("getting overflow runnerset %s: %w", ref.Name, err)
+		}
+
+		if rs.Spec.Replicas != nil && int(*rs.Spec.Replicas) == replicas {
+			return nil
+		}
+
+		copy := rs.DeepCopy()
+		v := int32(replicas)
+		copy.Spec.Replicas = &v
+
+		if err := r.Client.Patch(ctx, copy, client.MergeFrom(&rs)); err != nil {
+			return fmt.Errorf("patching overflow runnerset to have %d replicas: %w", replicas, err)
+		}
+	default:
+		return fmt.Errorf("unsupported overflowTargetRef.kind: %v", ref.Kind)
+	}
+
+	return nil
+}
+
+// githubClient returns the GitHub client for the metric target, which defaults to the controller-wide one.
+func (r *HorizontalRunnerAutoscalerReconciler) githubClient(st MetricTarget) *github.Client {
+	if st.GitHubClient != nil {
+		return st.GitHubClient
+	}
+
+	return r.GitHubClient
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) jobStateCache(ghc *github.Client) *github.JobStateCache {
+	if ghc == nil || ghc == r.GitHubClient {
+		r.jobStateCacheInit.Do(func() {
+			if r.JobStateCache == nil {
+				r.JobStateCache = github.NewJobStateCache(r.GitHubClient, r.CacheDuration, r.Log.WithName("jobstatecache"))
+			}
+		})
+
+		return r.JobStateCache
+	}
+
+	r.jobStateCachesMu.Lock()
+	defer r.jobStateCachesMu.Unlock()
+
+	if r.jobStateCaches == nil {
+		r.jobStateCaches = map[*github.Client]*github.JobStateCache{}
+	}
+
+	c, ok := r.jobStateCaches[ghc]
+	if !ok {
+		c = github.NewJobStateCache(ghc, r.CacheDuration, r.Log.WithName("jobstatecache"))
+		r.jobStateCaches[ghc] = c
+	}
+
+	return c
+}
+
 func (r *HorizontalRunnerAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	name := "horizontalrunnerautoscaler-controller"
 	if r.Name != "" {
@@ -378,11 +702,40 @@ func (r *HorizontalRunnerAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager
 	r.Recorder = mgr.GetEventRecorderFor(name)
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.HorizontalRunnerAutoscaler{}).
+		For(&v1alpha1.HorizontalRunnerAutoscaler{}, builder.WithPredicates(predicate.Funcs{
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				old, ok := e.ObjectOld.(*v1alpha1.HorizontalRunnerAutoscaler)
+				if !ok {
+					return true
+				}
+
+				new, ok := e.ObjectNew.(*v1alpha1.HorizontalRunnerAutoscaler)
+				if !ok {
+					return true
+				}
+
+				return hraUpdateNeedsReconcile(old, new)
+			},
+		})).
 		Named(name).
 		Complete(r)
 }
 
+// hraUpdateNeedsReconcile returns false for the updates only to the status fields written by the reconciler itself,
+// like the conditions, the metric values, and the scaling decision, so that patching them doesn't trigger another reconciliation.
+// The capacity reservations are written to the status by the webhook-based autoscaler, and need to be applied right away.
+func hraUpdateNeedsReconcile(old, new *v1alpha1.HorizontalRunnerAutoscaler) bool {
+	if old.Generation != new.Generation {
+		return true
+	}
+
+	if !old.DeletionTimestamp.Equal(new.DeletionTimestamp) {
+		return true
+	}
+
+	return !equality.Semantic.DeepEqual(old.Status.CapacityReservations, new.Status.CapacityReservations)
+}
+
 type Override struct {
 	ScheduledOverride v1alpha1.ScheduledOverride
 	Period            Period
@@ -400,13 +753,23 @@ func (r *HorizontalRunnerAutoscalerReconciler) matchScheduledOverrides(log logr.
 			"endTime", o.EndTime,
 			"frequency", o.RecurrenceRule.Frequency,
 			"untilTime", o.RecurrenceRule.UntilTime,
+			"schedule", o.Schedule,
+			"timeZone", o.TimeZone,
 		)
 
+		var duration time.Duration
+		if o.Duration != nil {
+			duration = o.Duration.Duration
+		}
+
 		a, u, err := MatchSchedule(
 			now, o.StartTime.Time, o.EndTime.Time,
 			RecurrenceRule{
 				Frequency: o.RecurrenceRule.Frequency,
 				UntilTime: o.RecurrenceRule.UntilTime.Time,
+				Schedule:  o.Schedule,
+				Duration:  duration,
+				TimeZone:  o.TimeZone,
 			},
 		)
 		if err != nil {
@@ -445,6 +808,40 @@ func (r *HorizontalRunnerAutoscalerReconciler) matchScheduledOverrides(log logr.
 	return minReplicas, active, upcoming, nil
 }
 
+// applyScheduledOverride returns the copy of the HRA whose maxReplicas and metric thresholds are overridden by the active scheduled override, if any.
+// minReplicas is overridden by getMinReplicas instead.
+func applyScheduledOverride(hra v1alpha1.HorizontalRunnerAutoscaler, active *Override) v1alpha1.HorizontalRunnerAutoscaler {
+	if active == nil {
+		return hra
+	}
+
+	o := active.ScheduledOverride
+
+	overridden := hra.DeepCopy()
+
+	if o.MaxReplicas != nil {
+		overridden.Spec.MaxReplicas = o.MaxReplicas
+	}
+
+	for i := range overridden.Spec.Metrics {
+		m := &overridden.Spec.Metrics[i]
+
+		if m.Type != v1alpha1.AutoscalingMetricTypePercentageRunnersBusy {
+			continue
+		}
+
+		if o.ScaleUpThreshold != "" {
+			m.ScaleUpThreshold = o.ScaleUpThreshold
+		}
+
+		if o.ScaleDownThreshold != "" {
+			m.ScaleDownThreshold = o.ScaleDownThreshold
+		}
+	}
+
+	return *overridden
+}
+
 func (r *HorizontalRunnerAutoscalerReconciler) getMinReplicas(log logr.Logger, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler) (int, *Override, *Override, error) {
 	minReplicas := defaultReplicas
 	if hra.Spec.MinReplicas != nil && *hra.Spec.MinReplicas >= 0 {
@@ -462,11 +859,34 @@ func (r *HorizontalRunnerAutoscalerReconciler) getMinReplicas(log logr.Logger, n
 }
 
 func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(log logr.Logger, now time.Time, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, minReplicas int) (int, error) {
+	desired, _, err := r.computeReplicasAndOverflowWithCache(log, now, st, hra, minReplicas)
+
+	return desired, err
+}
+
+// computeReplicasAndOverflowWithCache computes the desired replicas of the scale target, and the desired replicas of the overflow target
+// which is the demand above MaxReplicas. The overflow is always zero when the HRA has no OverflowTargetRef.
+func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasAndOverflowWithCache(log logr.Logger, now time.Time, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, minReplicas int) (int, int, error) {
+	d, err := r.computeScalingDecision(log, now, st, hra, minReplicas)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var overflow int
+	if d.Overflow != nil {
+		overflow = *d.Overflow
+	}
+
+	return d.Desired, overflow, nil
+}
+
+// computeScalingDecision computes the desired replicas of the scale target along with the breakdown of them.
+func (r *HorizontalRunnerAutoscalerReconciler) computeScalingDecision(log logr.Logger, now time.Time, st scaleTarget, hra v1alpha1.HorizontalRunnerAutoscaler, minReplicas int) (v1alpha1.ScalingDecision, error) {
 	var suggestedReplicas int
 
 	v, err := r.suggestDesiredReplicas(st, hra)
 	if err != nil {
-		return 0, err
+		return v1alpha1.ScalingDecision{}, err
 	}
 
 	if v == nil {
@@ -477,7 +897,7 @@ func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(log logr
 
 	var reserved int
 
-	for _, reservation := range hra.Spec.CapacityReservations {
+	for _, reservation := range capacityReservations(hra) {
 		if reservation.ExpirationTime.Time.After(now) {
 			reserved += reservation.Replicas
 		}
@@ -485,6 +905,12 @@ func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(log logr
 
 	newDesiredReplicas := suggestedReplicas + reserved
 
+	var overflow int
+
+	if hra.Spec.OverflowTargetRef != nil && hra.Spec.MaxReplicas != nil && newDesiredReplicas > *hra.Spec.MaxReplicas {
+		overflow = newDesiredReplicas - *hra.Spec.MaxReplicas
+	}
+
 	if newDesiredReplicas < minReplicas {
 		newDesiredReplicas = minReplicas
 	} else if hra.Spec.MaxReplicas != nil && newDesiredReplicas > *hra.Spec.MaxReplicas {
@@ -516,11 +942,30 @@ func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(log logr
 		if t.After(now) {
 			scaleDownDelayUntil = &t
 			newDesiredReplicas = *hra.Status.DesiredReplicas
+
+			if current := hra.Status.DesiredOverflowReplicas; current != nil && *current > overflow {
+				overflow = *current
+			}
 		}
 	} else {
 		newDesiredReplicas = *hra.Status.DesiredReplicas
 	}
 
+	//
+	// Stabilize and rate-limit the change according to the behavior
+	//
+
+	var limitedReplicas *int
+
+	if b := hra.Spec.Behavior; b != nil && hra.Status.DesiredReplicas != nil {
+		nsName := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}
+
+		if v := r.scalingHistories.applyBehavior(nsName, *b, now, *hra.Status.DesiredReplicas, newDesiredReplicas); v != newDesiredReplicas {
+			limitedReplicas = &newDesiredReplicas
+			newDesiredReplicas = v
+		}
+	}
+
 	//
 	// Logs various numbers for monitoring and debugging purpose
 	//
@@ -535,14 +980,43 @@ func (r *HorizontalRunnerAutoscalerReconciler) computeReplicasWithCache(log logr
 		kvs = append(kvs, "max", *maxReplicas)
 	}
 
+	if hra.Spec.OverflowTargetRef != nil {
+		kvs = append(kvs, "overflow", overflow)
+	}
+
 	if scaleDownDelayUntil != nil {
 		kvs = append(kvs, "last_scale_up_time", *hra.Status.LastSuccessfulScaleOutTime)
 		kvs = append(kvs, "scale_down_delay_until", scaleDownDelayUntil)
 	}
 
+	if limitedReplicas != nil {
+		kvs = append(kvs, "limited_by_behavior_from", *limitedReplicas)
+	}
+
 	log.V(1).Info(fmt.Sprintf("Calculated desired replicas of %d", newDesiredReplicas),
 		kvs...,
 	)
 
-	return newDesiredReplicas, nil
+	d := v1alpha1.ScalingDecision{
+		Suggested:             suggestedReplicas,
+		Reserved:              reserved,
+		MinReplicas:           minReplicas,
+		Desired:               newDesiredReplicas,
+		LimitedByBehaviorFrom: limitedReplicas,
+	}
+
+	if maxReplicas := hra.Spec.MaxReplicas; maxReplicas != nil {
+		v := *maxReplicas
+		d.MaxReplicas = &v
+	}
+
+	if hra.Spec.OverflowTargetRef != nil {
+		d.Overflow = &overflow
+	}
+
+	if scaleDownDelayUntil != nil {
+		d.ScaleDownDelayUntil = &metav1.Time{Time: *scaleDownDelayUntil}
+	}
+
+	return d, nil
 }
@@ -0,0 +1,368 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/actions-runner-controller/actions-runner-controller/github/ratelimit"
+)
+
+const (
+	defaultPredictiveLeadTime = 15 * time.Minute
+	defaultPredictiveMinWeeks = 2
+
+	demandHistoryConfigMapKey = "history.json"
+)
+
+// demandPrediction is the demand predicted to recur within the lead time.
+type demandPrediction struct {
+	replicas int
+
+	// peak is the start of the hour of the predicted peak
+	peak time.Time
+
+	// weeks is the number of the weeks the peak was observed in
+	weeks int
+}
+
+func (p *demandPrediction) String() string {
+	if p == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("min=%d peak=%s weeks=%d", p.replicas, p.peak.Format(time.RFC3339), p.weeks)
+}
+
+func predictiveLocation(p v1alpha1.PredictiveScaling) (*time.Location, error) {
+	if p.TimeZone == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid predictive time zone %q: %w", p.TimeZone, err)
+	}
+
+	return loc, nil
+}
+
+func demandHistoryConfigMapName(hra v1alpha1.HorizontalRunnerAutoscaler) string {
+	return hra.Name + "-demand-history"
+}
+
+// predictDemand loads the demand history of the HRA, and returns it along with the demand predicted to recur within the lead time, if any.
+func (r *HorizontalRunnerAutoscalerReconciler) predictDemand(ctx context.Context, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler) ([]v1alpha1.DemandHistoryBucket, *demandPrediction, error) {
+	p := *hra.Spec.Predictive
+
+	loc, err := predictiveLocation(p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	history, err := r.loadDemandHistory(ctx, hra)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	leadTime := defaultPredictiveLeadTime
+	if p.LeadTime != nil {
+		leadTime = p.LeadTime.Duration
+	}
+
+	minWeeks := defaultPredictiveMinWeeks
+	if p.MinWeeks != nil {
+		minWeeks = *p.MinWeeks
+	}
+
+	return history, predictRecurringDemand(history, now.In(loc), leadTime, minWeeks), nil
+}
+
+// configMapReader returns the reader of the demand history ConfigMaps.
+// Reading them with the cached client would start an informer of all the ConfigMaps in the cluster.
+func (r *HorizontalRunnerAutoscalerReconciler) configMapReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+
+	return r.Client
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) loadDemandHistory(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler) ([]v1alpha1.DemandHistoryBucket, error) {
+	if hra.Spec.Predictive.Storage != v1alpha1.PredictiveScalingStorageConfigMap {
+		return hra.Status.DemandHistory, nil
+	}
+
+	var cm corev1.ConfigMap
+
+	if err := r.configMapReader().Get(ctx, types.NamespacedName{Namespace: hra.Namespace, Name: demandHistoryConfigMapName(hra)}, &cm); kerrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("getting demand history configmap: %w", err)
+	}
+
+	var history []v1alpha1.DemandHistoryBucket
+
+	if data := cm.Data[demandHistoryConfigMapKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &history); err != nil {
+			return nil, fmt.Errorf("unmarshaling demand history: %w", err)
+		}
+	}
+
+	return history, nil
+}
+
+// saveDemandHistory stores the history in the updated HRA status, which is patched by the caller, or in the ConfigMap owned by the HRA.
+func (r *HorizontalRunnerAutoscalerReconciler) saveDemandHistory(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler, updated *v1alpha1.HorizontalRunnerAutoscaler, history []v1alpha1.DemandHistoryBucket) error {
+	if hra.Spec.Predictive.Storage != v1alpha1.PredictiveScalingStorageConfigMap {
+		updated.Status.DemandHistory = history
+
+		return nil
+	}
+
+	updated.Status.DemandHistory = nil
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+
+	var cm corev1.ConfigMap
+
+	nsName := types.NamespacedName{Namespace: hra.Namespace, Name: demandHistoryConfigMapName(hra)}
+
+	if err := r.configMapReader().Get(ctx, nsName, &cm); kerrors.IsNotFound(err) {
+		cm = corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: nsName.Namespace,
+				Name:      nsName.Name,
+			},
+			Data: map[string]string{demandHistoryConfigMapKey: string(data)},
+		}
+
+		if err := ctrl.SetControllerReference(&hra, &cm, r.Scheme); err != nil {
+			return err
+		}
+
+		if err := r.Create(ctx, &cm); err != nil {
+			return fmt.Errorf("creating demand history configmap: %w", err)
+		}
+
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("getting demand history configmap: %w", err)
+	}
+
+	if cm.Data[demandHistoryConfigMapKey] == string(data) {
+		return nil
+	}
+
+	copy := cm.DeepCopy()
+	if copy.Data == nil {
+		copy.Data = map[string]string{}
+	}
+	copy.Data[demandHistoryConfigMapKey] = string(data)
+
+	if err := r.Update(ctx, copy); err != nil {
+		return fmt.Errorf("updating demand history configmap: %w", err)
+	}
+
+	return nil
+}
+
+// countBusyRunners returns the number of the runners of the scale target that are running workflow jobs.
+func (r *HorizontalRunnerAutoscalerReconciler) countBusyRunners(ctx context.Context, st scaleTarget) (int, error) {
+	runnerMap, err := st.getRunnerMap()
+	if err != nil {
+		return 0, err
+	}
+
+	// Counting busy runners only refines the demand history and the idleness, which can wait until the rate limit budget recovers
+	runners, err := r.githubClient(st.metricTarget()).ListRunners(ratelimit.WithPriority(ctx, ratelimit.PriorityLow), st.enterprise, st.org, st.repo)
+	if err != nil {
+		return 0, err
+	}
+
+	var busy int
+
+	for _, runner := range runners {
+		if _, ok := runnerMap[runner.GetName()]; ok && runner.GetBusy() {
+			busy++
+		}
+	}
+
+	return busy, nil
+}
+
+// hourOf returns the start of the hour of t in the location of t.
+func hourOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+
+// foldDemandBucket folds the peaks of the latest occurrence of the bucket's hour into the averages,
+// when the occurrence is over as of the hour.
+func foldDemandBucket(b v1alpha1.DemandHistoryBucket, hour time.Time) v1alpha1.DemandHistoryBucket {
+	if b.ObservedHour.IsZero() || b.ObservedHour.Time.Equal(hour) {
+		return b
+	}
+
+	if b.Weeks == 0 {
+		b.Desired, b.Busy = b.CurrentDesired, b.CurrentBusy
+	} else {
+		// Rounding up so that the prediction doesn't fall short of the peak
+		b.Desired = (b.Desired + b.CurrentDesired + 1) / 2
+		b.Busy = (b.Busy + b.CurrentBusy + 1) / 2
+	}
+
+	b.Weeks++
+	b.CurrentDesired, b.CurrentBusy = 0, 0
+	b.ObservedHour = metav1.Time{}
+
+	return b
+}
+
+// recordDemand returns the copy of the history with the demand observed at now, in the location of the buckets.
+// desired or busy can be nil when it's unknown.
+// It also returns true only when the bucket of now is added or folded, or its peak grows,
+// so that the caller doesn't persist the history on every reconciliation.
+func recordDemand(history []v1alpha1.DemandHistoryBucket, now time.Time, desired, busy *int) ([]v1alpha1.DemandHistoryBucket, bool) {
+	hour := hourOf(now)
+
+	recorded := make([]v1alpha1.DemandHistoryBucket, 0, len(history)+1)
+
+	i := -1
+
+	for _, b := range history {
+		if b.Weekday == int(now.Weekday()) && b.Hour == now.Hour() {
+			i = len(recorded)
+		}
+
+		recorded = append(recorded, b)
+	}
+
+	var changed bool
+
+	if i < 0 {
+		i = len(recorded)
+		recorded = append(recorded, v1alpha1.DemandHistoryBucket{Weekday: int(now.Weekday()), Hour: now.Hour()})
+		changed = true
+	}
+
+	b := recorded[i]
+
+	if b.ObservedHour.IsZero() || !b.ObservedHour.Time.Equal(hour) {
+		b = foldDemandBucket(b, hour)
+		b.ObservedHour = metav1.Time{Time: hour}
+		changed = true
+	}
+
+	if desired != nil && *desired > b.CurrentDesired {
+		b.CurrentDesired = *desired
+		changed = true
+	}
+
+	if busy != nil && *busy > b.CurrentBusy {
+		b.CurrentBusy = *busy
+		changed = true
+	}
+
+	if !changed {
+		return history, false
+	}
+
+	recorded[i] = b
+
+	sort.SliceStable(recorded, func(i, j int) bool {
+		if recorded[i].Weekday != recorded[j].Weekday {
+			return recorded[i].Weekday < recorded[j].Weekday
+		}
+
+		return recorded[i].Hour < recorded[j].Hour
+	})
+
+	return recorded, true
+}
+
+// predictRecurringDemand returns the highest peak observed in at least minWeeks weeks
+// in the hours from now to the lead time later, in the location of the buckets.
+func predictRecurringDemand(history []v1alpha1.DemandHistoryBucket, now time.Time, leadTime time.Duration, minWeeks int) *demandPrediction {
+	var prediction *demandPrediction
+
+	end := now.Add(leadTime)
+
+	var hours []time.Time
+
+	// We step by the instant rather than the wall clock so that it never loops around daylight saving time changes
+	for t := now; t.Before(end); t = t.Add(time.Hour) {
+		hours = append(hours, hourOf(t))
+	}
+
+	hours = append(hours, hourOf(end))
+
+	for _, hour := range hours {
+		for _, b := range history {
+			if b.Weekday != int(hour.Weekday()) || b.Hour != hour.Hour() {
+				continue
+			}
+
+			b = foldDemandBucket(b, hour)
+			if b.Weeks < minWeeks {
+				continue
+			}
+
+			peak := b.Desired
+			if b.Busy > peak {
+				peak = b.Busy
+			}
+
+			if peak > 0 && (prediction == nil || peak > prediction.replicas) {
+				prediction = &demandPrediction{replicas: peak, peak: hour, weeks: b.Weeks}
+			}
+		}
+	}
+
+	return prediction
+}
+
+// recordDemandHistory records the desired replicas and the busy runners to the demand history of the HRA.
+// The desired replicas aren't recorded while minReplicas is raised by the prediction, so that the prediction doesn't reinforce itself.
+func (r *HorizontalRunnerAutoscalerReconciler) recordDemandHistory(ctx context.Context, log logr.Logger, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler, updated *v1alpha1.HorizontalRunnerAutoscaler, st scaleTarget, history []v1alpha1.DemandHistoryBucket, prediction *demandPrediction, desiredReplicas int) error {
+	loc, err := predictiveLocation(*hra.Spec.Predictive)
+	if err != nil {
+		return err
+	}
+
+	var desired, busy *int
+
+	if prediction == nil {
+		desired = &desiredReplicas
+	}
+
+	if n, err := r.countBusyRunners(ctx, st); err != nil {
+		log.V(1).Info("Could not count busy runners for the demand history", "error", err.Error())
+	} else {
+		busy = &n
+	}
+
+	recorded, changed := recordDemand(history, now.In(loc), desired, busy)
+	if !changed {
+		if hra.Spec.Predictive.Storage == v1alpha1.PredictiveScalingStorageConfigMap {
+			updated.Status.DemandHistory = nil
+		}
+
+		return nil
+	}
+
+	return r.saveDemandHistory(ctx, hra, updated, recorded)
+}
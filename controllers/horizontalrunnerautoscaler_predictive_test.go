@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+)
+
+func TestPredictRecurringDemand(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	intPtr := func(v int) *int { return &v }
+
+	// Monday 2022-03-07, the daily merge rush at 09:00-10:00 in Berlin
+	monday := time.Date(2022, 3, 7, 0, 0, 0, 0, loc)
+
+	var history []v1alpha1.DemandHistoryBucket
+
+	record := func(now time.Time, desired, busy *int) {
+		history, _ = recordDemand(history, now, desired, busy)
+	}
+
+	for week := 0; week < 3; week++ {
+		base := monday.AddDate(0, 0, 7*week)
+
+		record(base.Add(8*time.Hour+30*time.Minute), intPtr(1), intPtr(0))
+		record(base.Add(9*time.Hour+10*time.Minute), intPtr(6), intPtr(4))
+		record(base.Add(9*time.Hour+40*time.Minute), intPtr(10), intPtr(8))
+		record(base.Add(10*time.Hour+30*time.Minute), intPtr(2), nil)
+	}
+
+	require.Len(t, history, 3)
+
+	// The observations of the third week are not folded yet
+	rush := history[1]
+	require.Equal(t, 1, rush.Weekday)
+	require.Equal(t, 9, rush.Hour)
+	require.Equal(t, 2, rush.Weeks)
+	require.Equal(t, 10, rush.Desired)
+	require.Equal(t, 8, rush.Busy)
+	require.Equal(t, 10, rush.CurrentDesired)
+
+	fourthMonday := monday.AddDate(0, 0, 21)
+
+	// 15 minutes ahead of the rush
+	p := predictRecurringDemand(history, fourthMonday.Add(8*time.Hour+50*time.Minute), 15*time.Minute, 2)
+	require.NotNil(t, p)
+	require.Equal(t, 10, p.replicas)
+	require.Equal(t, 3, p.weeks)
+	require.Equal(t, "min=10 peak=2022-03-28T09:00:00+02:00 weeks=3", p.String())
+
+	// Too early for the rush
+	p = predictRecurringDemand(history, fourthMonday.Add(8*time.Hour), 15*time.Minute, 2)
+	require.NotNil(t, p)
+	require.Equal(t, 1, p.replicas)
+
+	// The rush hasn't recurred for enough weeks
+	p = predictRecurringDemand(history, fourthMonday.Add(8*time.Hour+50*time.Minute), 15*time.Minute, 4)
+	require.Nil(t, p)
+
+	// No demand on Tuesday
+	p = predictRecurringDemand(history, fourthMonday.AddDate(0, 0, 1).Add(8*time.Hour+50*time.Minute), 15*time.Minute, 1)
+	require.Nil(t, p)
+}
+
+func TestFoldDemandBucket(t *testing.T) {
+	hour := time.Date(2022, 3, 7, 9, 0, 0, 0, time.UTC)
+
+	b := v1alpha1.DemandHistoryBucket{Weekday: 1, Hour: 9, Weeks: 1, Desired: 4, Busy: 3, CurrentDesired: 7, CurrentBusy: 6, ObservedHour: metav1.Time{Time: hour}}
+
+	require.Equal(t, b, foldDemandBucket(b, hour), "the ongoing hour must not be folded")
+
+	folded := foldDemandBucket(b, hour.AddDate(0, 0, 7))
+	require.Equal(t, 2, folded.Weeks)
+	require.Equal(t, 6, folded.Desired)
+	require.Equal(t, 5, folded.Busy)
+	require.Zero(t, folded.CurrentDesired)
+	require.Zero(t, folded.CurrentBusy)
+}
+
+func TestRecordDemand_Changed(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	now := time.Date(2022, 3, 7, 9, 10, 0, 0, time.UTC)
+
+	history, changed := recordDemand(nil, now, intPtr(3), intPtr(2))
+	require.True(t, changed, "a new bucket must be persisted")
+
+	_, changed = recordDemand(history, now.Add(10*time.Minute), intPtr(2), intPtr(2))
+	require.False(t, changed, "the history must not be persisted while the peak doesn't grow")
+
+	_, changed = recordDemand(history, now.Add(20*time.Minute), intPtr(4), nil)
+	require.True(t, changed, "a growing peak must be persisted")
+
+	_, changed = recordDemand(history, now.AddDate(0, 0, 7), nil, nil)
+	require.True(t, changed, "a folded bucket must be persisted")
+}
+
+func TestDemandHistoryConfigMap(t *testing.T) {
+	ctx := context.Background()
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example", UID: "uid"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			Predictive: &v1alpha1.PredictiveScaling{Storage: v1alpha1.PredictiveScalingStorageConfigMap},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(sc).Build()
+
+	r := &HorizontalRunnerAutoscalerReconciler{
+		Client:    &uncachedConfigMapClient{Client: c},
+		APIReader: c,
+		Scheme:    sc,
+	}
+
+	history, err := r.loadDemandHistory(ctx, hra)
+	require.NoError(t, err)
+	require.Empty(t, history)
+
+	one := 1
+	history, _ = recordDemand(history, time.Date(2022, 3, 7, 9, 0, 0, 0, time.UTC), &one, &one)
+
+	for i := 0; i < 2; i++ {
+		updated := hra.DeepCopy()
+		require.NoError(t, r.saveDemandHistory(ctx, hra, updated, history))
+		require.Empty(t, updated.Status.DemandHistory)
+	}
+
+	loaded, err := r.loadDemandHistory(ctx, hra)
+	require.NoError(t, err)
+	require.Len(t, loaded, 1)
+	require.Equal(t, 1, loaded[0].CurrentBusy)
+	require.True(t, loaded[0].ObservedHour.Time.Equal(history[0].ObservedHour.Time))
+}
+
+// uncachedConfigMapClient fails reading ConfigMaps, which would start an informer of all the ConfigMaps in the cluster
+// when read with the cached client
+type uncachedConfigMapClient struct {
+	client.Client
+}
+
+func (c *uncachedConfigMapClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if _, ok := obj.(*corev1.ConfigMap); ok {
+		return errors.New("configmaps must be read with the api reader")
+	}
+
+	return c.Client.Get(ctx, key, obj)
+}
@@ -0,0 +1,391 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	gogithub "github.com/google/go-github/v45/github"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/actions-runner-controller/actions-runner-controller/github"
+	"github.com/actions-runner-controller/actions-runner-controller/github/ratelimit"
+)
+
+const (
+	defaultScaleToZeroIdlePeriod = 10 * time.Minute
+
+	// lastActiveTimeResolution is how often LastActiveTime is updated while the scale target is active,
+	// so that the HRA status isn't updated on every reconciliation
+	lastActiveTimeResolution = time.Minute
+
+	// placeholderRunnerCheckInterval is how often the placeholder runner is checked to be still registered on GitHub
+	placeholderRunnerCheckInterval = 10 * time.Minute
+)
+
+// isIdle returns true when the scale target of the HRA has had no demand for the idle period.
+func isIdle(hra v1alpha1.HorizontalRunnerAutoscaler, now time.Time) bool {
+	last := hra.Status.LastActiveTime
+	if last == nil {
+		return false
+	}
+
+	idlePeriod := defaultScaleToZeroIdlePeriod
+	if p := hra.Spec.ScaleToZero.IdlePeriod; p != nil {
+		idlePeriod = p.Duration
+	}
+
+	return !last.Add(idlePeriod).After(now)
+}
+
+// hasDemand returns true when the scale target needs runners other than the ones kept by minReplicas.
+func (r *HorizontalRunnerAutoscalerReconciler) hasDemand(ctx context.Context, log logr.Logger, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler, st scaleTarget, desiredReplicas, minReplicas int) bool {
+	if desiredReplicas > minReplicas {
+		return true
+	}
+
+	for _, reservation := range capacityReservations(hra) {
+		if reservation.ExpirationTime.Time.After(now) && reservation.Replicas > 0 {
+			return true
+		}
+	}
+
+	if desiredReplicas == 0 {
+		return false
+	}
+
+	busy, err := r.countBusyRunners(ctx, st)
+	if err != nil {
+		// We'd rather keep the runners than scale them to zero while they might be running jobs
+		log.V(1).Info("Could not count busy runners to detect idleness", "error", err.Error())
+
+		return true
+	}
+
+	return busy > 0
+}
+
+// updateScaleToZeroStatus updates the last active time of the scale target, and registers the placeholder runner when it's scaled to zero.
+func (r *HorizontalRunnerAutoscalerReconciler) updateScaleToZeroStatus(ctx context.Context, log logr.Logger, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler, updated *v1alpha1.HorizontalRunnerAutoscaler, st scaleTarget, desiredReplicas, minReplicas int) {
+	if last := hra.Status.LastActiveTime; last == nil || (now.Sub(last.Time) >= lastActiveTimeResolution && r.hasDemand(ctx, log, now, hra, st, desiredReplicas, minReplicas)) {
+		updated.Status.LastActiveTime = &metav1.Time{Time: now}
+	}
+
+	if desiredReplicas > 0 || !hra.Spec.ScaleToZero.Placeholder {
+		// The placeholder runner is no longer needed once the real runners are coming up
+		r.cleanUpPlaceholderRunner(ctx, log, hra, updated, r.githubClient(st.metricTarget()))
+
+		return
+	}
+
+	if current, last := hra.Status.DesiredReplicas, hra.Status.LastActiveTime; (current == nil || *current > 0) && last != nil {
+		r.Recorder.Event(&hra, corev1.EventTypeNormal, "ScaledToZero", fmt.Sprintf("Scaled %s to zero after having no demand since %s", hra.Spec.ScaleTargetRef.Name, last.Format(time.RFC3339)))
+	}
+
+	placeholder, err := r.ensurePlaceholderRunner(ctx, log, now, hra, st)
+	if _, throttled := ratelimit.IsThrottled(err); throttled {
+		log.V(1).Info("Deferred checking placeholder runner until the rate limit budget recovers", "error", err.Error())
+
+		return
+	} else if err != nil {
+		r.Recorder.Event(&hra, corev1.EventTypeWarning, "PlaceholderRunnerFailure", err.Error())
+
+		log.Error(err, "Could not register placeholder runner")
+
+		return
+	}
+
+	updated.Status.PlaceholderRunner = placeholder
+}
+
+func placeholderRunnerName(hra v1alpha1.HorizontalRunnerAutoscaler) string {
+	return hra.Namespace + "-" + hra.Name + "-placeholder"
+}
+
+// placeholderRunnerChecks remembers when the placeholder runner of each HRA was last seen registered,
+// so that the runners aren't listed on every reconciliation while the scale target stays at zero.
+type placeholderRunnerChecks struct {
+	mu        sync.Mutex
+	checkedAt map[types.NamespacedName]time.Time
+}
+
+func (c *placeholderRunnerChecks) due(nsName types.NamespacedName, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	checkedAt, ok := c.checkedAt[nsName]
+
+	return !ok || now.Sub(checkedAt) >= placeholderRunnerCheckInterval
+}
+
+func (c *placeholderRunnerChecks) checked(nsName types.NamespacedName, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.checkedAt == nil {
+		c.checkedAt = map[types.NamespacedName]time.Time{}
+	}
+
+	c.checkedAt[nsName] = now
+}
+
+func (c *placeholderRunnerChecks) forget(nsName types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.checkedAt, nsName)
+}
+
+// ensurePlaceholderRunner registers an offline runner with the labels of the scale target, unless it's already registered,
+// and returns the runner to be recorded in the HRA status.
+// GitHub removes the runner after it has been offline for a while, in which case it's registered again.
+// The runners are listed at most once per placeholderRunnerCheckInterval, as the registration rarely goes away.
+func (r *HorizontalRunnerAutoscalerReconciler) ensurePlaceholderRunner(ctx context.Context, log logr.Logger, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler, st scaleTarget) (*v1alpha1.PlaceholderRunnerStatus, error) {
+	nsName := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}
+
+	if p := hra.Status.PlaceholderRunner; p != nil && !r.placeholderRunnerChecks.due(nsName, now) {
+		return p, nil
+	}
+
+	ghc := r.githubClient(st.metricTarget())
+
+	name := placeholderRunnerName(hra)
+
+	// The placeholder runner is only a convenience, which can wait until the rate limit budget recovers
+	runners, err := ghc.ListRunners(ratelimit.WithPriority(ctx, ratelimit.PriorityLow), st.enterprise, st.org, st.repo)
+	if err != nil {
+		return hra.Status.PlaceholderRunner, err
+	}
+
+	for _, runner := range runners {
+		if runner.GetName() == name {
+			r.placeholderRunnerChecks.checked(nsName, now)
+
+			return &v1alpha1.PlaceholderRunnerStatus{
+				ID:           runner.GetID(),
+				Name:         name,
+				Enterprise:   st.enterprise,
+				Organization: st.org,
+				Repository:   st.repo,
+			}, nil
+		}
+	}
+
+	// The finalizer is added before the registration, so that the runner is never left behind by the HRA deletion
+	if err := r.addPlaceholderRunnerFinalizer(ctx, hra); err != nil {
+		return hra.Status.PlaceholderRunner, err
+	}
+
+	groupID, err := ghc.GetRunnerGroupID(ctx, st.org, st.group)
+	if err != nil {
+		return hra.Status.PlaceholderRunner, fmt.Errorf("placeholder runner: %w", err)
+	}
+
+	labels := []string{"self-hosted"}
+
+	for _, l := range st.labels {
+		if l != "self-hosted" {
+			labels = append(labels, l)
+		}
+	}
+
+	config, err := ghc.GenerateJITConfig(ctx, st.enterprise, st.org, st.repo, &github.JITRunnerConfigRequest{
+		Name:          name,
+		RunnerGroupID: groupID,
+		Labels:        labels,
+	})
+	if err != nil {
+		return hra.Status.PlaceholderRunner, err
+	}
+
+	r.placeholderRunnerChecks.checked(nsName, now)
+
+	log.Info("Registered placeholder runner", "runner", name, "id", config.Runner.GetID(), "labels", labels)
+
+	r.Recorder.Event(&hra, corev1.EventTypeNormal, "PlaceholderRunnerRegistered", fmt.Sprintf("Registered placeholder runner %s with labels %v", name, labels))
+
+	return &v1alpha1.PlaceholderRunnerStatus{
+		ID:           config.Runner.GetID(),
+		Name:         name,
+		Enterprise:   st.enterprise,
+		Organization: st.org,
+		Repository:   st.repo,
+	}, nil
+}
+
+// removePlaceholderRunner unregisters the placeholder runner recorded in the HRA status, if any,
+// and then removes the finalizer that keeps the HRA until the runner is unregistered.
+func (r *HorizontalRunnerAutoscalerReconciler) removePlaceholderRunner(ctx context.Context, log logr.Logger, hra v1alpha1.HorizontalRunnerAutoscaler, ghc *github.Client) error {
+	if p := hra.Status.PlaceholderRunner; p != nil {
+		if err := ghc.RemoveRunner(ctx, p.Enterprise, p.Organization, p.Repository, p.ID); err != nil {
+			errRes := &gogithub.ErrorResponse{}
+
+			// GitHub has already removed the offline runner
+			if !errors.As(err, &errRes) || errRes.Response == nil || errRes.Response.StatusCode != http.StatusNotFound {
+				return fmt.Errorf("removing placeholder runner %s: %w", p.Name, err)
+			}
+		}
+
+		log.Info("Removed placeholder runner", "runner", p.Name, "id", p.ID)
+
+		r.Recorder.Event(&hra, corev1.EventTypeNormal, "PlaceholderRunnerRemoved", fmt.Sprintf("Removed placeholder runner %s", p.Name))
+	}
+
+	r.placeholderRunnerChecks.forget(types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name})
+
+	finalizers, removed := removeFinalizer(hra.ObjectMeta.Finalizers, placeholderRunnerFinalizerName)
+	if !removed {
+		return nil
+	}
+
+	copy := hra.DeepCopy()
+	copy.ObjectMeta.Finalizers = finalizers
+
+	if err := r.Patch(ctx, copy, client.MergeFrom(&hra)); err != nil {
+		return fmt.Errorf("removing placeholder runner finalizer: %w", err)
+	}
+
+	return nil
+}
+
+// cleanUpPlaceholderRunner removes the placeholder runner that is no longer needed, and clears it from the HRA status.
+// The failure is retried on the next reconciliation, as the runner stays in the status.
+func (r *HorizontalRunnerAutoscalerReconciler) cleanUpPlaceholderRunner(ctx context.Context, log logr.Logger, hra v1alpha1.HorizontalRunnerAutoscaler, updated *v1alpha1.HorizontalRunnerAutoscaler, ghc *github.Client) {
+	if _, hasFinalizer := removeFinalizer(hra.ObjectMeta.Finalizers, placeholderRunnerFinalizerName); hra.Status.PlaceholderRunner == nil && !hasFinalizer {
+		return
+	}
+
+	if err := r.removePlaceholderRunner(ctx, log, hra, ghc); err != nil {
+		r.Recorder.Event(&hra, corev1.EventTypeWarning, "PlaceholderRunnerFailure", err.Error())
+
+		log.Error(err, "Could not remove placeholder runner")
+
+		return
+	}
+
+	updated.Status.PlaceholderRunner = nil
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) addPlaceholderRunnerFinalizer(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler) error {
+	finalizers, added := addFinalizer(hra.ObjectMeta.Finalizers, placeholderRunnerFinalizerName)
+	if !added {
+		return nil
+	}
+
+	copy := hra.DeepCopy()
+	copy.ObjectMeta.Finalizers = finalizers
+
+	if err := r.Patch(ctx, copy, client.MergeFrom(&hra)); err != nil {
+		return fmt.Errorf("adding placeholder runner finalizer: %w", err)
+	}
+
+	return nil
+}
+
+// reconcileDeletion unregisters the placeholder runner of the HRA being deleted, and then removes the finalizer.
+func (r *HorizontalRunnerAutoscalerReconciler) reconcileDeletion(ctx context.Context, log logr.Logger, hra v1alpha1.HorizontalRunnerAutoscaler) (ctrl.Result, error) {
+	if _, hasFinalizer := removeFinalizer(hra.ObjectMeta.Finalizers, placeholderRunnerFinalizerName); !hasFinalizer {
+		return ctrl.Result{}, nil
+	}
+
+	ghc, err := r.placeholderRunnerClient(ctx, hra)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.removePlaceholderRunner(ctx, log, hra, ghc); err != nil {
+		r.Recorder.Event(&hra, corev1.EventTypeWarning, "PlaceholderRunnerFailure", err.Error())
+
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// placeholderRunnerClient returns the GitHub client for the credentials the placeholder runner was registered with,
+// which are the HRA's, the scale target's if it still exists, or the controller-wide ones.
+func (r *HorizontalRunnerAutoscalerReconciler) placeholderRunnerClient(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler) (*github.Client, error) {
+	credsFrom := hra.Spec.GitHubAPICredentialsFrom
+
+	if credsFrom == nil {
+		nsName := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Spec.ScaleTargetRef.Name}
+
+		switch hra.Spec.ScaleTargetRef.Kind {
+		case "", "RunnerDeployment":
+			var rd v1alpha1.RunnerDeployment
+			if err := r.Get(ctx, nsName, &rd); err == nil {
+				credsFrom = rd.Spec.Template.Spec.GitHubAPICredentialsFrom
+			} else if !kerrors.IsNotFound(err) {
+				return nil, err
+			}
+		case "RunnerSet":
+			var rs v1alpha1.RunnerSet
+			if err := r.Get(ctx, nsName, &rs); err == nil {
+				credsFrom = rs.Spec.RunnerConfig.GitHubAPICredentialsFrom
+			} else if !kerrors.IsNotFound(err) {
+				return nil, err
+			}
+		}
+	}
+
+	return r.GitHubClients.ClientFor(ctx, r.GitHubClient, hra.Namespace, credsFrom)
+}
+
+// wakeUpScaleTarget scales the target of the HRA from zero to one replica, so that the first queued workflow job
+// doesn't need to wait for the next reconciliation of the HRA. It returns true when the target is woken up.
+func wakeUpScaleTarget(ctx context.Context, c client.Client, hra v1alpha1.HorizontalRunnerAutoscaler) (bool, error) {
+	nsName := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Spec.ScaleTargetRef.Name}
+
+	switch hra.Spec.ScaleTargetRef.Kind {
+	case "", "RunnerDeployment":
+		var rd v1alpha1.RunnerDeployment
+		if err := c.Get(ctx, nsName, &rd); err != nil {
+			return false, err
+		}
+
+		if rd.Spec.Replicas == nil || *rd.Spec.Replicas > 0 {
+			return false, nil
+		}
+
+		one := 1
+
+		copy := rd.DeepCopy()
+		copy.Spec.Replicas = &one
+
+		if err := c.Patch(ctx, copy, client.MergeFrom(&rd)); err != nil {
+			return false, fmt.Errorf("patching runnerdeployment to wake it up: %w", err)
+		}
+	case "RunnerSet":
+		var rs v1alpha1.RunnerSet
+		if err := c.Get(ctx, nsName, &rs); err != nil {
+			return false, err
+		}
+
+		if rs.Spec.Replicas == nil || *rs.Spec.Replicas > 0 {
+			return false, nil
+		}
+
+		one := int32(1)
+
+		copy := rs.DeepCopy()
+		copy.Spec.Replicas = &one
+
+		if err := c.Patch(ctx, copy, client.MergeFrom(&rs)); err != nil {
+			return false, fmt.Errorf("patching runnerset to wake it up: %w", err)
+		}
+	default:
+		return false, fmt.Errorf("unsupported scaleTargetRef.kind: %v", hra.Spec.ScaleTargetRef.Kind)
+	}
+
+	return true, nil
+}
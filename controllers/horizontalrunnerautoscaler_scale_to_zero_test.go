@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	githubfake "github.com/actions-runner-controller/actions-runner-controller/github/fake"
+)
+
+func TestIsIdle(t *testing.T) {
+	now := time.Date(2022, 3, 7, 9, 0, 0, 0, time.UTC)
+
+	timePtr := func(t time.Time) *time.Time { return &t }
+	durationPtr := func(d time.Duration) *time.Duration { return &d }
+
+	testcases := []struct {
+		name       string
+		lastActive *time.Time
+		idlePeriod *time.Duration
+		want       bool
+	}{
+		{
+			name: "never observed",
+			want: false,
+		},
+		{
+			name:       "active within the default idle period",
+			lastActive: timePtr(now.Add(-9 * time.Minute)),
+			want:       false,
+		},
+		{
+			name:       "idle for the default idle period",
+			lastActive: timePtr(now.Add(-10 * time.Minute)),
+			want:       true,
+		},
+		{
+			name:       "active within the custom idle period",
+			lastActive: timePtr(now.Add(-time.Hour)),
+			idlePeriod: durationPtr(2 * time.Hour),
+			want:       false,
+		},
+		{
+			name:       "idle for the custom idle period",
+			lastActive: timePtr(now.Add(-time.Hour)),
+			idlePeriod: durationPtr(30 * time.Minute),
+			want:       true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleToZero: &v1alpha1.ScaleToZero{},
+				},
+			}
+
+			if tc.lastActive != nil {
+				hra.Status.LastActiveTime = &metav1.Time{Time: *tc.lastActive}
+			}
+
+			if tc.idlePeriod != nil {
+				hra.Spec.ScaleToZero.IdlePeriod = &metav1.Duration{Duration: *tc.idlePeriod}
+			}
+
+			require.Equal(t, tc.want, isIdle(hra, now))
+		})
+	}
+}
+
+func TestWakeUpScaleTarget(t *testing.T) {
+	ctx := context.Background()
+
+	intPtr := func(v int) *int { return &v }
+	int32Ptr := func(v int32) *int32 { return &v }
+
+	hraFor := func(kind, name string) v1alpha1.HorizontalRunnerAutoscaler {
+		return v1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"},
+			Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: v1alpha1.ScaleTargetRef{Kind: kind, Name: name},
+				ScaleToZero:    &v1alpha1.ScaleToZero{},
+			},
+		}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(sc).WithObjects(
+		&v1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "zero"},
+			Spec:       v1alpha1.RunnerDeploymentSpec{Replicas: intPtr(0)},
+		},
+		&v1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "two"},
+			Spec:       v1alpha1.RunnerDeploymentSpec{Replicas: intPtr(2)},
+		},
+		&v1alpha1.RunnerSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "zero"},
+		},
+	).Build()
+
+	var rs v1alpha1.RunnerSet
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "zero"}, &rs))
+	rs.Spec.Replicas = int32Ptr(0)
+	require.NoError(t, c.Update(ctx, &rs))
+
+	woken, err := wakeUpScaleTarget(ctx, c, hraFor("", "zero"))
+	require.NoError(t, err)
+	require.True(t, woken)
+
+	var rd v1alpha1.RunnerDeployment
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "zero"}, &rd))
+	require.Equal(t, 1, *rd.Spec.Replicas)
+
+	woken, err = wakeUpScaleTarget(ctx, c, hraFor("RunnerDeployment", "two"))
+	require.NoError(t, err)
+	require.False(t, woken)
+
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "two"}, &rd))
+	require.Equal(t, 2, *rd.Spec.Replicas)
+
+	woken, err = wakeUpScaleTarget(ctx, c, hraFor("RunnerSet", "zero"))
+	require.NoError(t, err)
+	require.True(t, woken)
+
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "zero"}, &rs))
+	require.Equal(t, int32(1), *rs.Spec.Replicas)
+
+	_, err = wakeUpScaleTarget(ctx, c, hraFor("RunnerDeployment", "missing"))
+	require.Error(t, err)
+}
+
+func TestEnsurePlaceholderRunner(t *testing.T) {
+	ctx := context.Background()
+
+	server := githubfake.NewServer(
+		githubfake.WithListRepositoryWorkflowRunsResponse(200, "{}", "{}", "{}"),
+		githubfake.WithListWorkflowJobsResponse(200, nil),
+		githubfake.WithListRunnersResponse(200, githubfake.RunnersListBody),
+	)
+	defer server.Close()
+
+	recorder := record.NewFakeRecorder(10)
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(sc).WithObjects(hra).Build()
+
+	r := &HorizontalRunnerAutoscalerReconciler{
+		Client:       c,
+		GitHubClient: newGithubClient(server),
+		Recorder:     recorder,
+	}
+
+	st := scaleTarget{org: "test", labels: []string{"self-hosted", "linux", "gpu"}}
+
+	now := time.Now()
+
+	placeholder, err := r.ensurePlaceholderRunner(ctx, logr.Discard(), now, *hra, st)
+	require.NoError(t, err)
+	require.Contains(t, <-recorder.Events, "Registered placeholder runner default-example-placeholder with labels [self-hosted linux gpu]")
+	require.Equal(t, &v1alpha1.PlaceholderRunnerStatus{ID: 3, Name: "default-example-placeholder", Organization: "test"}, placeholder)
+
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example"}, hra))
+	require.Equal(t, []string{placeholderRunnerFinalizerName}, hra.Finalizers, "the finalizer must be added before the registration")
+
+	// The registration isn't checked again within the interval
+	hra.Status.PlaceholderRunner = placeholder
+	st.group = "missing"
+
+	cached, err := r.ensurePlaceholderRunner(ctx, logr.Discard(), now.Add(time.Minute), *hra, st)
+	require.NoError(t, err)
+	require.Equal(t, placeholder, cached)
+
+	_, err = r.ensurePlaceholderRunner(ctx, logr.Discard(), now.Add(placeholderRunnerCheckInterval), *hra, st)
+	require.Error(t, err)
+}
+
+func TestRemovePlaceholderRunner(t *testing.T) {
+	ctx := context.Background()
+
+	server := githubfake.NewServer(
+		githubfake.WithListRepositoryWorkflowRunsResponse(200, "{}", "{}", "{}"),
+		githubfake.WithListWorkflowJobsResponse(200, nil),
+		githubfake.WithListRunnersResponse(200, githubfake.RunnersListBody),
+	)
+	defer server.Close()
+
+	for _, id := range []int64{
+		1,
+		// GitHub has already removed the runner, which is served as 404 by the fake server
+		3,
+	} {
+		recorder := record.NewFakeRecorder(10)
+
+		hra := &v1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example", Finalizers: []string{placeholderRunnerFinalizerName}},
+			Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				PlaceholderRunner: &v1alpha1.PlaceholderRunnerStatus{ID: id, Name: "default-example-placeholder", Organization: "test"},
+			},
+		}
+
+		c := fake.NewClientBuilder().WithScheme(sc).WithObjects(hra).Build()
+
+		r := &HorizontalRunnerAutoscalerReconciler{
+			Client:       c,
+			GitHubClient: newGithubClient(server),
+			Recorder:     recorder,
+		}
+
+		updated := hra.DeepCopy()
+
+		r.cleanUpPlaceholderRunner(ctx, logr.Discard(), *hra, updated, r.GitHubClient)
+		require.Nil(t, updated.Status.PlaceholderRunner)
+		require.Contains(t, <-recorder.Events, "Removed placeholder runner default-example-placeholder")
+
+		require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example"}, hra))
+		require.Empty(t, hra.Finalizers, "the finalizer must be removed once the runner is unregistered")
+	}
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+)
+
+// Reasons of the HRA conditions, which are named after the ones of HorizontalPodAutoscaler where applicable
+const (
+	reasonSucceededRescale       = "SucceededRescale"
+	reasonReadyForNewScale       = "ReadyForNewScale"
+	reasonScaleDownDelayed       = "ScaleDownDelayed"
+	reasonFailedGetScaleTarget   = "FailedGetScaleTarget"
+	reasonFailedUpdateScale      = "FailedUpdateScale"
+	reasonValidMetricComputed    = "ValidMetricComputed"
+	reasonNoMetrics              = "NoMetrics"
+	reasonFailedGetMetrics       = "FailedGetMetrics"
+	reasonFailedComputeMin       = "FailedComputeMinReplicas"
+	reasonFailedPredictDemand    = "FailedPredictDemand"
+	reasonFailedGetGitHubClient  = "FailedGetGitHubClient"
+	reasonGitHubAPIThrottled     = "GitHubAPIThrottled"
+	reasonTooFewReplicas         = "TooFewReplicas"
+	reasonTooManyReplicas        = "TooManyReplicas"
+	reasonLimitedByBehavior      = "LimitedByBehavior"
+	reasonDesiredWithinRange     = "DesiredWithinRange"
+	reasonUnsupportedScaleTarget = "UnsupportedScaleTarget"
+)
+
+type metricValueKey struct{}
+
+// withMetricValue returns the context to which a metric provider reports the observed value of the metric with setMetricValue.
+func withMetricValue(ctx context.Context, v *string) context.Context {
+	return context.WithValue(ctx, metricValueKey{}, v)
+}
+
+// setMetricValue reports the observed value of the metric to be shown in the HRA status.
+// It's a no-op when the caller doesn't collect the value.
+func setMetricValue(ctx context.Context, format string, args ...interface{}) {
+	if v, ok := ctx.Value(metricValueKey{}).(*string); ok && v != nil {
+		*v = fmt.Sprintf(format, args...)
+	}
+}
+
+// recordMetricStatus adds the value observed for the metric to the statuses collected for the scale target, if any.
+func (t MetricTarget) recordMetricStatus(metricType, value string, suggested *int) {
+	if t.metricStatuses == nil {
+		return
+	}
+
+	s := v1alpha1.MetricStatus{Type: metricType, Value: value}
+
+	if suggested != nil {
+		v := *suggested
+		s.SuggestedReplicas = &v
+	}
+
+	*t.metricStatuses = append(*t.metricStatuses, s)
+}
+
+// setScalingConditions sets the conditions explaining the scaling decision to the updated HRA.
+func setScalingConditions(updated *v1alpha1.HorizontalRunnerAutoscaler, current *int, d v1alpha1.ScalingDecision) {
+	generation := updated.Generation
+
+	able := metav1.Condition{
+		Type:               v1alpha1.ConditionTypeAbleToScale,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+	}
+
+	if until := d.ScaleDownDelayUntil; until != nil {
+		able.Reason = reasonScaleDownDelayed
+		able.Message = fmt.Sprintf("scaling down is delayed until %s after the last scale out", until.Format(time.RFC3339))
+	} else if current == nil || *current != d.Desired {
+		able.Reason = reasonSucceededRescale
+		able.Message = fmt.Sprintf("the desired replicas of the scale target were updated to %d", d.Desired)
+	} else {
+		able.Reason = reasonReadyForNewScale
+		able.Message = "the desired replicas of the scale target are up to date"
+	}
+
+	meta.SetStatusCondition(&updated.Status.Conditions, able)
+
+	active := metav1.Condition{
+		Type:               v1alpha1.ConditionTypeScalingActive,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+	}
+
+	if n := len(updated.Spec.Metrics); n > 0 {
+		active.Reason = reasonValidMetricComputed
+		active.Message = fmt.Sprintf("the desired replicas were computed from %d metric(s)", n)
+	} else {
+		active.Reason = reasonNoMetrics
+		active.Message = "the desired replicas were computed from minReplicas and the capacity reservations as no metric is configured"
+	}
+
+	meta.SetStatusCondition(&updated.Status.Conditions, active)
+
+	limited := metav1.Condition{
+		Type:               v1alpha1.ConditionTypeScalingLimited,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+	}
+
+	unbounded := d.Suggested + d.Reserved
+
+	if from := d.LimitedByBehaviorFrom; from != nil {
+		limited.Reason = reasonLimitedByBehavior
+		limited.Message = fmt.Sprintf("the desired replicas were limited from %d to %d by the behavior", *from, d.Desired)
+	} else if unbounded < d.MinReplicas {
+		limited.Reason = reasonTooFewReplicas
+		limited.Message = fmt.Sprintf("the desired replicas of %d (suggested %d + reserved %d) were raised to minReplicas of %d", unbounded, d.Suggested, d.Reserved, d.MinReplicas)
+	} else if max := d.MaxReplicas; max != nil && unbounded > *max {
+		limited.Reason = reasonTooManyReplicas
+		limited.Message = fmt.Sprintf("the desired replicas of %d (suggested %d + reserved %d) were limited to maxReplicas of %d", unbounded, d.Suggested, d.Reserved, *max)
+	} else {
+		limited.Status = metav1.ConditionFalse
+		limited.Reason = reasonDesiredWithinRange
+		limited.Message = "the desired replicas are within the range of minReplicas and maxReplicas"
+	}
+
+	meta.SetStatusCondition(&updated.Status.Conditions, limited)
+}
+
+// updateStatusOnError records the error and sets the condition of the type to false in the HRA status.
+// The status isn't updated when it already has the same error, so that a persistent error doesn't trigger
+// reconciliations other than the retries.
+func (r *HorizontalRunnerAutoscalerReconciler) updateStatusOnError(ctx context.Context, log logr.Logger, hra v1alpha1.HorizontalRunnerAutoscaler, conditionType, reason string, err error) {
+	message := err.Error()
+
+	if c := meta.FindStatusCondition(hra.Status.Conditions, conditionType); c != nil &&
+		c.Status == metav1.ConditionFalse && c.Reason == reason && c.Message == message && hra.Status.LastError == message {
+
+		return
+	}
+
+	updated := hra.DeepCopy()
+
+	meta.SetStatusCondition(&updated.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: hra.Generation,
+	})
+
+	if hra.Status.LastError != message {
+		updated.Status.LastError = message
+		updated.Status.LastErrorTime = &metav1.Time{Time: time.Now()}
+	}
+
+	if err := r.Status().Patch(ctx, updated, client.MergeFrom(&hra)); err != nil {
+		log.Error(err, "Could not update horizontalrunnerautoscaler status for the error")
+	}
+}
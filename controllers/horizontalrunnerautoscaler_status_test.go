@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+)
+
+func TestSetScalingConditions(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	until := metav1.NewTime(time.Date(2022, 3, 7, 9, 0, 0, 0, time.UTC))
+
+	testcases := []struct {
+		name     string
+		current  *int
+		metrics  int
+		decision v1alpha1.ScalingDecision
+		want     map[string]string
+	}{
+		{
+			name:     "scaled within range",
+			current:  intPtr(2),
+			metrics:  1,
+			decision: v1alpha1.ScalingDecision{Suggested: 3, Reserved: 1, MinReplicas: 1, MaxReplicas: intPtr(10), Desired: 4},
+			want: map[string]string{
+				v1alpha1.ConditionTypeAbleToScale:    reasonSucceededRescale,
+				v1alpha1.ConditionTypeScalingActive:  reasonValidMetricComputed,
+				v1alpha1.ConditionTypeScalingLimited: reasonDesiredWithinRange,
+			},
+		},
+		{
+			name:     "limited to max",
+			current:  intPtr(10),
+			metrics:  1,
+			decision: v1alpha1.ScalingDecision{Suggested: 12, Reserved: 2, MinReplicas: 1, MaxReplicas: intPtr(10), Desired: 10},
+			want: map[string]string{
+				v1alpha1.ConditionTypeAbleToScale:    reasonReadyForNewScale,
+				v1alpha1.ConditionTypeScalingActive:  reasonValidMetricComputed,
+				v1alpha1.ConditionTypeScalingLimited: reasonTooManyReplicas,
+			},
+		},
+		{
+			name:     "no metrics",
+			current:  intPtr(2),
+			decision: v1alpha1.ScalingDecision{Suggested: 2, MinReplicas: 2, Desired: 2},
+			want: map[string]string{
+				v1alpha1.ConditionTypeAbleToScale:    reasonReadyForNewScale,
+				v1alpha1.ConditionTypeScalingActive:  reasonNoMetrics,
+				v1alpha1.ConditionTypeScalingLimited: reasonDesiredWithinRange,
+			},
+		},
+		{
+			name:     "raised to min",
+			current:  intPtr(3),
+			metrics:  1,
+			decision: v1alpha1.ScalingDecision{Suggested: 0, MinReplicas: 3, Desired: 3},
+			want: map[string]string{
+				v1alpha1.ConditionTypeAbleToScale:    reasonReadyForNewScale,
+				v1alpha1.ConditionTypeScalingActive:  reasonValidMetricComputed,
+				v1alpha1.ConditionTypeScalingLimited: reasonTooFewReplicas,
+			},
+		},
+		{
+			name:     "scale down delayed and limited by behavior",
+			current:  intPtr(5),
+			metrics:  1,
+			decision: v1alpha1.ScalingDecision{Suggested: 1, MinReplicas: 1, Desired: 5, ScaleDownDelayUntil: &until, LimitedByBehaviorFrom: intPtr(4)},
+			want: map[string]string{
+				v1alpha1.ConditionTypeAbleToScale:    reasonScaleDownDelayed,
+				v1alpha1.ConditionTypeScalingActive:  reasonValidMetricComputed,
+				v1alpha1.ConditionTypeScalingLimited: reasonLimitedByBehavior,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			hra := &v1alpha1.HorizontalRunnerAutoscaler{}
+
+			for i := 0; i < tc.metrics; i++ {
+				hra.Spec.Metrics = append(hra.Spec.Metrics, v1alpha1.MetricSpec{Type: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy})
+			}
+
+			setScalingConditions(hra, tc.current, tc.decision)
+
+			require.Len(t, hra.Status.Conditions, 3)
+
+			for tpe, reason := range tc.want {
+				c := meta.FindStatusCondition(hra.Status.Conditions, tpe)
+				require.NotNil(t, c, tpe)
+				require.Equal(t, reason, c.Reason, tpe)
+			}
+		})
+	}
+}
+
+func TestSuggestDesiredReplicas_MetricStatuses(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	registry := NewMetricProviderRegistry()
+
+	require.NoError(t, registry.Register("Queue", MetricProviderFunc(func(ctx context.Context, _ MetricTarget, _ v1alpha1.HorizontalRunnerAutoscaler, _ v1alpha1.MetricSpec) (*int, error) {
+		setMetricValue(ctx, "queued=%d", 4)
+
+		return intPtr(4), nil
+	})))
+
+	require.NoError(t, registry.Register("Nil", MetricProviderFunc(func(_ context.Context, _ MetricTarget, _ v1alpha1.HorizontalRunnerAutoscaler, _ v1alpha1.MetricSpec) (*int, error) {
+		return nil, nil
+	})))
+
+	h := &HorizontalRunnerAutoscalerReconciler{
+		MetricProviders: registry,
+	}
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			MinReplicas:   intPtr(0),
+			MaxReplicas:   intPtr(10),
+			Metrics:       []v1alpha1.MetricSpec{{Type: "Nil"}, {Type: "Queue"}},
+			MetricsPolicy: v1alpha1.MetricsPolicyMax,
+		},
+	}
+
+	var statuses []v1alpha1.MetricStatus
+
+	got, err := h.suggestDesiredReplicas(scaleTarget{metricStatuses: &statuses}, hra)
+	require.NoError(t, err)
+	require.Equal(t, 4, *got)
+
+	require.Equal(t, []v1alpha1.MetricStatus{
+		{Type: "Nil"},
+		{Type: "Queue", Value: "queued=4", SuggestedReplicas: intPtr(4)},
+	}, statuses)
+}
+
+func TestUpdateStatusOnError(t *testing.T) {
+	ctx := context.Background()
+
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example"},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(sc).WithObjects(hra).Build()
+
+	r := &HorizontalRunnerAutoscalerReconciler{
+		Client: c,
+	}
+
+	get := func() v1alpha1.HorizontalRunnerAutoscaler {
+		var got v1alpha1.HorizontalRunnerAutoscaler
+		require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example"}, &got))
+		return got
+	}
+
+	r.updateStatusOnError(ctx, logr.Discard(), get(), v1alpha1.ConditionTypeScalingActive, reasonFailedGetMetrics, errors.New("boom"))
+
+	first := get()
+	require.Equal(t, "boom", first.Status.LastError)
+	require.NotNil(t, first.Status.LastErrorTime)
+
+	cond := meta.FindStatusCondition(first.Status.Conditions, v1alpha1.ConditionTypeScalingActive)
+	require.NotNil(t, cond)
+	require.Equal(t, metav1.ConditionFalse, cond.Status)
+	require.Equal(t, reasonFailedGetMetrics, cond.Reason)
+	require.Equal(t, "boom", cond.Message)
+
+	// The same error doesn't update the status again
+	r.updateStatusOnError(ctx, logr.Discard(), first, v1alpha1.ConditionTypeScalingActive, reasonFailedGetMetrics, errors.New("boom"))
+	require.Equal(t, first.ResourceVersion, get().ResourceVersion)
+
+	r.updateStatusOnError(ctx, logr.Discard(), first, v1alpha1.ConditionTypeAbleToScale, reasonFailedUpdateScale, errors.New("conflict"))
+
+	second := get()
+	require.Equal(t, "conflict", second.Status.LastError)
+	require.Len(t, second.Status.Conditions, 2)
+}
+
+func TestScheduledOverridesSummary(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	end := time.Date(2022, 3, 7, 18, 0, 0, 0, time.UTC)
+
+	active := &Override{
+		ScheduledOverride: v1alpha1.ScheduledOverride{MinReplicas: intPtr(5), MaxReplicas: intPtr(20)},
+		Period:            Period{StartTime: end.Add(-10 * time.Hour), EndTime: end},
+	}
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{MinReplicas: intPtr(1), MaxReplicas: intPtr(10)},
+	}
+
+	require.Equal(t, "min=1 max=10 time="+end.String(), scheduledOverridesSummary(hra, active, nil))
+
+	hra.Spec.MaxReplicas = nil
+	require.Equal(t, "min=1 max=unlimited time="+end.String(), scheduledOverridesSummary(hra, active, nil),
+		"the max after the override must be shown even when maxReplicas is omitted")
+
+	active.ScheduledOverride.MaxReplicas = nil
+	require.Equal(t, "min=1 time="+end.String(), scheduledOverridesSummary(hra, active, nil))
+}
+
+func TestHRAUpdateNeedsReconcile(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	old := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example", Generation: 1},
+	}
+
+	statusOnly := old.DeepCopy()
+	statusOnly.Status.DesiredReplicas = intPtr(3)
+	statusOnly.Status.Conditions = []metav1.Condition{{Type: v1alpha1.ConditionTypeAbleToScale, Status: metav1.ConditionTrue}}
+	require.False(t, hraUpdateNeedsReconcile(old, statusOnly), "the status written by the reconciler itself must not trigger a reconciliation")
+
+	specChanged := old.DeepCopy()
+	specChanged.Generation = 2
+	specChanged.Spec.MaxReplicas = intPtr(10)
+	require.True(t, hraUpdateNeedsReconcile(old, specChanged))
+
+	reserved := old.DeepCopy()
+	reserved.Status.CapacityReservations = []v1alpha1.CapacityReservation{{Name: "job-1", Replicas: 1}}
+	require.True(t, hraUpdateNeedsReconcile(old, reserved), "the capacity reservations added by the webhook must be applied right away")
+
+	deleted := old.DeepCopy()
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+	require.True(t, hraUpdateNeedsReconcile(old, deleted))
+}
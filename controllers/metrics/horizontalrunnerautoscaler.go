@@ -16,6 +16,7 @@ var (
 		horizontalRunnerAutoscalerMinReplicas,
 		horizontalRunnerAutoscalerMaxReplicas,
 		horizontalRunnerAutoscalerDesiredReplicas,
+		horizontalRunnerAutoscalerDesiredOverflowReplicas,
 	}
 )
 
@@ -41,6 +42,13 @@ var (
 		},
 		[]string{hraName, hraNamespace},
 	)
+	horizontalRunnerAutoscalerDesiredOverflowReplicas = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_status_desired_overflow_replicas",
+			Help: "desiredOverflowReplicas of HorizontalRunnerAutoscaler",
+		},
+		[]string{hraName, hraNamespace},
+	)
 )
 
 func SetHorizontalRunnerAutoscalerSpec(o metav1.ObjectMeta, spec v1alpha1.HorizontalRunnerAutoscalerSpec) {
@@ -64,4 +72,7 @@ func SetHorizontalRunnerAutoscalerStatus(o metav1.ObjectMeta, status v1alpha1.Ho
 	if status.DesiredReplicas != nil {
 		horizontalRunnerAutoscalerDesiredReplicas.With(labels).Set(float64(*status.DesiredReplicas))
 	}
+	if status.DesiredOverflowReplicas != nil {
+		horizontalRunnerAutoscalerDesiredOverflowReplicas.With(labels).Set(float64(*status.DesiredOverflowReplicas))
+	}
 }
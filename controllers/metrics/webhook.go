@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	webhookEvent  = "event"
+	webhookAction = "action"
+	webhookResult = "result"
+
+	workflowJobRepository   = "repository"
+	workflowJobRunnerLabels = "runner_labels"
+	workflowJobHRA          = "horizontalrunnerautoscaler"
+	workflowJobHRANamespace = "namespace"
+	workflowJobConclusion   = "conclusion"
+)
+
+const (
+	// WebhookEventResultMatched is the result of an event that scaled a HorizontalRunnerAutoscaler
+	WebhookEventResultMatched = "matched"
+	// WebhookEventResultIgnored is the result of an event that triggers neither scale-up nor scale-down by its type or action
+	WebhookEventResultIgnored = "ignored"
+	// WebhookEventResultUnmatched is the result of an event that no HorizontalRunnerAutoscaler matched
+	WebhookEventResultUnmatched = "unmatched"
+	// WebhookEventResultFailed is the result of an event that failed to be processed
+	WebhookEventResultFailed = "failed"
+)
+
+var (
+	githubWebhookMetrics = []prometheus.Collector{
+		githubWebhookDuplicateDeliveries,
+		githubWebhookEventsReceived,
+		githubWebhookEventsProcessed,
+		workflowJobQueueDurationSeconds,
+		workflowJobRunDurationSeconds,
+	}
+)
+
+var (
+	// The buckets range from 1 second to about 4.5 hours, as a workflow job can wait for a runner
+	// and run for that long
+	workflowJobDurationBuckets = prometheus.ExponentialBuckets(1, 2, 15)
+
+	workflowJobQueueDurationLabels = []string{
+		workflowJobRepository,
+		workflowJobRunnerLabels,
+		workflowJobHRA,
+		workflowJobHRANamespace,
+	}
+	workflowJobRunDurationLabels = []string{
+		workflowJobRepository,
+		workflowJobRunnerLabels,
+		workflowJobHRA,
+		workflowJobHRANamespace,
+		workflowJobConclusion,
+	}
+)
+
+var (
+	githubWebhookDuplicateDeliveries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_webhook_duplicate_deliveries_total",
+			Help: "The number of GitHub webhook deliveries ignored because the same delivery ID has already been seen",
+		},
+		[]string{webhookEvent},
+	)
+	githubWebhookEventsReceived = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_webhook_events_received_total",
+			Help: "The number of GitHub webhook events received",
+		},
+		[]string{webhookEvent, webhookAction},
+	)
+	githubWebhookEventsProcessed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_webhook_events_processed_total",
+			Help: "The number of GitHub webhook events processed, by the result that is either matched, ignored, unmatched, or failed",
+		},
+		[]string{webhookEvent, webhookAction, webhookResult},
+	)
+	workflowJobQueueDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "github_workflow_job_queue_duration_seconds",
+			Help:    "The time from a workflow job is queued until it's picked up by a runner, observed on the in_progress workflow_job event",
+			Buckets: workflowJobDurationBuckets,
+		},
+		workflowJobQueueDurationLabels,
+	)
+	workflowJobRunDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "github_workflow_job_run_duration_seconds",
+			Help:    "The time from a workflow job is picked up by a runner until it completes, observed on the completed workflow_job event",
+			Buckets: workflowJobDurationBuckets,
+		},
+		workflowJobRunDurationLabels,
+	)
+)
+
+func IncGitHubWebhookDuplicateDeliveries(event string) {
+	githubWebhookDuplicateDeliveries.With(prometheus.Labels{webhookEvent: event}).Inc()
+}
+
+func IncGitHubWebhookEventsReceived(event, action string) {
+	githubWebhookEventsReceived.With(prometheus.Labels{webhookEvent: event, webhookAction: action}).Inc()
+}
+
+func IncGitHubWebhookEventsProcessed(event, action, result string) {
+	githubWebhookEventsProcessed.With(prometheus.Labels{webhookEvent: event, webhookAction: action, webhookResult: result}).Inc()
+}
+
+// WorkflowJob is the labels of the workflow job durations
+type WorkflowJob struct {
+	Repository string
+	// RunnerLabels is the comma-separated runner labels the job requested
+	RunnerLabels string
+	// HRAName and HRANamespace are of the HorizontalRunnerAutoscaler that matched the job. Empty when none matched.
+	HRAName      string
+	HRANamespace string
+	// Conclusion is a label of the run duration only, as the queue duration is observed before the job completes
+	Conclusion string
+}
+
+func (job WorkflowJob) queueDurationLabels() prometheus.Labels {
+	return prometheus.Labels{
+		workflowJobRepository:   job.Repository,
+		workflowJobRunnerLabels: job.RunnerLabels,
+		workflowJobHRA:          job.HRAName,
+		workflowJobHRANamespace: job.HRANamespace,
+	}
+}
+
+func ObserveWorkflowJobQueueDuration(job WorkflowJob, d time.Duration) {
+	workflowJobQueueDurationSeconds.With(job.queueDurationLabels()).Observe(d.Seconds())
+}
+
+func ObserveWorkflowJobRunDuration(job WorkflowJob, d time.Duration) {
+	labels := job.queueDurationLabels()
+	labels[workflowJobConclusion] = job.Conclusion
+
+	workflowJobRunDurationSeconds.With(labels).Observe(d.Seconds())
+}
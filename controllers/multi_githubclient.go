@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/actions-runner-controller/actions-runner-controller/github"
+	"github.com/actions-runner-controller/actions-runner-controller/hash"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// The keys of the GitHub API credentials Secret.
+	// They are the same as the ones of the controller-wide Secret created by the Helm chart.
+	secretDataKeyGitHubToken             = "github_token"
+	secretDataKeyGitHubAppID             = "github_app_id"
+	secretDataKeyGitHubAppInstallationID = "github_app_installation_id"
+	secretDataKeyGitHubAppPrivateKey     = "github_app_private_key"
+)
+
+// MultiGitHubClient builds and caches a GitHub API client per credentials Secret referenced by
+// runners, runner sets, and HRAs.
+// The clients share the controller-wide settings other than credentials, like the GitHub Enterprise URL.
+type MultiGitHubClient struct {
+	client client.Client
+
+	// config is the controller-wide config whose credentials are replaced by the ones in Secrets
+	config github.Config
+
+	mu      sync.Mutex
+	clients map[types.NamespacedName]cachedGitHubClient
+}
+
+type cachedGitHubClient struct {
+	// secretHash is used to rebuild the client when the Secret is updated
+	secretHash string
+	client     *github.Client
+}
+
+func NewMultiGitHubClient(client client.Client, config github.Config) *MultiGitHubClient {
+	return &MultiGitHubClient{
+		client:  client,
+		config:  config,
+		clients: map[types.NamespacedName]cachedGitHubClient{},
+	}
+}
+
+// ClientFor returns the client for the credentials referenced from a resource in the namespace.
+// It returns the default client when the reference is nil.
+// It's safe to call this on a nil MultiGitHubClient, in which case only a nil reference is allowed.
+func (c *MultiGitHubClient) ClientFor(ctx context.Context, defaultClient *github.Client, namespace string, ref *v1alpha1.GitHubAPICredentialsFrom) (*github.Client, error) {
+	if ref == nil {
+		return defaultClient, nil
+	}
+
+	return c.clientForSecret(ctx, namespace, ref.SecretRef.Name)
+}
+
+// ClientForPod returns the client for the credentials the runner pod was created with.
+func (c *MultiGitHubClient) ClientForPod(ctx context.Context, defaultClient *github.Client, pod *corev1.Pod) (*github.Client, error) {
+	secretName, ok := pod.Annotations[AnnotationKeyGitHubAPICredsSecret]
+	if !ok {
+		return defaultClient, nil
+	}
+
+	return c.clientForSecret(ctx, pod.Namespace, secretName)
+}
+
+func (c *MultiGitHubClient) clientForSecret(ctx context.Context, namespace, secretName string) (*github.Client, error) {
+	if c == nil {
+		return nil, fmt.Errorf("github api credentials secret %s/%s is referenced but this controller doesn't support per-resource credentials", namespace, secretName)
+	}
+
+	if secretName == "" {
+		return nil, fmt.Errorf("github api credentials secret name must not be empty")
+	}
+
+	nsName := types.NamespacedName{Namespace: namespace, Name: secretName}
+
+	var secret corev1.Secret
+	if err := c.client.Get(ctx, nsName, &secret); err != nil {
+		return nil, fmt.Errorf("getting github api credentials secret %s: %w", nsName, err)
+	}
+
+	secretHash := hash.FNVHashStringObjects(secret.Data)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.clients[nsName]; ok && cached.secretHash == secretHash {
+		return cached.client, nil
+	}
+
+	conf, err := c.configFromSecret(secret)
+	if err != nil {
+		return nil, fmt.Errorf("reading github api credentials secret %s: %w", nsName, err)
+	}
+
+	ghClient, err := conf.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("creating github client from secret %s: %w", nsName, err)
+	}
+
+	c.clients[nsName] = cachedGitHubClient{
+		secretHash: secretHash,
+		client:     ghClient,
+	}
+
+	return ghClient, nil
+}
+
+func (c *MultiGitHubClient) configFromSecret(secret corev1.Secret) (*github.Config, error) {
+	conf := c.config
+
+	conf.Token = ""
+	conf.AppID = 0
+	conf.AppInstallationID = 0
+	conf.AppPrivateKey = ""
+	conf.BasicauthUsername = ""
+	conf.BasicauthPassword = ""
+
+	if appID := string(secret.Data[secretDataKeyGitHubAppID]); appID != "" {
+		id, err := strconv.ParseInt(appID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", secretDataKeyGitHubAppID, err)
+		}
+		conf.AppID = id
+
+		// The installation for each enterprise or organization is discovered when the installation ID is omitted
+		if installationID := string(secret.Data[secretDataKeyGitHubAppInstallationID]); installationID != "" {
+			id, err := strconv.ParseInt(installationID, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: %w", secretDataKeyGitHubAppInstallationID, err)
+			}
+			conf.AppInstallationID = id
+		}
+
+		conf.AppPrivateKey = string(secret.Data[secretDataKeyGitHubAppPrivateKey])
+		if conf.AppPrivateKey == "" {
+			return nil, fmt.Errorf("%s is required along with %s", secretDataKeyGitHubAppPrivateKey, secretDataKeyGitHubAppID)
+		}
+
+		return &conf, nil
+	}
+
+	conf.Token = string(secret.Data[secretDataKeyGitHubToken])
+	if conf.Token == "" {
+		return nil, fmt.Errorf("either %s or %s is required", secretDataKeyGitHubToken, secretDataKeyGitHubAppID)
+	}
+
+	return &conf, nil
+}
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/actions-runner-controller/actions-runner-controller/github"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMultiGitHubClient(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "tenant-a",
+			Name:      "github-creds",
+		},
+		Data: map[string][]byte{
+			"github_token": []byte("token-a"),
+		},
+	}
+
+	k8sClient := fake.NewFakeClientWithScheme(sc, secret)
+
+	defaultClient := &github.Client{}
+
+	c := NewMultiGitHubClient(k8sClient, github.Config{Token: "controller-wide", EnterpriseURL: "https://github.example.com"})
+
+	ctx := context.Background()
+
+	got, err := c.ClientFor(ctx, defaultClient, "tenant-a", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != defaultClient {
+		t.Errorf("expected the default client to be used without a reference")
+	}
+
+	ref := &v1alpha1.GitHubAPICredentialsFrom{SecretRef: v1alpha1.SecretReference{Name: "github-creds"}}
+
+	first, err := c.ClientFor(ctx, defaultClient, "tenant-a", ref)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first == defaultClient {
+		t.Fatalf("expected a client dedicated to the secret")
+	}
+	if !strings.HasPrefix(first.GithubBaseURL, "https://github.example.com") {
+		t.Errorf("expected the client to inherit the controller-wide enterprise url, got %q", first.GithubBaseURL)
+	}
+
+	second, err := c.ClientFor(ctx, defaultClient, "tenant-a", ref)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second != first {
+		t.Errorf("expected the client to be reused while the secret is unchanged")
+	}
+
+	secret.Data["github_token"] = []byte("token-a-rotated")
+	if err := k8sClient.Update(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+
+	third, err := c.ClientFor(ctx, defaultClient, "tenant-a", ref)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if third == first {
+		t.Errorf("expected the client to be rebuilt after the secret is updated")
+	}
+
+	if _, err := c.ClientFor(ctx, defaultClient, "tenant-b", ref); err == nil {
+		t.Errorf("expected an error for the secret in another namespace")
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "tenant-a",
+			Name:      "runner",
+			Annotations: map[string]string{
+				AnnotationKeyGitHubAPICredsSecret: "github-creds",
+			},
+		},
+	}
+
+	fromPod, err := c.ClientForPod(ctx, defaultClient, pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fromPod != third {
+		t.Errorf("expected the runner pod to use the same client as the runner")
+	}
+
+	var nilClients *MultiGitHubClient
+	if _, err := nilClients.ClientFor(ctx, defaultClient, "tenant-a", ref); err == nil {
+		t.Errorf("expected an error when per-resource credentials are not supported")
+	}
+}
+
+func TestMultiGitHubClient_InvalidSecret(t *testing.T) {
+	testcases := []struct {
+		name string
+		data map[string]string
+		want string
+	}{
+		{
+			name: "empty",
+			data: map[string]string{},
+			want: "either github_token or github_app_id is required",
+		},
+		{
+			name: "invalid app id",
+			data: map[string]string{"github_app_id": "abc"},
+			want: "parsing github_app_id",
+		},
+		{
+			name: "invalid installation id",
+			data: map[string]string{"github_app_id": "1", "github_app_installation_id": "abc", "github_app_private_key": "key"},
+			want: "parsing github_app_installation_id",
+		},
+		{
+			name: "missing private key",
+			data: map[string]string{"github_app_id": "1", "github_app_installation_id": "2"},
+			want: "github_app_private_key is required",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "github-creds",
+				},
+				Data: map[string][]byte{},
+			}
+			for k, v := range tc.data {
+				secret.Data[k] = []byte(v)
+			}
+
+			c := NewMultiGitHubClient(fake.NewFakeClientWithScheme(sc, secret), github.Config{})
+
+			ref := &v1alpha1.GitHubAPICredentialsFrom{SecretRef: v1alpha1.SecretReference{Name: "github-creds"}}
+
+			_, err := c.ClientFor(context.Background(), nil, "default", ref)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -545,7 +545,7 @@ func TestNewRunnerPodFromRunnerController(t *testing.T) {
 			Name: "runner",
 			Labels: map[string]string{
 				"actions-runner-controller/inject-registration-token": "true",
-				"pod-template-hash": "8857b86c7",
+				"pod-template-hash": "7f9cb86cdd",
 				"runnerset-name":    "runner",
 			},
 			OwnerReferences: []metav1.OwnerReference{
@@ -641,8 +641,15 @@ func TestNewRunnerPodFromRunnerController(t *testing.T) {
 							Value: "runner",
 						},
 						{
-							Name:  "RUNNER_TOKEN",
-							Value: "",
+							Name: "RUNNER_TOKEN",
+							ValueFrom: &corev1.EnvVarSource{
+								SecretKeyRef: &corev1.SecretKeySelector{
+									LocalObjectReference: corev1.LocalObjectReference{
+										Name: "runner-registration-token",
+									},
+									Key: "token",
+								},
+							},
 						},
 					},
 					VolumeMounts: []corev1.VolumeMount{
@@ -702,7 +709,7 @@ func TestNewRunnerPodFromRunnerController(t *testing.T) {
 			Name: "runner",
 			Labels: map[string]string{
 				"actions-runner-controller/inject-registration-token": "true",
-				"pod-template-hash": "8857b86c7",
+				"pod-template-hash": "7f9cb86cdd",
 				"runnerset-name":    "runner",
 			},
 			OwnerReferences: []metav1.OwnerReference{
@@ -774,8 +781,15 @@ func TestNewRunnerPodFromRunnerController(t *testing.T) {
 							Value: "runner",
 						},
 						{
-							Name:  "RUNNER_TOKEN",
-							Value: "",
+							Name: "RUNNER_TOKEN",
+							ValueFrom: &corev1.EnvVarSource{
+								SecretKeyRef: &corev1.SecretKeySelector{
+									LocalObjectReference: corev1.LocalObjectReference{
+										Name: "runner-registration-token",
+									},
+									Key: "token",
+								},
+							},
 						},
 					},
 					VolumeMounts: []corev1.VolumeMount{
@@ -799,7 +813,7 @@ func TestNewRunnerPodFromRunnerController(t *testing.T) {
 			Name: "runner",
 			Labels: map[string]string{
 				"actions-runner-controller/inject-registration-token": "true",
-				"pod-template-hash": "8857b86c7",
+				"pod-template-hash": "7f9cb86cdd",
 				"runnerset-name":    "runner",
 			},
 			OwnerReferences: []metav1.OwnerReference{
@@ -871,8 +885,15 @@ func TestNewRunnerPodFromRunnerController(t *testing.T) {
 							Value: "runner",
 						},
 						{
-							Name:  "RUNNER_TOKEN",
-							Value: "",
+							Name: "RUNNER_TOKEN",
+							ValueFrom: &corev1.EnvVarSource{
+								SecretKeyRef: &corev1.SecretKeySelector{
+									LocalObjectReference: corev1.LocalObjectReference{
+										Name: "runner-registration-token",
+									},
+									Key: "token",
+								},
+							},
 						},
 					},
 					VolumeMounts: []corev1.VolumeMount{
@@ -11,6 +11,9 @@ import (
 	"gomodules.xyz/jsonpatch/v2"
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -21,7 +24,7 @@ const (
 	AnnotationKeyTokenExpirationDate = "actions-runner-controller/token-expires-at"
 )
 
-// +kubebuilder:webhook:path=/mutate-runner-set-pod,mutating=true,failurePolicy=ignore,groups="",resources=pods,verbs=create,versions=v1,name=mutate-runner-pod.webhook.actions.summerwind.dev,sideEffects=None,admissionReviewVersions=v1beta1
+// +kubebuilder:webhook:path=/mutate-runner-set-pod,mutating=true,failurePolicy=ignore,groups="",resources=pods,verbs=create,versions=v1,name=mutate-runner-pod.webhook.actions.summerwind.dev,sideEffects=NoneOnDryRun,admissionReviewVersions=v1beta1
 
 type PodRunnerTokenInjector struct {
 	client.Client
@@ -30,10 +33,17 @@ type PodRunnerTokenInjector struct {
 	Log          logr.Logger
 	Recorder     record.EventRecorder
 	GitHubClient *github.Client
-	decoder      *admission.Decoder
+	// GitHubClients provides the clients for runner pods with their own GitHub API credentials
+	GitHubClients *MultiGitHubClient
+	decoder       *admission.Decoder
 }
 
 func (t *PodRunnerTokenInjector) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.DryRun != nil && *req.DryRun {
+		// Registering the runner and writing its secret are side effects, which must not happen on dry runs
+		return newEmptyResponse()
+	}
+
 	var pod corev1.Pod
 	err := t.decoder.Decode(req, &pod)
 	if err != nil {
@@ -66,17 +76,47 @@ func (t *PodRunnerTokenInjector) Handle(ctx context.Context, req admission.Reque
 		return newEmptyResponse()
 	}
 
-	rt, err := t.GitHubClient.GetRegistrationToken(context.Background(), enterprise, org, repo, pod.Name)
+	if pod.Namespace == "" {
+		// The namespace of the pod being created can be available only in the request
+		pod.Namespace = req.Namespace
+	}
+
+	ghc, err := t.GitHubClients.ClientForPod(ctx, t.GitHubClient, &pod)
 	if err != nil {
-		t.Log.Error(err, "Failed to get new registration token")
+		t.Log.Error(err, "Failed to get github client")
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	ts := rt.GetExpiresAt().Format(time.RFC3339)
+	switch {
+	case podUsesJITConfig(&pod) && jitConfigVolumeMounted(&pod), podUsesRegistrationTokenSecret(&pod):
+		// The runner controller has already created the secret for the pod of the Runner
+	case podUsesJITConfig(&pod):
+		config, err := generateJITConfig(ctx, ghc, &pod)
+		if err != nil {
+			t.Log.Error(err, "Failed to generate JIT config")
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+
+		if err := t.applyRunnerPodSecret(ctx, &pod, newJITConfigSecret(&pod, config)); err != nil {
+			t.Log.Error(err, "Failed to update JIT config secret")
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+	default:
+		rt, err := ghc.GetRegistrationToken(context.Background(), enterprise, org, repo, pod.Name)
+		if err != nil {
+			t.Log.Error(err, "Failed to get new registration token")
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+
+		if err := t.applyRunnerPodSecret(ctx, &pod, newRegistrationTokenSecret(pod.Namespace, pod.Name, rt.GetToken())); err != nil {
+			t.Log.Error(err, "Failed to update registration token secret")
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
 
-	updated := mutatePod(&pod, *rt.Token)
+		pod.Annotations[AnnotationKeyTokenExpirationDate] = rt.GetExpiresAt().Format(time.RFC3339)
+	}
 
-	updated.Annotations[AnnotationKeyTokenExpirationDate] = ts
+	updated := mutatePod(&pod)
 
 	forceRunnerPodRestartPolicyNever(updated)
 
@@ -90,6 +130,37 @@ func (t *PodRunnerTokenInjector) Handle(ctx context.Context, req admission.Reque
 	return res
 }
 
+// applyRunnerPodSecret creates or updates the secret, like the JIT config or the registration token, for the runner pod being created.
+// The secret is always overwritten, as it can be left over from the previous pod of the same name whose runner has already used it.
+func (t *PodRunnerTokenInjector) applyRunnerPodSecret(ctx context.Context, pod *corev1.Pod, desired *corev1.Secret) error {
+	// The pod has no UID until it's created, so the secret is owned by the owner of the pod, like the StatefulSet of a RunnerSet.
+	// It's deleted along with the pod by the runner pod controller.
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		desired.OwnerReferences = []metav1.OwnerReference{*owner}
+	}
+
+	return createOrUpdateSecret(ctx, t.Client, desired)
+}
+
+// createOrUpdateSecret creates the secret, or overwrites the labels, the owners and the data of the existing one.
+func createOrUpdateSecret(ctx context.Context, c client.Client, desired *corev1.Secret) error {
+	var secret corev1.Secret
+	if err := c.Get(ctx, types.NamespacedName{Namespace: desired.Namespace, Name: desired.Name}, &secret); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+
+		return c.Create(ctx, desired)
+	}
+
+	updated := secret.DeepCopy()
+	updated.Labels = desired.Labels
+	updated.OwnerReferences = desired.OwnerReferences
+	updated.Data = desired.Data
+
+	return c.Update(ctx, updated)
+}
+
 func getEnv(container *corev1.Container, key string) (string, bool) {
 	for _, env := range container.Env {
 		if env.Name == key {
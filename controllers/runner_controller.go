@@ -29,6 +29,7 @@ import (
 
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -53,16 +54,21 @@ const (
 	EnvVarRepo       = "RUNNER_REPO"
 	EnvVarEnterprise = "RUNNER_ENTERPRISE"
 	EnvVarEphemeral  = "RUNNER_EPHEMERAL"
+	EnvVarLabels     = "RUNNER_LABELS"
+	EnvVarGroup      = "RUNNER_GROUP"
+	EnvVarWorkDir    = "RUNNER_WORKDIR"
 	EnvVarTrue       = "true"
 )
 
 // RunnerReconciler reconciles a Runner object
 type RunnerReconciler struct {
 	client.Client
-	Log                         logr.Logger
-	Recorder                    record.EventRecorder
-	Scheme                      *runtime.Scheme
-	GitHubClient                *github.Client
+	Log          logr.Logger
+	Recorder     record.EventRecorder
+	Scheme       *runtime.Scheme
+	GitHubClient *github.Client
+	// GitHubClients provides the clients for runners with their own GitHub API credentials
+	GitHubClients               *MultiGitHubClient
 	RunnerImage                 string
 	RunnerImagePullSecrets      []string
 	DockerImage                 string
@@ -72,13 +78,23 @@ type RunnerReconciler struct {
 	RegistrationRecheckJitter   time.Duration
 
 	UnregistrationRetryDelay time.Duration
+
+	// GitHubStatusSyncInterval is the interval of syncing the runner status with the state of the runner seen in the GitHub API.
+	// Zero disables the sync.
+	GitHubStatusSyncInterval time.Duration
+
+	// APIReader reads the registration token secret bypassing the cache, so that the secret deleted along with the previous pod
+	// isn't seen as existing right before creating the next pod. If nil, the cached client is used.
+	APIReader client.Reader
+
+	gitHubStatusSyncs gitHubStatusSyncs
 }
 
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners/finalizers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;delete
 // +kubebuilder:rbac:groups=core,resources=pods/finalizers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
@@ -105,6 +121,8 @@ func (r *RunnerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			return ctrl.Result{}, nil
 		}
 	} else {
+		r.gitHubStatusSyncs.forget(req.NamespacedName)
+
 		// Request to remove a runner. DeletionTimestamp was set in the runner - we need to unregister runner
 		var pod corev1.Pod
 		if err := r.Get(ctx, req.NamespacedName, &pod); err != nil {
@@ -154,9 +172,16 @@ func (r *RunnerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			log.Error(err, "Failed to update runner status for Phase/Reason/Message")
 			return ctrl.Result{}, err
 		}
+
+		runner = *updated
 	}
 
-	return ctrl.Result{}, nil
+	requeueAfter, err := r.syncGitHubStatus(ctx, log, runner, &pod)
+	if err != nil {
+		log.Error(err, "Failed to sync runner status with GitHub")
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func runnerPodReady(pod *corev1.Pod) bool {
@@ -244,10 +269,15 @@ func (r *RunnerReconciler) processRunnerDeletion(runner v1alpha1.Runner, ctx con
 }
 
 func (r *RunnerReconciler) processRunnerCreation(ctx context.Context, runner v1alpha1.Runner, log logr.Logger) (reconcile.Result, error) {
-	if updated, err := r.updateRegistrationToken(ctx, runner); err != nil {
-		return ctrl.Result{RequeueAfter: RetryDelayOnCreateRegistrationError}, nil
-	} else if updated {
-		return ctrl.Result{Requeue: true}, nil
+	jitConfig := jitConfigEnabled(runner.Spec.RunnerConfig)
+
+	// A runner with the JIT config is registered by the controller below, and needs no registration token
+	if !jitConfig {
+		if updated, err := r.updateRegistrationToken(ctx, runner); err != nil {
+			return ctrl.Result{RequeueAfter: RetryDelayOnCreateRegistrationError}, nil
+		} else if updated {
+			return ctrl.Result{Requeue: true}, nil
+		}
 	}
 
 	newPod, err := r.newPod(runner)
@@ -256,6 +286,12 @@ func (r *RunnerReconciler) processRunnerCreation(ctx context.Context, runner v1a
 		return ctrl.Result{}, err
 	}
 
+	if jitConfig {
+		if err := r.ensureJITConfigSecret(ctx, runner, &newPod, log); err != nil {
+			return ctrl.Result{RequeueAfter: RetryDelayOnCreateRegistrationError}, nil
+		}
+	}
+
 	if err := r.Create(ctx, &newPod); err != nil {
 		if kerrors.IsAlreadyExists(err) {
 			// Gracefully handle pod-already-exists errors due to informer cache delay.
@@ -278,14 +314,38 @@ func (r *RunnerReconciler) processRunnerCreation(ctx context.Context, runner v1a
 	return ctrl.Result{}, nil
 }
 
+// updateRegistrationToken rotates the registration token stored in the Secret of the runner pod, when the token is expired or the secret is missing.
+// The secret is deleted along with the pod, so that a new token is issued whenever the pod is recreated.
 func (r *RunnerReconciler) updateRegistrationToken(ctx context.Context, runner v1alpha1.Runner) (bool, error) {
-	if runner.IsRegisterable() {
+	log := r.Log.WithValues("runner", runner.Name)
+
+	var secret corev1.Secret
+	var secretFound bool
+
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: runner.Namespace, Name: registrationTokenSecretName(runner.Name)}, &secret); err == nil {
+		secretFound = secret.DeletionTimestamp.IsZero()
+	} else if !kerrors.IsNotFound(err) {
+		log.Error(err, "Failed to get registration token secret")
+		return false, err
+	}
+
+	if runner.IsRegisterable() && secretFound {
 		return false, nil
 	}
 
-	log := r.Log.WithValues("runner", runner.Name)
+	ghc, err := r.GitHubClients.ClientFor(ctx, r.GitHubClient, runner.Namespace, runner.Spec.GitHubAPICredentialsFrom)
+	if err != nil {
+		r.Recorder.Event(&runner, corev1.EventTypeWarning, "FailedUpdateRegistrationToken", fmt.Sprintf("Getting GitHub API client failed: %v", err))
+		log.Error(err, "Failed to get github client")
+		return false, err
+	}
 
-	rt, err := r.GitHubClient.GetRegistrationToken(ctx, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, runner.Name)
+	rt, err := ghc.GetRegistrationToken(ctx, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, runner.Name)
 	if err != nil {
 		// An error can be a permanent, permission issue like the below:
 		//    POST https://api.github.com/enterprises/YOUR_ENTERPRISE/actions/runners/registration-token: 403 Resource not accessible by integration []
@@ -296,13 +356,34 @@ func (r *RunnerReconciler) updateRegistrationToken(ctx context.Context, runner v
 		return false, err
 	}
 
+	desired := newRegistrationTokenSecret(runner.Namespace, runner.Name, rt.GetToken())
+
+	if secretFound {
+		updatedSecret := secret.DeepCopy()
+		updatedSecret.Data = desired.Data
+
+		if err := r.Update(ctx, updatedSecret); err != nil {
+			log.Error(err, "Failed to update registration token secret")
+			return false, err
+		}
+	} else {
+		if err := ctrl.SetControllerReference(&runner, desired, r.Scheme); err != nil {
+			return false, err
+		}
+
+		if err := r.Create(ctx, desired); err != nil {
+			log.Error(err, "Failed to create registration token secret")
+			return false, err
+		}
+	}
+
 	updated := runner.DeepCopy()
 	updated.Status.Registration = v1alpha1.RunnerStatusRegistration{
-		Organization: runner.Spec.Organization,
-		Repository:   runner.Spec.Repository,
-		Labels:       runner.Spec.Labels,
-		Token:        rt.GetToken(),
-		ExpiresAt:    metav1.NewTime(rt.GetExpiresAt().Time),
+		Organization:    runner.Spec.Organization,
+		Repository:      runner.Spec.Repository,
+		Labels:          runner.Spec.Labels,
+		TokenSecretName: desired.Name,
+		ExpiresAt:       metav1.NewTime(rt.GetExpiresAt().Time),
 	}
 
 	if err := r.Status().Patch(ctx, updated, client.MergeFrom(&runner)); err != nil {
@@ -316,6 +397,41 @@ func (r *RunnerReconciler) updateRegistrationToken(ctx context.Context, runner v
 	return true, nil
 }
 
+// ensureJITConfigSecret registers the just-in-time runner for the pod being created, and writes its JIT config to the Secret.
+// The config is regenerated for every pod, as the secret left over from the previous pod of the same name
+// may hold the single-use config that the previous pod has already used.
+func (r *RunnerReconciler) ensureJITConfigSecret(ctx context.Context, runner v1alpha1.Runner, pod *corev1.Pod, log logr.Logger) error {
+	ghc, err := r.GitHubClients.ClientFor(ctx, r.GitHubClient, runner.Namespace, runner.Spec.GitHubAPICredentialsFrom)
+	if err != nil {
+		r.Recorder.Event(&runner, corev1.EventTypeWarning, "FailedGenerateJITConfig", fmt.Sprintf("Getting GitHub API client failed: %v", err))
+		log.Error(err, "Failed to get github client")
+		return err
+	}
+
+	config, err := generateJITConfig(ctx, ghc, pod)
+	if err != nil {
+		r.Recorder.Event(&runner, corev1.EventTypeWarning, "FailedGenerateJITConfig", fmt.Sprintf("Generating JIT config failed: %v", err))
+		log.Error(err, "Failed to generate JIT config")
+		return err
+	}
+
+	newSecret := newJITConfigSecret(pod, config)
+
+	if err := ctrl.SetControllerReference(&runner, newSecret, r.Scheme); err != nil {
+		return err
+	}
+
+	if err := createOrUpdateSecret(ctx, r.Client, newSecret); err != nil {
+		log.Error(err, "Failed to write JIT config secret")
+		return err
+	}
+
+	r.Recorder.Event(&runner, corev1.EventTypeNormal, "JITConfigGenerated", fmt.Sprintf("Registered just-in-time runner with id %d", config.Runner.GetID()))
+	log.Info("Registered just-in-time runner", "id", config.Runner.GetID(), "secret", newSecret.Name)
+
+	return nil
+}
+
 func (r *RunnerReconciler) newPod(runner v1alpha1.Runner) (corev1.Pod, error) {
 	var template corev1.Pod
 
@@ -334,7 +450,7 @@ func (r *RunnerReconciler) newPod(runner v1alpha1.Runner) (corev1.Pod, error) {
 	// - GithubBaseURL setting of the controller (can be configured via GITHUB_ENTERPRISE_URL)
 	//
 	// (2) We don't recreate the runner pod when there are changes in:
-	// - runner.status.registration.token
+	// - the registration token in the secret referenced by runner.status.registration.tokenSecretName
 	//   - This token expires and changes hourly, but you don't need to recreate the pod due to that.
 	//     It's the opposite.
 	//     An unexpired token is required only when the runner agent is registering itself on launch.
@@ -351,9 +467,9 @@ func (r *RunnerReconciler) newPod(runner v1alpha1.Runner) (corev1.Pod, error) {
 		r.GitHubClient.GithubBaseURL,
 		// Token change should trigger replacement.
 		// We need to include this explicitly here because
-		// runner.Spec does not contain the possibly updated token stored in the
-		// runner status yet.
-		runner.Status.Registration.Token,
+		// runner.Spec does not contain the expiration of the possibly updated token
+		// stored in the runner status yet.
+		runner.Status.Registration.ExpiresAt,
 	)
 
 	objectMeta := metav1.ObjectMeta{
@@ -527,8 +643,8 @@ func (r *RunnerReconciler) newPod(runner v1alpha1.Runner) (corev1.Pod, error) {
 
 	pod.ObjectMeta.Name = runner.ObjectMeta.Name
 
-	// Inject the registration token and the runner name
-	updated := mutatePod(&pod, runner.Status.Registration.Token)
+	// Inject the reference to the registration token secret and the runner name
+	updated := mutatePod(&pod)
 
 	if err := ctrl.SetControllerReference(&runner, updated, r.Scheme); err != nil {
 		return pod, err
@@ -537,17 +653,20 @@ func (r *RunnerReconciler) newPod(runner v1alpha1.Runner) (corev1.Pod, error) {
 	return *updated, nil
 }
 
-func mutatePod(pod *corev1.Pod, token string) *corev1.Pod {
+func mutatePod(pod *corev1.Pod) *corev1.Pod {
 	updated := pod.DeepCopy()
 
 	if getRunnerEnv(pod, EnvVarRunnerName) == "" {
 		setRunnerEnv(updated, EnvVarRunnerName, pod.ObjectMeta.Name)
 	}
 
-	if getRunnerEnv(pod, EnvVarRunnerToken) == "" {
-		setRunnerEnv(updated, EnvVarRunnerToken, token)
+	if podUsesJITConfig(pod) {
+		// The runner starts with the JIT config mounted from the secret, rather than registering itself with the token
+		return mountJITConfigSecret(updated)
 	}
 
+	setRegistrationTokenSecretRef(updated)
+
 	return updated
 }
 
@@ -610,6 +729,14 @@ func newRunnerPodWithContainerMode(containerMode string, runnerName string, temp
 	template.ObjectMeta.Labels = CloneAndAddLabel(template.ObjectMeta.Labels, LabelKeyRunnerSetName, runnerName)
 	template.ObjectMeta.Labels = CloneAndAddLabel(template.ObjectMeta.Labels, LabelKeyPodMutation, LabelValuePodMutation)
 
+	if runnerSpec.GitHubAPICredentialsFrom != nil {
+		// Lets the runner pod controller and the token injector use the same credentials as the runner's owner
+		if template.ObjectMeta.Annotations == nil {
+			template.ObjectMeta.Annotations = map[string]string{}
+		}
+		template.ObjectMeta.Annotations[AnnotationKeyGitHubAPICredsSecret] = runnerSpec.GitHubAPICredentialsFrom.SecretRef.Name
+	}
+
 	workDir := runnerSpec.WorkDir
 	if workDir == "" {
 		workDir = "/runner/_work"
@@ -638,11 +765,11 @@ func newRunnerPodWithContainerMode(containerMode string, runnerName string, temp
 			Value: runnerSpec.Enterprise,
 		},
 		{
-			Name:  "RUNNER_LABELS",
+			Name:  EnvVarLabels,
 			Value: strings.Join(runnerSpec.Labels, ","),
 		},
 		{
-			Name:  "RUNNER_GROUP",
+			Name:  EnvVarGroup,
 			Value: runnerSpec.Group,
 		},
 		{
@@ -658,7 +785,7 @@ func newRunnerPodWithContainerMode(containerMode string, runnerName string, temp
 			Value: githubBaseURL,
 		},
 		{
-			Name:  "RUNNER_WORKDIR",
+			Name:  EnvVarWorkDir,
 			Value: workDir,
 		},
 		{
@@ -667,6 +794,13 @@ func newRunnerPodWithContainerMode(containerMode string, runnerName string, temp
 		},
 	}
 
+	if jitConfigEnabled(runnerSpec) {
+		env = append(env, corev1.EnvVar{
+			Name:  EnvVarRunnerJITConfigFile,
+			Value: jitConfigFilePath,
+		})
+	}
+
 	var seLinuxOptions *corev1.SELinuxOptions
 	if template.Spec.SecurityContext != nil {
 		seLinuxOptions = template.Spec.SecurityContext.SELinuxOptions
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/actions-runner-controller/actions-runner-controller/github/ratelimit"
+)
+
+const (
+	// DefaultRunnerGitHubStatusSyncInterval is the default interval of syncing the runner status with the GitHub API.
+	// It's the same as the max-age GitHub sets to the list runners API response, so that the runners in the same
+	// enterprise, organization, or repository share one cached response within an interval.
+	DefaultRunnerGitHubStatusSyncInterval = time.Minute
+)
+
+// gitHubStatusSyncs remembers when each runner was last synced with the GitHub API.
+// It's kept in memory rather than in the runner status, so that the status is patched only when the state changes.
+type gitHubStatusSyncs struct {
+	mu       sync.Mutex
+	syncedAt map[types.NamespacedName]time.Time
+}
+
+func (s *gitHubStatusSyncs) get(nsName types.NamespacedName) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.syncedAt[nsName]
+
+	return t, ok
+}
+
+func (s *gitHubStatusSyncs) set(nsName types.NamespacedName, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.syncedAt == nil {
+		s.syncedAt = map[types.NamespacedName]time.Time{}
+	}
+
+	s.syncedAt[nsName] = t
+}
+
+func (s *gitHubStatusSyncs) forget(nsName types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.syncedAt, nsName)
+}
+
+// syncGitHubStatus records the state of the runner seen in the GitHub API to the runner status, at most once per sync interval.
+// The status is patched only when the ID, the status, the busy flag, or the current job changes.
+// The runners are listed with the low priority, so that the sync is skipped when the API rate limit budget runs low.
+// It returns the delay until the next sync.
+func (r *RunnerReconciler) syncGitHubStatus(ctx context.Context, log logr.Logger, runner v1alpha1.Runner, pod *corev1.Pod) (time.Duration, error) {
+	nsName := types.NamespacedName{Namespace: runner.Namespace, Name: runner.Name}
+
+	interval := r.GitHubStatusSyncInterval
+	if interval <= 0 || pod.Status.Phase != corev1.PodRunning || !pod.DeletionTimestamp.IsZero() {
+		r.gitHubStatusSyncs.forget(nsName)
+
+		return 0, nil
+	}
+
+	now := time.Now()
+
+	if last, ok := r.gitHubStatusSyncs.get(nsName); ok {
+		if next := last.Add(interval); next.After(now) {
+			return next.Sub(now), nil
+		}
+	}
+
+	ghc, err := r.GitHubClients.ClientFor(ctx, r.GitHubClient, runner.Namespace, runner.Spec.GitHubAPICredentialsFrom)
+	if err != nil {
+		return interval, err
+	}
+
+	runners, err := ghc.ListRunners(ratelimit.WithPriority(ctx, ratelimit.PriorityLow), runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository)
+	if err != nil {
+		var throttled *ratelimit.ThrottledError
+		if errors.As(err, &throttled) {
+			log.V(1).Info("Skipped syncing runner status with GitHub to save the API rate limit budget", "error", err.Error())
+
+			return interval, nil
+		}
+
+		return interval, err
+	}
+
+	r.gitHubStatusSyncs.set(nsName, now)
+
+	// The ID is more reliable than the name, as a runner with the same name can be registered again
+	var id int64
+	if v := podRunnerID(pod); v != "" {
+		id, _ = strconv.ParseInt(v, 10, 64)
+	}
+
+	status := v1alpha1.RunnerGitHubStatus{
+		Status: v1alpha1.RunnerGitHubStatusUnregistered,
+	}
+
+	for _, ghr := range runners {
+		if (id != 0 && ghr.GetID() == id) || (id == 0 && ghr.GetName() == runner.Name) {
+			status.ID = ghr.GetID()
+			status.Status = ghr.GetStatus()
+			status.Busy = ghr.GetBusy()
+			break
+		}
+	}
+
+	updated := runner.DeepCopy()
+
+	prev := runner.Status.GitHub
+	changed := prev == nil || prev.ID != status.ID || prev.Status != status.Status || prev.Busy != status.Busy
+
+	if changed {
+		status.LastTransitionTime = metav1.NewTime(now)
+		updated.Status.GitHub = &status
+	}
+
+	// The job that was notified by the webhook has finished, but the completed event hasn't been received
+	if !status.Busy && runner.Status.CurrentJob != nil && runner.Status.CurrentJob.StartedAt != nil && runner.Status.CurrentJob.StartedAt.Add(interval).Before(now) {
+		updated.Status.CurrentJob = nil
+		changed = true
+	}
+
+	if !changed {
+		return interval, nil
+	}
+
+	if err := r.Status().Patch(ctx, updated, client.MergeFrom(&runner)); err != nil {
+		return interval, err
+	}
+
+	log.V(1).Info("Synced runner status with GitHub", "id", status.ID, "status", status.Status, "busy", status.Busy)
+
+	return interval, nil
+}
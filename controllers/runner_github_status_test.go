@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	githubfake "github.com/actions-runner-controller/actions-runner-controller/github/fake"
+)
+
+func TestSyncGitHubStatus(t *testing.T) {
+	ctx := context.Background()
+
+	server := githubfake.NewServer(
+		githubfake.WithListRepositoryWorkflowRunsResponse(200, "{}", "{}", "{}"),
+		githubfake.WithListWorkflowJobsResponse(200, nil),
+		githubfake.WithListRunnersResponse(200, githubfake.RunnersListBody),
+	)
+	defer server.Close()
+
+	newRunner := func(name string) *v1alpha1.Runner {
+		return &v1alpha1.Runner{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: v1alpha1.RunnerSpec{
+				RunnerConfig: v1alpha1.RunnerConfig{Organization: "test"},
+			},
+		}
+	}
+
+	newPod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(sc).WithObjects(newRunner("test1"), newRunner("test2"), newRunner("test3")).Build()
+
+	r := &RunnerReconciler{
+		Client:                   c,
+		Scheme:                   sc,
+		GitHubClient:             newGithubClient(server),
+		GitHubStatusSyncInterval: time.Minute,
+	}
+
+	getRunner := func(name string) v1alpha1.Runner {
+		var got v1alpha1.Runner
+		require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, &got))
+		return got
+	}
+
+	requeueAfter, err := r.syncGitHubStatus(ctx, logr.Discard(), getRunner("test1"), newPod("test1"))
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, requeueAfter)
+
+	status := getRunner("test1").Status.GitHub
+	require.NotNil(t, status)
+	require.Equal(t, int64(1), status.ID)
+	require.Equal(t, v1alpha1.RunnerGitHubStatusOnline, status.Status)
+	require.False(t, status.Busy)
+
+	// The runner is matched by the runner ID annotation rather than the name when it's available
+	pod := newPod("test3")
+	pod.Annotations = map[string]string{AnnotationKeyRunnerID: "2"}
+
+	_, err = r.syncGitHubStatus(ctx, logr.Discard(), getRunner("test3"), pod)
+	require.NoError(t, err)
+	require.Equal(t, v1alpha1.RunnerGitHubStatusOffline, getRunner("test3").Status.GitHub.Status)
+	require.Equal(t, int64(2), getRunner("test3").Status.GitHub.ID)
+
+	// A runner not registered yet, or already removed
+	pod.Annotations = nil
+
+	r.gitHubStatusSyncs.forget(types.NamespacedName{Namespace: "default", Name: "test3"})
+
+	_, err = r.syncGitHubStatus(ctx, logr.Discard(), getRunner("test3"), pod)
+	require.NoError(t, err)
+	require.Equal(t, v1alpha1.RunnerGitHubStatusUnregistered, getRunner("test3").Status.GitHub.Status)
+	require.Zero(t, getRunner("test3").Status.GitHub.ID)
+
+	// The runner synced recently isn't synced again until the interval elapses
+	synced := getRunner("test2")
+	synced.Status.GitHub = &v1alpha1.RunnerGitHubStatus{Status: v1alpha1.RunnerGitHubStatusOnline}
+	require.NoError(t, c.Status().Update(ctx, &synced))
+
+	r.gitHubStatusSyncs.set(types.NamespacedName{Namespace: "default", Name: "test2"}, time.Now().Add(-30*time.Second))
+
+	requeueAfter, err = r.syncGitHubStatus(ctx, logr.Discard(), getRunner("test2"), newPod("test2"))
+	require.NoError(t, err)
+	require.True(t, requeueAfter > 0 && requeueAfter <= 30*time.Second, "unexpected requeueAfter: %s", requeueAfter)
+	require.Equal(t, v1alpha1.RunnerGitHubStatusOnline, getRunner("test2").Status.GitHub.Status)
+
+	// The current job left over from a missed completed event is cleared once the runner is seen idle
+	r.gitHubStatusSyncs.set(types.NamespacedName{Namespace: "default", Name: "test2"}, time.Now().Add(-2*time.Minute))
+
+	stale := getRunner("test2")
+	startedAt := metav1.NewTime(time.Now().Add(-10 * time.Minute))
+	stale.Status.CurrentJob = &v1alpha1.RunnerCurrentJob{Repository: "test/valid", JobID: 1, StartedAt: &startedAt}
+	require.NoError(t, c.Status().Update(ctx, &stale))
+
+	_, err = r.syncGitHubStatus(ctx, logr.Discard(), getRunner("test2"), newPod("test2"))
+	require.NoError(t, err)
+	require.Equal(t, v1alpha1.RunnerGitHubStatusOffline, getRunner("test2").Status.GitHub.Status)
+	require.Nil(t, getRunner("test2").Status.CurrentJob)
+
+	// The unchanged state isn't patched again
+	r.gitHubStatusSyncs.forget(types.NamespacedName{Namespace: "default", Name: "test2"})
+
+	before := getRunner("test2")
+
+	_, err = r.syncGitHubStatus(ctx, logr.Discard(), before, newPod("test2"))
+	require.NoError(t, err)
+	require.Equal(t, before.ResourceVersion, getRunner("test2").ResourceVersion)
+
+	// The sync is disabled
+	r.GitHubStatusSyncInterval = 0
+
+	requeueAfter, err = r.syncGitHubStatus(ctx, logr.Discard(), *newRunner("test1"), newPod("test1"))
+	require.NoError(t, err)
+	require.Zero(t, requeueAfter)
+}
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	gogithub "github.com/google/go-github/v45/github"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	"github.com/actions-runner-controller/actions-runner-controller/github"
+)
+
+const (
+	// EnvVarRunnerJITConfigFile is the path to the file of the encoded JIT config the runner starts with.
+	// Its presence in the runner container also tells the controller and the token injector to register
+	// the runner with a JIT config rather than a registration token.
+	EnvVarRunnerJITConfigFile = "RUNNER_JITCONFIG_FILE"
+
+	jitConfigVolumeName = "jitconfig"
+	jitConfigMountPath  = "/etc/actions-runner-controller/jitconfig"
+	jitConfigSecretKey  = "jitconfig"
+	jitConfigFilePath   = jitConfigMountPath + "/" + jitConfigSecretKey
+)
+
+func jitConfigEnabled(runnerSpec v1alpha1.RunnerConfig) bool {
+	return runnerSpec.JITConfig != nil && *runnerSpec.JITConfig
+}
+
+func jitConfigSecretName(podName string) string {
+	return podName + "-jitconfig"
+}
+
+func podUsesJITConfig(pod *corev1.Pod) bool {
+	return getRunnerEnv(pod, EnvVarRunnerJITConfigFile) != ""
+}
+
+func jitConfigVolumeMounted(pod *corev1.Pod) bool {
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == jitConfigVolumeName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// generateJITConfig registers the just-in-time runner for the pod with the group, labels and work directory set in the pod.
+// When a runner with the same name is left registered, like when the pod of a StatefulSet is recreated before its runner
+// starts, the runner is removed and registered again unless it's busy.
+func generateJITConfig(ctx context.Context, ghc *github.Client, pod *corev1.Pod) (*github.JITRunnerConfig, error) {
+	enterprise := getRunnerEnv(pod, EnvVarEnterprise)
+	org := getRunnerEnv(pod, EnvVarOrg)
+	repo := getRunnerEnv(pod, EnvVarRepo)
+
+	groupID, err := ghc.GetRunnerGroupID(ctx, org, getRunnerEnv(pod, EnvVarGroup))
+	if err != nil {
+		return nil, err
+	}
+
+	labels := []string{"self-hosted"}
+
+	for _, l := range strings.Split(getRunnerEnv(pod, EnvVarLabels), ",") {
+		if l != "" && l != "self-hosted" {
+			labels = append(labels, l)
+		}
+	}
+
+	req := &github.JITRunnerConfigRequest{
+		Name:          pod.Name,
+		RunnerGroupID: groupID,
+		Labels:        labels,
+		WorkFolder:    getRunnerEnv(pod, EnvVarWorkDir),
+	}
+
+	config, err := ghc.GenerateJITConfig(ctx, enterprise, org, repo, req)
+
+	var errRes *gogithub.ErrorResponse
+	if !errors.As(err, &errRes) || errRes.Response == nil || errRes.Response.StatusCode != http.StatusConflict {
+		return config, err
+	}
+
+	runners, listErr := ghc.ListRunners(ctx, enterprise, org, repo)
+	if listErr != nil {
+		return nil, fmt.Errorf("listing runners to remove the conflicting runner: %w", listErr)
+	}
+
+	for _, r := range runners {
+		if r.GetName() != pod.Name {
+			continue
+		}
+
+		if r.GetBusy() {
+			return nil, fmt.Errorf("runner %s is already registered and busy: %w", pod.Name, err)
+		}
+
+		if err := ghc.RemoveRunner(ctx, enterprise, org, repo, r.GetID()); err != nil {
+			return nil, fmt.Errorf("removing the conflicting runner %s: %w", pod.Name, err)
+		}
+	}
+
+	return ghc.GenerateJITConfig(ctx, enterprise, org, repo, req)
+}
+
+// newJITConfigSecret returns the Secret holding the encoded JIT config of the runner pod.
+// The secret is labeled with the pod name, so that it's deleted along with the pod.
+func newJITConfigSecret(pod *corev1.Pod, config *github.JITRunnerConfig) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: pod.Namespace,
+			Name:      jitConfigSecretName(pod.Name),
+			Labels: map[string]string{
+				"runner-pod": pod.Name,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			jitConfigSecretKey: []byte(config.EncodedJITConfig),
+		},
+	}
+}
+
+// mountJITConfigSecret mounts the JIT config Secret of the pod into the runner container, and removes the registration token
+// so that the runner container never holds a reusable credential.
+func mountJITConfigSecret(pod *corev1.Pod) *corev1.Pod {
+	updated := pod.DeepCopy()
+
+	if jitConfigVolumeMounted(updated) {
+		return updated
+	}
+
+	updated.Spec.Volumes = append(updated.Spec.Volumes, corev1.Volume{
+		Name: jitConfigVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: jitConfigSecretName(pod.Name),
+			},
+		},
+	})
+
+	for i := range updated.Spec.Containers {
+		c := &updated.Spec.Containers[i]
+		if c.Name != containerName {
+			continue
+		}
+
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      jitConfigVolumeName,
+			MountPath: jitConfigMountPath,
+			ReadOnly:  true,
+		})
+
+		var env []corev1.EnvVar
+
+		for _, e := range c.Env {
+			if e.Name != EnvVarRunnerToken {
+				env = append(env, e)
+			}
+		}
+
+		c.Env = env
+	}
+
+	return updated
+}
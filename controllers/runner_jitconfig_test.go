@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	githubfake "github.com/actions-runner-controller/actions-runner-controller/github/fake"
+)
+
+func newJITConfigTestPod(t *testing.T, group string) *corev1.Pod {
+	t.Helper()
+
+	jitConfig := true
+
+	template := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "runner-0"},
+	}
+
+	pod, err := newRunnerPod("runner", template, v1alpha1.RunnerConfig{
+		Organization: "test",
+		Group:        group,
+		Labels:       []string{"linux", "gpu"},
+		JITConfig:    &jitConfig,
+	}, "runner:latest", nil, "docker:dind", "", "")
+	require.NoError(t, err)
+
+	pod.Name = template.Name
+
+	return &pod
+}
+
+func TestMutatePod_JITConfig(t *testing.T) {
+	pod := newJITConfigTestPod(t, "")
+
+	require.Equal(t, jitConfigFilePath, getRunnerEnv(pod, EnvVarRunnerJITConfigFile))
+
+	updated := mutatePod(pod)
+
+	require.Equal(t, "runner-0", getRunnerEnv(updated, EnvVarRunnerName))
+
+	for _, c := range updated.Spec.Containers {
+		for _, e := range c.Env {
+			require.NotEqual(t, EnvVarRunnerToken, e.Name, "container %s", c.Name)
+		}
+	}
+
+	var volume *corev1.Volume
+	for i := range updated.Spec.Volumes {
+		if v := updated.Spec.Volumes[i]; v.Name == jitConfigVolumeName {
+			volume = &v
+		}
+	}
+	require.NotNil(t, volume)
+	require.Equal(t, "runner-0-jitconfig", volume.Secret.SecretName)
+
+	require.Equal(t, containerName, updated.Spec.Containers[0].Name)
+	require.Contains(t, updated.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      jitConfigVolumeName,
+		MountPath: jitConfigMountPath,
+		ReadOnly:  true,
+	})
+
+	// Mutating the pod again, like when the injector sees the pod created by the runner controller, doesn't duplicate the volume
+	require.Equal(t, len(updated.Spec.Volumes), len(mutatePod(updated).Spec.Volumes))
+}
+
+func TestGenerateJITConfig(t *testing.T) {
+	server := githubfake.NewServer(
+		githubfake.WithListRepositoryWorkflowRunsResponse(200, "{}", "{}", "{}"),
+		githubfake.WithListWorkflowJobsResponse(200, nil),
+		githubfake.WithListRunnersResponse(200, githubfake.RunnersListBody),
+	)
+	defer server.Close()
+
+	ghc := newGithubClient(server)
+
+	config, err := generateJITConfig(context.Background(), ghc, newJITConfigTestPod(t, "gpu"))
+	require.NoError(t, err)
+	require.Equal(t, githubfake.JITConfig, config.EncodedJITConfig)
+
+	_, err = generateJITConfig(context.Background(), ghc, newJITConfigTestPod(t, "missing"))
+	require.Error(t, err)
+}
+
+func TestPodRunnerTokenInjector_ApplyRunnerPodSecret(t *testing.T) {
+	ctx := context.Background()
+
+	c := fake.NewClientBuilder().WithScheme(sc).Build()
+
+	injector := &PodRunnerTokenInjector{Client: c}
+
+	server := githubfake.NewServer(
+		githubfake.WithListRepositoryWorkflowRunsResponse(200, "{}", "{}", "{}"),
+		githubfake.WithListWorkflowJobsResponse(200, nil),
+		githubfake.WithListRunnersResponse(200, githubfake.RunnersListBody),
+	)
+	defer server.Close()
+
+	ghc := newGithubClient(server)
+
+	pod := newJITConfigTestPod(t, "")
+
+	controller := true
+	pod.OwnerReferences = []metav1.OwnerReference{
+		{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "runner", UID: "uid", Controller: &controller},
+	}
+
+	get := func() corev1.Secret {
+		var secret corev1.Secret
+		require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "runner-0-jitconfig"}, &secret))
+		return secret
+	}
+
+	config, err := generateJITConfig(ctx, ghc, pod)
+	require.NoError(t, err)
+	require.NoError(t, injector.applyRunnerPodSecret(ctx, pod, newJITConfigSecret(pod, config)))
+
+	created := get()
+	require.Equal(t, githubfake.JITConfig, string(created.Data[jitConfigSecretKey]))
+	require.Equal(t, "runner-0", created.Labels["runner-pod"])
+	require.Equal(t, pod.OwnerReferences, created.OwnerReferences)
+
+	// The secret left over from the previous pod of the same name gets the newly generated config
+	stale := created.DeepCopy()
+	stale.Data[jitConfigSecretKey] = []byte("used")
+	require.NoError(t, c.Update(ctx, stale))
+
+	require.NoError(t, injector.applyRunnerPodSecret(ctx, pod, newJITConfigSecret(pod, config)))
+	require.Equal(t, githubfake.JITConfig, string(get().Data[jitConfigSecretKey]))
+}
+
+func TestEnsureJITConfigSecret_Regenerates(t *testing.T) {
+	ctx := context.Background()
+
+	server := githubfake.NewServer(
+		githubfake.WithListRepositoryWorkflowRunsResponse(200, "{}", "{}", "{}"),
+		githubfake.WithListWorkflowJobsResponse(200, nil),
+		githubfake.WithListRunnersResponse(200, githubfake.RunnersListBody),
+	)
+	defer server.Close()
+
+	pod := newJITConfigTestPod(t, "")
+
+	runner := v1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "runner-0", UID: "uid"},
+	}
+
+	// The secret left over from the previous pod of the same name holds the config already used
+	stale := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "runner-0-jitconfig"},
+		Data:       map[string][]byte{jitConfigSecretKey: []byte("used")},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(sc).WithObjects(stale).Build()
+
+	r := &RunnerReconciler{
+		Client:       c,
+		Scheme:       sc,
+		Recorder:     record.NewFakeRecorder(10),
+		GitHubClient: newGithubClient(server),
+	}
+
+	require.NoError(t, r.ensureJITConfigSecret(ctx, runner, pod, logr.Discard()))
+
+	var secret corev1.Secret
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "runner-0-jitconfig"}, &secret))
+	require.Equal(t, githubfake.JITConfig, string(secret.Data[jitConfigSecretKey]))
+	require.Equal(t, "runner-0", secret.Labels["runner-pod"])
+}
+
+func TestPodRunnerTokenInjector_DryRun(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(sc).Build()
+
+	injector := &PodRunnerTokenInjector{Client: c}
+
+	dryRun := true
+
+	res := injector.Handle(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{DryRun: &dryRun},
+	})
+	require.True(t, res.Allowed)
+	require.Empty(t, res.Patches)
+
+	var secrets corev1.SecretList
+	require.NoError(t, c.List(context.Background(), &secrets))
+	require.Empty(t, secrets.Items, "no secret must be written on dry runs")
+}
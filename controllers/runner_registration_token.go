@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	registrationTokenSecretKey = "token"
+)
+
+func registrationTokenSecretName(podName string) string {
+	return podName + "-registration-token"
+}
+
+// newRegistrationTokenSecret returns the Secret holding the registration token of the runner pod.
+// The secret is labeled with the pod name, so that it's deleted along with the pod.
+func newRegistrationTokenSecret(namespace, podName, token string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      registrationTokenSecretName(podName),
+			Labels: map[string]string{
+				"runner-pod": podName,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			registrationTokenSecretKey: []byte(token),
+		},
+	}
+}
+
+// podUsesRegistrationTokenSecret returns true when the runner container of the pod reads the registration token from its Secret.
+func podUsesRegistrationTokenSecret(pod *corev1.Pod) bool {
+	for _, c := range pod.Spec.Containers {
+		if c.Name != containerName {
+			continue
+		}
+
+		for _, e := range c.Env {
+			if e.Name == EnvVarRunnerToken && e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
+				return e.ValueFrom.SecretKeyRef.Name == registrationTokenSecretName(pod.Name)
+			}
+		}
+	}
+
+	return false
+}
+
+// setRegistrationTokenSecretRef makes the runner container read the registration token from the Secret of the pod,
+// unless the container has its own RUNNER_TOKEN.
+func setRegistrationTokenSecretRef(pod *corev1.Pod) {
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		if c.Name != containerName {
+			continue
+		}
+
+		for _, e := range c.Env {
+			if e.Name == EnvVarRunnerToken {
+				return
+			}
+		}
+
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name: EnvVarRunnerToken,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: registrationTokenSecretName(pod.Name),
+					},
+					Key: registrationTokenSecretKey,
+				},
+			},
+		})
+	}
+}
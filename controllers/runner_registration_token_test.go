@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+	githubfake "github.com/actions-runner-controller/actions-runner-controller/github/fake"
+)
+
+func TestMutatePod_RegistrationTokenSecret(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "runner-0"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "runner"},
+				{Name: "docker"},
+			},
+		},
+	}
+
+	require.False(t, podUsesRegistrationTokenSecret(pod))
+
+	updated := mutatePod(pod)
+
+	require.True(t, podUsesRegistrationTokenSecret(updated))
+	require.Equal(t, []corev1.EnvVar{
+		{Name: EnvVarRunnerName, Value: "runner-0"},
+		{
+			Name: EnvVarRunnerToken,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "runner-0-registration-token"},
+					Key:                  "token",
+				},
+			},
+		},
+	}, updated.Spec.Containers[0].Env)
+	require.Empty(t, updated.Spec.Containers[1].Env)
+
+	// The token given by the user is kept as is
+	pod.Spec.Containers[0].Env = []corev1.EnvVar{{Name: EnvVarRunnerToken, Value: "user-token"}}
+
+	updated = mutatePod(pod)
+
+	require.False(t, podUsesRegistrationTokenSecret(updated))
+	require.Equal(t, "user-token", getRunnerEnv(updated, EnvVarRunnerToken))
+}
+
+func TestUpdateRegistrationToken(t *testing.T) {
+	ctx := context.Background()
+
+	server := githubfake.NewServer(
+		githubfake.WithListRepositoryWorkflowRunsResponse(200, "{}", "{}", "{}"),
+		githubfake.WithListWorkflowJobsResponse(200, nil),
+		githubfake.WithListRunnersResponse(200, githubfake.RunnersListBody),
+	)
+	defer server.Close()
+
+	runner := &v1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example-runner"},
+		Spec: v1alpha1.RunnerSpec{
+			RunnerConfig: v1alpha1.RunnerConfig{Organization: "test"},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(sc).WithObjects(runner).Build()
+
+	r := &RunnerReconciler{
+		Client:       c,
+		Log:          logr.Discard(),
+		Recorder:     record.NewFakeRecorder(10),
+		Scheme:       sc,
+		GitHubClient: newGithubClient(server),
+	}
+
+	getRunner := func() v1alpha1.Runner {
+		var got v1alpha1.Runner
+		require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example-runner"}, &got))
+		return got
+	}
+
+	secretKey := types.NamespacedName{Namespace: "default", Name: "example-runner-registration-token"}
+
+	updated, err := r.updateRegistrationToken(ctx, getRunner())
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	var secret corev1.Secret
+	require.NoError(t, c.Get(ctx, secretKey, &secret))
+	require.Equal(t, githubfake.RegistrationToken, string(secret.Data["token"]))
+	require.Equal(t, "example-runner", secret.Labels["runner-pod"])
+	require.Equal(t, "Runner", metav1.GetControllerOf(&secret).Kind)
+
+	registration := getRunner().Status.Registration
+	require.Equal(t, "example-runner-registration-token", registration.TokenSecretName)
+	require.False(t, registration.ExpiresAt.IsZero())
+
+	updated, err = r.updateRegistrationToken(ctx, getRunner())
+	require.NoError(t, err)
+	require.False(t, updated)
+
+	// The secret deleted along with the previous runner pod is recreated with a new token
+	require.NoError(t, c.Delete(ctx, &secret))
+
+	updated, err = r.updateRegistrationToken(ctx, getRunner())
+	require.NoError(t, err)
+	require.True(t, updated)
+	require.NoError(t, c.Get(ctx, secretKey, &secret))
+
+	// The expired token is rotated in the existing secret
+	expired := getRunner()
+	expired.Status.Registration.ExpiresAt = metav1.Time{}
+	require.NoError(t, c.Status().Update(ctx, &expired))
+
+	secret.Data["token"] = []byte("expired")
+	require.NoError(t, c.Update(ctx, &secret))
+
+	updated, err = r.updateRegistrationToken(ctx, getRunner())
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	require.NoError(t, c.Get(ctx, secretKey, &secret))
+	require.Equal(t, githubfake.RegistrationToken, string(secret.Data["token"]))
+}
+
+// staleSecretClient serves the secrets from the snapshot taken before they were deleted, like the informer cache lagging behind
+type staleSecretClient struct {
+	client.Client
+	secrets map[types.NamespacedName]corev1.Secret
+}
+
+func (c *staleSecretClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if s, ok := c.secrets[key]; ok {
+		if secret, ok := obj.(*corev1.Secret); ok {
+			s.DeepCopyInto(secret)
+			return nil
+		}
+	}
+
+	return c.Client.Get(ctx, key, obj)
+}
+
+func TestUpdateRegistrationToken_StaleCache(t *testing.T) {
+	ctx := context.Background()
+
+	server := githubfake.NewServer(
+		githubfake.WithListRepositoryWorkflowRunsResponse(200, "{}", "{}", "{}"),
+		githubfake.WithListWorkflowJobsResponse(200, nil),
+		githubfake.WithListRunnersResponse(200, githubfake.RunnersListBody),
+	)
+	defer server.Close()
+
+	runner := &v1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "example-runner"},
+		Spec: v1alpha1.RunnerSpec{
+			RunnerConfig: v1alpha1.RunnerConfig{Organization: "test"},
+		},
+		Status: v1alpha1.RunnerStatus{
+			Registration: v1alpha1.RunnerStatusRegistration{
+				Organization:    "test",
+				TokenSecretName: "example-runner-registration-token",
+				ExpiresAt:       metav1.NewTime(time.Now().Add(time.Hour)),
+			},
+		},
+	}
+
+	apiServer := fake.NewClientBuilder().WithScheme(sc).WithObjects(runner).Build()
+
+	secretKey := types.NamespacedName{Namespace: "default", Name: "example-runner-registration-token"}
+
+	// The secret has already been deleted along with the previous pod, but the cache still has it
+	cached := &staleSecretClient{
+		Client:  apiServer,
+		secrets: map[types.NamespacedName]corev1.Secret{secretKey: *newRegistrationTokenSecret("default", "example-runner", "used")},
+	}
+
+	r := &RunnerReconciler{
+		Client:       cached,
+		APIReader:    apiServer,
+		Log:          logr.Discard(),
+		Recorder:     record.NewFakeRecorder(10),
+		Scheme:       sc,
+		GitHubClient: newGithubClient(server),
+	}
+
+	var got v1alpha1.Runner
+	require.NoError(t, apiServer.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example-runner"}, &got))
+
+	updated, err := r.updateRegistrationToken(ctx, got)
+	require.NoError(t, err)
+	require.True(t, updated, "the secret must be recreated even when the cache still has it")
+
+	var secret corev1.Secret
+	require.NoError(t, apiServer.Get(ctx, secretKey, &secret))
+	require.Equal(t, githubfake.RegistrationToken, string(secret.Data["token"]))
+}
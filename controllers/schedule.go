@@ -2,14 +2,24 @@ package controllers
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/actions-runner-controller/actions-runner-controller/pkg/cron"
 	"github.com/teambition/rrule-go"
 )
 
 type RecurrenceRule struct {
 	Frequency string
 	UntilTime time.Time
+
+	// Schedule is a cron expression or an RRULE, each occurrence of which starts a period of Duration.
+	// Frequency and the end time are ignored when it's set.
+	Schedule string
+	Duration time.Duration
+
+	// TimeZone is the IANA time zone name in which Schedule and Frequency are interpreted. Defaults to UTC.
+	TimeZone string
 }
 
 type Period struct {
@@ -26,6 +36,31 @@ func (r *Period) String() string {
 }
 
 func MatchSchedule(now time.Time, startTime, endTime time.Time, recurrenceRule RecurrenceRule) (*Period, *Period, error) {
+	if recurrenceRule.TimeZone != "" {
+		loc, err := time.LoadLocation(recurrenceRule.TimeZone)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid time zone %q: %w", recurrenceRule.TimeZone, err)
+		}
+
+		// Recurrences are computed in the location of the start time,
+		// so that e.g. a daily override starts at the same local time across daylight saving time changes
+		now = now.In(loc)
+		startTime = startTime.In(loc)
+	} else if recurrenceRule.Schedule != "" {
+		now = now.UTC()
+		startTime = startTime.UTC()
+	}
+
+	if recurrenceRule.Schedule != "" {
+		return calculateActiveAndUpcomingScheduledPeriods(
+			now,
+			startTime,
+			recurrenceRule.Schedule,
+			recurrenceRule.Duration,
+			recurrenceRule.UntilTime,
+		)
+	}
+
 	return calculateActiveAndUpcomingRecurringPeriods(
 		now,
 		startTime,
@@ -120,3 +155,80 @@ func calculateActiveAndUpcomingRecurringPeriods(now, startTime, endTime time.Tim
 
 	return active, next, nil
 }
+
+// calculateActiveAndUpcomingScheduledPeriods returns the active and upcoming periods of duration, starting at each occurrence of
+// the schedule, which is either a cron expression like "0 8 * * 1-5" or an RRULE like "FREQ=WEEKLY;BYDAY=MO;BYHOUR=8;BYMINUTE=0;BYSECOND=0".
+// The schedule is interpreted in the location of now. Non-zero startTime and untilTime bound the occurrences.
+func calculateActiveAndUpcomingScheduledPeriods(now, startTime time.Time, schedule string, duration time.Duration, untilTime time.Time) (*Period, *Period, error) {
+	if duration <= 0 {
+		return nil, nil, fmt.Errorf("duration must be set to a positive value for schedule %q", schedule)
+	}
+
+	next, err := parseSchedule(schedule, startTime, now.Location())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	occurs := func(t time.Time) bool {
+		return !t.IsZero() && !t.Before(startTime) && (untilTime.IsZero() || !t.After(untilTime))
+	}
+
+	var active *Period
+
+	// The latest occurrence within the last duration is the active one
+	for t := next(now.Add(-duration)); !t.IsZero() && !t.After(now); t = next(t) {
+		if occurs(t) {
+			active = &Period{StartTime: t, EndTime: t.Add(duration)}
+		}
+	}
+
+	var upcoming *Period
+
+	after := now
+	if after.Before(startTime) {
+		after = startTime.Add(-1)
+	}
+
+	if t := next(after); occurs(t) {
+		upcoming = &Period{StartTime: t, EndTime: t.Add(duration)}
+	}
+
+	return active, upcoming, nil
+}
+
+// parseSchedule parses the cron expression or the RRULE and returns the function
+// that returns the first occurrence after the given time, or the zero time if there's none.
+func parseSchedule(schedule string, startTime time.Time, loc *time.Location) (func(time.Time) time.Time, error) {
+	if !strings.Contains(schedule, "FREQ=") {
+		s, err := cron.Parse(schedule)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(t time.Time) time.Time {
+			return s.Next(t.In(loc))
+		}, nil
+	}
+
+	opt, err := rrule.StrToROptionInLocation(strings.ReplaceAll(schedule, "RRULE:", ""), loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rrule %q: %w", schedule, err)
+	}
+
+	if opt.Dtstart.IsZero() {
+		if startTime.IsZero() {
+			return nil, fmt.Errorf("invalid rrule %q: either DTSTART or startTime must be set", schedule)
+		}
+
+		opt.Dtstart = startTime
+	}
+
+	r, err := rrule.NewRRule(*opt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rrule %q: %w", schedule, err)
+	}
+
+	return func(t time.Time) time.Time {
+		return r.After(t, false)
+	}, nil
+}
@@ -615,3 +615,175 @@ func FuzzMatchSchedule(f *testing.F) {
 		_, _, _ = MatchSchedule(now, start, end, RecurrenceRule{Frequency: freq})
 	})
 }
+
+func TestCalculateActiveAndUpcomingScheduledPeriods(t *testing.T) {
+	type testcase struct {
+		name string
+
+		now      string
+		start    string
+		until    string
+		schedule string
+		duration time.Duration
+		timeZone string
+
+		wantActive   string
+		wantUpcoming string
+	}
+
+	testcases := []testcase{
+		{
+			name:         "weekdays in berlin before the override",
+			now:          "2022-03-25T06:59:00Z",
+			schedule:     "0 8 * * 1-5",
+			duration:     11 * time.Hour,
+			timeZone:     "Europe/Berlin",
+			wantUpcoming: "2022-03-25T08:00:00+01:00-2022-03-25T19:00:00+01:00",
+		},
+		{
+			name:         "weekdays in berlin during the override",
+			now:          "2022-03-25T07:00:00Z",
+			schedule:     "0 8 * * 1-5",
+			duration:     11 * time.Hour,
+			timeZone:     "Europe/Berlin",
+			wantActive:   "2022-03-25T08:00:00+01:00-2022-03-25T19:00:00+01:00",
+			wantUpcoming: "2022-03-28T08:00:00+02:00-2022-03-28T19:00:00+02:00",
+		},
+		{
+			name:         "weekend in berlin",
+			now:          "2022-03-26T12:00:00Z",
+			schedule:     "0 8 * * MON-FRI",
+			duration:     11 * time.Hour,
+			timeZone:     "Europe/Berlin",
+			wantUpcoming: "2022-03-28T08:00:00+02:00-2022-03-28T19:00:00+02:00",
+		},
+		{
+			name:         "utc by default",
+			now:          "2022-03-25T07:00:00Z",
+			schedule:     "0 8 * * 1-5",
+			duration:     11 * time.Hour,
+			wantUpcoming: "2022-03-25T08:00:00Z-2022-03-25T19:00:00Z",
+		},
+		{
+			name:         "day of month or day of week",
+			now:          "2022-03-01T00:30:00Z",
+			schedule:     "0 0 1,15 * 5",
+			duration:     time.Hour,
+			wantActive:   "2022-03-01T00:00:00Z-2022-03-01T01:00:00Z",
+			wantUpcoming: "2022-03-04T00:00:00Z-2022-03-04T01:00:00Z",
+		},
+		{
+			name:         "not before the start time",
+			now:          "2022-03-25T09:00:00Z",
+			start:        "2022-04-01T00:00:00Z",
+			schedule:     "@daily",
+			duration:     time.Hour,
+			wantUpcoming: "2022-04-01T00:00:00Z-2022-04-01T01:00:00Z",
+		},
+		{
+			name:       "not after the until time",
+			now:        "2022-03-25T00:30:00Z",
+			until:      "2022-03-25T12:00:00Z",
+			schedule:   "@daily",
+			duration:   time.Hour,
+			wantActive: "2022-03-25T00:00:00Z-2022-03-25T01:00:00Z",
+		},
+		{
+			name:         "rrule",
+			now:          "2022-03-25T07:00:00Z",
+			schedule:     "DTSTART;TZID=Europe/Berlin:20220101T000000\nRRULE:FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR;BYHOUR=8;BYMINUTE=0;BYSECOND=0",
+			duration:     11 * time.Hour,
+			timeZone:     "Europe/Berlin",
+			wantActive:   "2022-03-25T08:00:00+01:00-2022-03-25T19:00:00+01:00",
+			wantUpcoming: "2022-03-28T08:00:00+02:00-2022-03-28T19:00:00+02:00",
+		},
+		{
+			name:         "rrule starting at the start time",
+			now:          "2022-03-25T07:00:00Z",
+			start:        "2022-01-01T00:00:00Z",
+			schedule:     "FREQ=DAILY;BYHOUR=12;BYMINUTE=0;BYSECOND=0",
+			duration:     time.Hour,
+			wantUpcoming: "2022-03-25T12:00:00Z-2022-03-25T13:00:00Z",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			now, err := time.Parse(time.RFC3339, tc.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var start, until time.Time
+
+			if tc.start != "" {
+				if start, err = time.Parse(time.RFC3339, tc.start); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tc.until != "" {
+				if until, err = time.Parse(time.RFC3339, tc.until); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			active, upcoming, err := MatchSchedule(now, start, time.Time{}, RecurrenceRule{
+				UntilTime: until,
+				Schedule:  tc.schedule,
+				Duration:  tc.duration,
+				TimeZone:  tc.timeZone,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if active.String() != tc.wantActive {
+				t.Errorf("unexpected active: want %q, got %q", tc.wantActive, active)
+			}
+
+			if upcoming.String() != tc.wantUpcoming {
+				t.Errorf("unexpected upcoming: want %q, got %q", tc.wantUpcoming, upcoming)
+			}
+		})
+	}
+}
+
+func TestMatchScheduleErrors(t *testing.T) {
+	now := time.Now()
+
+	for _, r := range []RecurrenceRule{
+		{Schedule: "0 8 * *", Duration: time.Hour},
+		{Schedule: "0 24 * * *", Duration: time.Hour},
+		{Schedule: "0 8 * * 5-1", Duration: time.Hour},
+		{Schedule: "0 8 * * *"},
+		{Schedule: "FREQ=DAILY;BYHOUR=8", Duration: time.Hour},
+		{Schedule: "0 8 * * *", Duration: time.Hour, TimeZone: "Mars/Olympus_Mons"},
+	} {
+		if _, _, err := MatchSchedule(now, time.Time{}, time.Time{}, r); err == nil {
+			t.Errorf("expected error for %+v", r)
+		}
+	}
+}
+
+func TestMatchScheduleFrequencyInTimeZone(t *testing.T) {
+	start, err := time.Parse(time.RFC3339, "2022-03-20T08:00:00+01:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now, err := time.Parse(time.RFC3339, "2022-03-28T06:30:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The daily override keeps starting at 08:00 in Berlin after the daylight saving time starts
+	active, _, err := MatchSchedule(now, start, start.Add(time.Hour), RecurrenceRule{Frequency: "Daily", TimeZone: "Europe/Berlin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "2022-03-28T08:00:00+02:00-2022-03-28T09:00:00+02:00"; active.String() != want {
+		t.Errorf("unexpected active: want %q, got %q", want, active)
+	}
+}
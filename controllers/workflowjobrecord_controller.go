@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+)
+
+const (
+	// DefaultWorkflowJobRecordTTL is the default duration a WorkflowJobRecord is kept after its job completes
+	DefaultWorkflowJobRecordTTL = 24 * time.Hour
+)
+
+// WorkflowJobRecordReconciler deletes WorkflowJobRecords after their TTL
+type WorkflowJobRecordReconciler struct {
+	client.Client
+	Log  logr.Logger
+	Name string
+
+	// TTL is how long a WorkflowJobRecord is kept after its job completes.
+	// The record of a job that was never seen completed is kept for TTL after it's created.
+	// Defaults to DefaultWorkflowJobRecordTTL.
+	TTL time.Duration
+}
+
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=workflowjobrecords,verbs=get;list;watch;create;patch;delete
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=workflowjobrecords/status,verbs=get;update;patch
+
+func (r *WorkflowJobRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("workflowjobrecord", req.NamespacedName)
+
+	var record v1alpha1.WorkflowJobRecord
+	if err := r.Get(ctx, req.NamespacedName, &record); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !record.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	ttl := r.TTL
+	if ttl == 0 {
+		ttl = DefaultWorkflowJobRecordTTL
+	}
+
+	expiresAt := record.CreationTimestamp.Add(ttl)
+	if completedAt := record.Status.CompletedAt; completedAt != nil {
+		expiresAt = completedAt.Add(ttl)
+	}
+
+	if d := time.Until(expiresAt); d > 0 {
+		return ctrl.Result{RequeueAfter: d}, nil
+	}
+
+	if err := r.Delete(ctx, &record); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	log.V(1).Info("Deleted expired workflow job record", "status", record.Status.Status, "completedAt", record.Status.CompletedAt)
+
+	return ctrl.Result{}, nil
+}
+
+func (r *WorkflowJobRecordReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	name := "workflowjobrecord-controller"
+	if r.Name != "" {
+		name = r.Name
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.WorkflowJobRecord{}).
+		Named(name).
+		Complete(r)
+}
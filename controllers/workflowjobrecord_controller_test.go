@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/actions-runner-controller/actions-runner-controller/api/v1alpha1"
+)
+
+func TestWorkflowJobRecordReconciler(t *testing.T) {
+	ctx := context.Background()
+
+	completedAt := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+
+	expired := &v1alpha1.WorkflowJobRecord{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "job-1"},
+		Status:     v1alpha1.WorkflowJobRecordStatus{Status: v1alpha1.WorkflowJobStatusCompleted, CompletedAt: &completedAt},
+	}
+
+	inProgress := &v1alpha1.WorkflowJobRecord{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "job-2", CreationTimestamp: completedAt},
+		Status:     v1alpha1.WorkflowJobRecordStatus{Status: v1alpha1.WorkflowJobStatusInProgress},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(sc).WithObjects(expired, inProgress).Build()
+
+	r := &WorkflowJobRecordReconciler{
+		Client: c,
+		Log:    logr.Discard(),
+		TTL:    time.Hour,
+	}
+
+	reconcile := func(name string) time.Duration {
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}})
+		require.NoError(t, err)
+		return res.RequeueAfter
+	}
+
+	require.Zero(t, reconcile("job-1"))
+
+	var got v1alpha1.WorkflowJobRecord
+	require.Error(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "job-1"}, &got))
+
+	// The record of a job not seen completed is kept for TTL after it's created
+	r.TTL = 3 * time.Hour
+
+	requeueAfter := reconcile("job-2")
+	require.True(t, requeueAfter > 0 && requeueAfter <= time.Hour, "unexpected requeueAfter: %s", requeueAfter)
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "job-2"}, &got))
+}
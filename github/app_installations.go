@@ -0,0 +1,205 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+const (
+	// appInstallationsRefreshInterval is how often we list the installations of the GitHub App
+	// to notice installations that are removed or moved to another account.
+	appInstallationsRefreshInterval = 10 * time.Minute
+
+	// appInstallationsMinRefreshInterval is the minimum interval between listings triggered by an unknown owner,
+	// so that a runner for an organization the App isn't installed to doesn't exhaust the API rate limit.
+	appInstallationsMinRefreshInterval = time.Minute
+)
+
+// appInstallations discovers the installations of a GitHub App and creates a client per installation,
+// so that a single App can serve any number of organizations and enterprises without configuring installation IDs.
+type appInstallations struct {
+	// appClient authenticates as the App itself with a JWT
+	appClient *github.Client
+
+	// config is used to create the installation clients
+	config Config
+
+	mu sync.Mutex
+	// installationIDs is the installation ID keyed by the lower-cased login of the organization or the user,
+	// or the lower-cased slug of the enterprise the App is installed to
+	installationIDs map[string]int64
+	clients         map[int64]*Client
+	listedAt        time.Time
+
+	// refreshing is closed when the listing in flight completes, and nil when there's none.
+	// refreshErr is the error of the last listing.
+	refreshing chan struct{}
+	refreshErr error
+
+	now func() time.Time
+}
+
+func newAppInstallations(appClient *github.Client, config Config) *appInstallations {
+	return &appInstallations{
+		appClient:       appClient,
+		config:          config,
+		installationIDs: map[string]int64{},
+		clients:         map[int64]*Client{},
+		now:             time.Now,
+	}
+}
+
+// clientFor returns the client of the installation for the owner, which is an enterprise, an organization, or a user.
+func (i *appInstallations) clientFor(ctx context.Context, owner string) (*Client, error) {
+	if owner == "" {
+		return nil, fmt.Errorf("owner is required to find the installation of github app %d", i.config.AppID)
+	}
+
+	key := strings.ToLower(owner)
+
+	i.mu.Lock()
+	sinceListed := i.now().Sub(i.listedAt)
+	_, ok := i.installationIDs[key]
+	i.mu.Unlock()
+
+	// The installations are listed without the lock held, so that the clients of the known installations
+	// don't wait for the listing
+	if sinceListed >= appInstallationsRefreshInterval || (!ok && sinceListed >= appInstallationsMinRefreshInterval) {
+		if err := i.refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	id, ok := i.installationIDs[key]
+	if !ok {
+		return nil, fmt.Errorf("github app %d is not installed to %q", i.config.AppID, owner)
+	}
+
+	if c, ok := i.clients[id]; ok {
+		return c, nil
+	}
+
+	conf := i.config
+	conf.AppInstallationID = id
+
+	c, err := conf.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("creating client for installation %d of github app %d: %w", id, i.config.AppID, err)
+	}
+
+	i.clients[id] = c
+
+	return c, nil
+}
+
+// refresh lists the installations of the App and drops the clients of the installations that no longer exist.
+// The callers concurrent with the listing in flight wait for it rather than listing again.
+// It must be called without the lock held.
+func (i *appInstallations) refresh(ctx context.Context) error {
+	i.mu.Lock()
+	if refreshing := i.refreshing; refreshing != nil {
+		i.mu.Unlock()
+
+		select {
+		case <-refreshing:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		i.mu.Lock()
+		defer i.mu.Unlock()
+
+		return i.refreshErr
+	}
+
+	refreshing := make(chan struct{})
+	i.refreshing = refreshing
+	i.mu.Unlock()
+
+	installationIDs, err := i.listInstallations(ctx)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.refreshing = nil
+	i.refreshErr = err
+	close(refreshing)
+
+	if err != nil {
+		return err
+	}
+
+	active := map[int64]struct{}{}
+	for _, id := range installationIDs {
+		active[id] = struct{}{}
+	}
+
+	for id := range i.clients {
+		if _, ok := active[id]; !ok {
+			delete(i.clients, id)
+		}
+	}
+
+	i.installationIDs = installationIDs
+	i.listedAt = i.now()
+
+	return nil
+}
+
+// appInstallation is an installation of the App.
+// go-github's Installation lacks the slug of the enterprise account, which has no login.
+type appInstallation struct {
+	ID      int64 `json:"id"`
+	Account struct {
+		Login string `json:"login"`
+		Slug  string `json:"slug"`
+	} `json:"account"`
+	SuspendedAt *time.Time `json:"suspended_at"`
+}
+
+// listInstallations returns the IDs of the active installations of the App, keyed by the lower-cased login or slug of their accounts.
+func (i *appInstallations) listInstallations(ctx context.Context) (map[string]int64, error) {
+	installationIDs := map[string]int64{}
+
+	page := 1
+	for {
+		req, err := i.appClient.NewRequest("GET", fmt.Sprintf("app/installations?per_page=100&page=%d", page), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var list []appInstallation
+
+		res, err := i.appClient.Do(ctx, req, &list)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list installations of github app %d: %w", i.config.AppID, err)
+		}
+
+		for _, inst := range list {
+			if inst.SuspendedAt != nil && !inst.SuspendedAt.IsZero() {
+				continue
+			}
+
+			for _, name := range []string{inst.Account.Login, inst.Account.Slug} {
+				if name != "" {
+					installationIDs[strings.ToLower(name)] = inst.ID
+				}
+			}
+		}
+
+		if res.NextPage == 0 {
+			break
+		}
+		page = res.NextPage
+	}
+
+	return installationIDs, nil
+}
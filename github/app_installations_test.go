@@ -0,0 +1,229 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeAppServer struct {
+	mu sync.Mutex
+
+	// installations is the JSON of each installation of the App
+	installations []string
+
+	listed int
+
+	// listDelay delays the response of the listing, to let concurrent clients wait for it
+	listDelay time.Duration
+}
+
+func (s *fakeAppServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(req.URL.Path, "/api/v3")
+	auth := req.Header.Get("Authorization")
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case path == "/app/installations" && req.Method == http.MethodGet:
+		if !strings.HasPrefix(auth, "Bearer ") {
+			http.Error(w, "expected a jwt", http.StatusUnauthorized)
+			return
+		}
+
+		s.listed++
+
+		if s.listDelay > 0 {
+			s.mu.Unlock()
+			time.Sleep(s.listDelay)
+			s.mu.Lock()
+		}
+
+		fmt.Fprintf(w, "[%s]", strings.Join(s.installations, ","))
+	case strings.HasPrefix(path, "/app/installations/") && strings.HasSuffix(path, "/access_tokens") && req.Method == http.MethodPost:
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/app/installations/"), "/access_tokens")
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": "token-%s", "expires_at": %q}`, id, time.Now().Add(time.Hour).Format(time.RFC3339))
+	case path == "/orgs/org-a/actions/runners":
+		if auth != "token token-11" {
+			http.Error(w, fmt.Sprintf("unexpected authorization %q", auth), http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprint(w, `{"total_count": 1, "runners": [{"id": 1, "name": "org-a-runner"}]}`)
+	case path == "/orgs/org-c/actions/runners":
+		if auth != "token token-13" {
+			http.Error(w, fmt.Sprintf("unexpected authorization %q", auth), http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprint(w, `{"total_count": 0, "runners": []}`)
+	case path == "/enterprises/my-enterprise/actions/runners":
+		if auth != "token token-14" {
+			http.Error(w, fmt.Sprintf("unexpected authorization %q", auth), http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprint(w, `{"total_count": 1, "runners": [{"id": 2, "name": "enterprise-runner"}]}`)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func newTestPrivateKey(t *testing.T) string {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+}
+
+func TestAppInstallationDiscovery(t *testing.T) {
+	s := &fakeAppServer{
+		installations: []string{
+			`{"id": 11, "account": {"login": "Org-A"}}`,
+			`{"id": 12, "account": {"login": "org-b"}, "suspended_at": "2022-01-01T00:00:00Z"}`,
+			// An enterprise account has a slug instead of a login
+			`{"id": 14, "target_type": "Enterprise", "account": {"slug": "My-Enterprise", "name": "My Enterprise"}}`,
+		},
+	}
+
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	c := Config{
+		EnterpriseURL: server.URL,
+		AppID:         1,
+		AppPrivateKey: newTestPrivateKey(t),
+	}
+
+	client, err := c.NewClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	client.installations.now = func() time.Time { return now }
+
+	ctx := context.Background()
+
+	runners, err := client.ListRunners(ctx, "", "org-a", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runners) != 1 || runners[0].GetName() != "org-a-runner" {
+		t.Errorf("unexpected runners: %v", runners)
+	}
+
+	runners, err = client.ListRunners(ctx, "my-enterprise", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runners) != 1 || runners[0].GetName() != "enterprise-runner" {
+		t.Errorf("unexpected enterprise runners: %v", runners)
+	}
+
+	if _, err := client.ListRunners(ctx, "", "org-b", ""); err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Errorf("expected the suspended installation not to be used, got %v", err)
+	}
+
+	// The App is installed to a new organization after the last listing
+	s.mu.Lock()
+	s.installations = append(s.installations, `{"id": 13, "account": {"login": "org-c"}}`)
+	s.mu.Unlock()
+
+	if _, err := client.ListRunners(ctx, "", "org-c", ""); err == nil {
+		t.Errorf("expected the installations not to be listed again so soon")
+	}
+
+	now = now.Add(appInstallationsMinRefreshInterval)
+
+	if _, err := client.ListRunners(ctx, "", "org-c", ""); err != nil {
+		t.Errorf("expected the new installation to be discovered, got %v", err)
+	}
+
+	if s.listed != 2 {
+		t.Errorf("unexpected number of installation listings: want 2, got %d", s.listed)
+	}
+
+	// The App is uninstalled from the organization
+	s.mu.Lock()
+	s.installations = s.installations[1:]
+	s.mu.Unlock()
+
+	now = now.Add(appInstallationsRefreshInterval)
+
+	if _, err := client.ListRunners(ctx, "", "org-a", ""); err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Errorf("expected the removed installation not to be used, got %v", err)
+	}
+
+	if _, ok := client.installations.clients[11]; ok {
+		t.Errorf("expected the client of the removed installation to be dropped")
+	}
+}
+
+func TestAppInstallationDiscovery_Concurrent(t *testing.T) {
+	s := &fakeAppServer{
+		installations: []string{`{"id": 11, "account": {"login": "org-a"}}`},
+		listDelay:     100 * time.Millisecond,
+	}
+
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	c := Config{
+		EnterpriseURL: server.URL,
+		AppID:         1,
+		AppPrivateKey: newTestPrivateKey(t),
+	}
+
+	client, err := c.NewClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 10)
+
+	for n := 0; n < 10; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := client.installations.clientFor(ctx, "org-a")
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if s.listed != 1 {
+		t.Errorf("expected the concurrent clients to share one listing, got %d listings", s.listed)
+	}
+}
@@ -13,6 +13,25 @@ import (
 const (
 	RegistrationToken = "fake-registration-token"
 
+	JITConfig = "fake-encoded-jit-config"
+
+	JITConfigBody = `
+{
+  "runner": {"id": 3, "name": "test3", "os": "linux", "status": "offline", "busy": false},
+  "encoded_jit_config": "fake-encoded-jit-config"
+}
+`
+
+	RunnerGroupsListBody = `
+{
+  "total_count": 2,
+  "runner_groups": [
+    {"id": 1, "name": "Default", "visibility": "all", "default": true},
+    {"id": 2, "name": "gpu", "visibility": "all", "default": false}
+  ]
+}
+`
+
 	RunnersListBody = `
 {
   "total_count": 2,
@@ -203,6 +222,30 @@ func NewServer(opts ...Option) *httptest.Server {
 			Body:   "",
 		},
 
+		// For GenerateJITConfig
+		"/repos/test/valid/actions/runners/generate-jitconfig": &Handler{
+			Status: http.StatusCreated,
+			Body:   JITConfigBody,
+		},
+		"/repos/test/error/actions/runners/generate-jitconfig": &Handler{
+			Status: http.StatusBadRequest,
+			Body:   "",
+		},
+		"/orgs/test/actions/runners/generate-jitconfig": &Handler{
+			Status: http.StatusCreated,
+			Body:   JITConfigBody,
+		},
+		"/enterprises/test/actions/runners/generate-jitconfig": &Handler{
+			Status: http.StatusCreated,
+			Body:   JITConfigBody,
+		},
+
+		// For GetRunnerGroupID
+		"/orgs/test/actions/runner-groups": &Handler{
+			Status: http.StatusOK,
+			Body:   RunnerGroupsListBody,
+		},
+
 		// For auto-scaling based on the number of queued(pending) workflow runs
 		"/repos/test/valid/actions/runs": config.FixedResponses.ListRepositoryWorkflowRuns,
 
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/actions-runner-controller/actions-runner-controller/github/metrics"
+	"github.com/actions-runner-controller/actions-runner-controller/github/ratelimit"
 	"github.com/actions-runner-controller/actions-runner-controller/logging"
 	"github.com/bradleyfalzon/ghinstallation/v2"
 	"github.com/go-logr/logr"
@@ -42,6 +43,10 @@ type Client struct {
 	mu        sync.Mutex
 	// GithubBaseURL to Github without API suffix.
 	GithubBaseURL string
+	// installations is set when the client authenticates as a GitHub App without the installation ID
+	installations *appInstallations
+	// repositoryRotation is the next page of the repositories rotated by ListActiveOrganizationRepositories, per organization
+	repositoryRotation map[string]int
 }
 
 type BasicAuthTransport struct {
@@ -57,10 +62,38 @@ func (p BasicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error)
 // NewClient creates a Github Client
 func (c *Config) NewClient() (*Client, error) {
 	var transport http.RoundTripper
+	var discoverInstallations bool
 	if len(c.BasicauthUsername) > 0 && len(c.BasicauthPassword) > 0 {
 		transport = BasicAuthTransport{Username: c.BasicauthUsername, Password: c.BasicauthPassword}
 	} else if len(c.Token) > 0 {
 		transport = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: c.Token})).Transport
+	} else if c.AppInstallationID == 0 {
+		// Authenticate as the App to discover its installations.
+		// Every API call for an owner is made by the client of the installation for the owner.
+		var err error
+		var tr *ghinstallation.AppsTransport
+
+		if _, err = os.Stat(c.AppPrivateKey); err == nil {
+			tr, err = ghinstallation.NewAppsTransportKeyFromFile(http.DefaultTransport, c.AppID, c.AppPrivateKey)
+			if err != nil {
+				return nil, fmt.Errorf("authentication failed: using private key at %s: %v", c.AppPrivateKey, err)
+			}
+		} else {
+			tr, err = ghinstallation.NewAppsTransport(http.DefaultTransport, c.AppID, []byte(c.AppPrivateKey))
+			if err != nil {
+				return nil, fmt.Errorf("authentication failed: using private key of size %d (%s...): %v", len(c.AppPrivateKey), strings.Split(c.AppPrivateKey, "\n")[0], err)
+			}
+		}
+
+		if len(c.EnterpriseURL) > 0 {
+			githubAPIURL, err := getEnterpriseApiUrl(c.EnterpriseURL)
+			if err != nil {
+				return nil, fmt.Errorf("enterprise url incorrect: %v", err)
+			}
+			tr.BaseURL = githubAPIURL
+		}
+		transport = tr
+		discoverInstallations = true
 	} else {
 		var tr *ghinstallation.Transport
 
@@ -86,8 +119,12 @@ func (c *Config) NewClient() (*Client, error) {
 		transport = tr
 	}
 
+	// The rate limit budgeting is done beneath the cache, so that calls served from the cache are never throttled
+	budget := ratelimit.NewBudget()
+	rateLimited := ratelimit.Transport{Transport: transport, Budget: budget}
+
 	cached := httpcache.NewTransport(httpcache.NewMemoryCache())
-	cached.Transport = transport
+	cached.Transport = rateLimited
 	loggingTransport := logging.Transport{Transport: cached, Log: c.Log}
 	metricsTransport := metrics.Transport{Transport: loggingTransport}
 	httpClient := &http.Client{Transport: metricsTransport}
@@ -137,14 +174,35 @@ func (c *Config) NewClient() (*Client, error) {
 
 	client.UserAgent = "actions-runner-controller"
 
+	var installations *appInstallations
+	if discoverInstallations {
+		installations = newAppInstallations(client, *c)
+	}
+
 	return &Client{
 		Client:        client,
 		regTokens:     map[string]*github.RegistrationToken{},
 		mu:            sync.Mutex{},
 		GithubBaseURL: githubBaseURL,
+		installations: installations,
 	}, nil
 }
 
+// clientFor returns the go-github client to call the API for the owner, which is an enterprise, an organization, or a user.
+// It's the client of the GitHub App installation for the owner when the installation ID isn't configured.
+func (c *Client) clientFor(ctx context.Context, owner string) (*github.Client, error) {
+	if c.installations == nil {
+		return c.Client, nil
+	}
+
+	ic, err := c.installations.clientFor(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return ic.Client, nil
+}
+
 // GetRegistrationToken returns a registration token tied with the name of repository and runner.
 func (c *Client) GetRegistrationToken(ctx context.Context, enterprise, org, repo, name string) (*github.RegistrationToken, error) {
 	c.mu.Lock()
@@ -176,10 +234,10 @@ func (c *Client) GetRegistrationToken(ctx context.Context, enterprise, org, repo
 		return rt, err
 	}
 
-	rt, res, err := c.createRegistrationToken(ctx, enterprise, owner, repo)
+	rt, res, err := c.createRegistrationToken(ratelimit.WithPriority(ctx, ratelimit.PriorityHigh), enterprise, owner, repo)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to create registration token: %v", err)
+		return nil, fmt.Errorf("failed to create registration token: %w", err)
 	}
 
 	if res.StatusCode != 201 {
@@ -202,7 +260,7 @@ func (c *Client) RemoveRunner(ctx context.Context, enterprise, org, repo string,
 		return err
 	}
 
-	res, err := c.removeRunner(ctx, enterprise, owner, repo, runnerID)
+	res, err := c.removeRunner(ratelimit.WithPriority(ctx, ratelimit.PriorityHigh), enterprise, owner, repo, runnerID)
 
 	if err != nil {
 		return fmt.Errorf("failed to remove runner: %w", err)
@@ -248,10 +306,15 @@ func (c *Client) ListRunners(ctx context.Context, enterprise, org, repo string)
 func (c *Client) ListOrganizationRunnerGroups(ctx context.Context, org string) ([]*github.RunnerGroup, error) {
 	var runnerGroups []*github.RunnerGroup
 
+	gh, err := c.clientFor(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+
 	opts := github.ListOrgRunnerGroupOptions{}
 	opts.PerPage = 100
 	for {
-		list, res, err := c.Client.Actions.ListOrganizationRunnerGroups(ctx, org, &opts)
+		list, res, err := gh.Actions.ListOrganizationRunnerGroups(ctx, org, &opts)
 		if err != nil {
 			return runnerGroups, fmt.Errorf("failed to list organization runner groups: %w", err)
 		}
@@ -292,9 +355,14 @@ func (c *Client) ListOrganizationRunnerGroupsForRepository(ctx context.Context,
 func (c *Client) ListRunnerGroupRepositoryAccesses(ctx context.Context, org string, runnerGroupId int64) ([]*github.Repository, error) {
 	var repos []*github.Repository
 
+	gh, err := c.clientFor(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+
 	opts := github.ListOptions{PerPage: 100}
 	for {
-		list, res, err := c.Client.Actions.ListRepositoryAccessRunnerGroup(ctx, org, runnerGroupId, &opts)
+		list, res, err := gh.Actions.ListRepositoryAccessRunnerGroup(ctx, org, runnerGroupId, &opts)
 		if err != nil {
 			return nil, fmt.Errorf("failed to list repository access for runner group: %w", err)
 		}
@@ -332,13 +400,18 @@ func (c *Client) listOrganizationRunnerGroupsVisibleToRepo(ctx context.Context,
 		}
 	}
 
-	req, err := c.Client.NewRequest("GET", u, nil)
+	gh, err := c.clientFor(ctx, org)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := gh.NewRequest("GET", u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	groups := &github.RunnerGroups{}
-	resp, err := c.Client.Do(ctx, req, &groups)
+	resp, err := gh.Do(ctx, req, &groups)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -362,33 +435,48 @@ func (c *Client) cleanup() {
 // so the calling functions don't need to switch and their code is a bit cleaner
 
 func (c *Client) createRegistrationToken(ctx context.Context, enterprise, org, repo string) (*github.RegistrationToken, *github.Response, error) {
+	gh, err := c.clientFor(ctx, ownerOf(enterprise, org))
+	if err != nil {
+		return nil, nil, err
+	}
+
 	if len(repo) > 0 {
-		return c.Client.Actions.CreateRegistrationToken(ctx, org, repo)
+		return gh.Actions.CreateRegistrationToken(ctx, org, repo)
 	}
 	if len(org) > 0 {
-		return c.Client.Actions.CreateOrganizationRegistrationToken(ctx, org)
+		return gh.Actions.CreateOrganizationRegistrationToken(ctx, org)
 	}
-	return c.Client.Enterprise.CreateRegistrationToken(ctx, enterprise)
+	return gh.Enterprise.CreateRegistrationToken(ctx, enterprise)
 }
 
 func (c *Client) removeRunner(ctx context.Context, enterprise, org, repo string, runnerID int64) (*github.Response, error) {
+	gh, err := c.clientFor(ctx, ownerOf(enterprise, org))
+	if err != nil {
+		return nil, err
+	}
+
 	if len(repo) > 0 {
-		return c.Client.Actions.RemoveRunner(ctx, org, repo, runnerID)
+		return gh.Actions.RemoveRunner(ctx, org, repo, runnerID)
 	}
 	if len(org) > 0 {
-		return c.Client.Actions.RemoveOrganizationRunner(ctx, org, runnerID)
+		return gh.Actions.RemoveOrganizationRunner(ctx, org, runnerID)
 	}
-	return c.Client.Enterprise.RemoveRunner(ctx, enterprise, runnerID)
+	return gh.Enterprise.RemoveRunner(ctx, enterprise, runnerID)
 }
 
 func (c *Client) listRunners(ctx context.Context, enterprise, org, repo string, opts *github.ListOptions) (*github.Runners, *github.Response, error) {
+	gh, err := c.clientFor(ctx, ownerOf(enterprise, org))
+	if err != nil {
+		return nil, nil, err
+	}
+
 	if len(repo) > 0 {
-		return c.Client.Actions.ListRunners(ctx, org, repo, opts)
+		return gh.Actions.ListRunners(ctx, org, repo, opts)
 	}
 	if len(org) > 0 {
-		return c.Client.Actions.ListOrganizationRunners(ctx, org, opts)
+		return gh.Actions.ListOrganizationRunners(ctx, org, opts)
 	}
-	return c.Client.Enterprise.ListRunners(ctx, enterprise, opts)
+	return gh.Enterprise.ListRunners(ctx, enterprise, opts)
 }
 
 func (c *Client) ListRepositoryWorkflowRuns(ctx context.Context, user string, repoName string) ([]*github.WorkflowRun, error) {
@@ -420,11 +508,16 @@ func (c *Client) listRepositoryWorkflowRuns(ctx context.Context, user string, re
 		Status: status,
 	}
 
+	gh, err := c.clientFor(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
 	for {
-		list, res, err := c.Client.Actions.ListRepositoryWorkflowRuns(ctx, user, repoName, &opts)
+		list, res, err := gh.Actions.ListRepositoryWorkflowRuns(ctx, user, repoName, &opts)
 
 		if err != nil {
-			return workflowRuns, fmt.Errorf("failed to list workflow runs: %v", err)
+			return workflowRuns, fmt.Errorf("failed to list workflow runs: %w", err)
 		}
 
 		workflowRuns = append(workflowRuns, list.WorkflowRuns...)
@@ -437,6 +530,118 @@ func (c *Client) listRepositoryWorkflowRuns(ctx context.Context, user string, re
 	return workflowRuns, nil
 }
 
+// ListActiveOrganizationRepositories returns up to max repositories of the organization that can have workflow runs.
+// The first half of them are the most recently pushed ones. The other half rotates through the rest of the repositories
+// in the order of their names across calls, so that a repository without recent pushes, like the one with queued runs
+// triggered by schedule or workflow_dispatch, is eventually scanned too.
+func (c *Client) ListActiveOrganizationRepositories(ctx context.Context, org string, max int) ([]*github.Repository, error) {
+	gh, err := c.clientFor(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+
+	rotating := max / 2
+	recent := max - rotating
+
+	repos, err := listOrganizationRepositories(ctx, gh, org, github.RepositoryListByOrgOptions{Sort: "pushed", Direction: "desc"}, recent, nil)
+	if err != nil {
+		return repos, err
+	}
+
+	if rotating == 0 {
+		return repos, nil
+	}
+
+	seen := make(map[string]struct{}, len(repos))
+	for _, r := range repos {
+		seen[r.GetName()] = struct{}{}
+	}
+
+	c.mu.Lock()
+	page := c.repositoryRotation[org]
+	c.mu.Unlock()
+
+	if page == 0 {
+		page = 1
+	}
+
+	opts := github.RepositoryListByOrgOptions{
+		Sort:      "full_name",
+		Direction: "asc",
+		ListOptions: github.ListOptions{
+			Page:    page,
+			PerPage: rotating,
+		},
+	}
+
+	list, res, err := gh.Repositories.ListByOrg(ctx, org, &opts)
+	if err != nil {
+		return repos, fmt.Errorf("failed to list repositories: %w", err)
+	}
+
+	for _, repo := range list {
+		if _, ok := seen[repo.GetName()]; ok || repo.GetArchived() || repo.GetDisabled() {
+			continue
+		}
+
+		repos = append(repos, repo)
+
+		if len(repos) >= max {
+			break
+		}
+	}
+
+	next := res.NextPage
+	if next == 0 {
+		// Wrap around to the first page
+		next = 1
+	}
+
+	c.mu.Lock()
+	if c.repositoryRotation == nil {
+		c.repositoryRotation = map[string]int{}
+	}
+	c.repositoryRotation[org] = next
+	c.mu.Unlock()
+
+	return repos, nil
+}
+
+// listOrganizationRepositories appends up to max repositories of the organization listed with opts to repos,
+// skipping archived and disabled ones.
+func listOrganizationRepositories(ctx context.Context, gh *github.Client, org string, opts github.RepositoryListByOrgOptions, max int, repos []*github.Repository) ([]*github.Repository, error) {
+	opts.PerPage = 100
+	if max < opts.PerPage {
+		opts.PerPage = max
+	}
+
+	for len(repos) < max {
+		list, res, err := gh.Repositories.ListByOrg(ctx, org, &opts)
+		if err != nil {
+			return repos, fmt.Errorf("failed to list repositories: %w", err)
+		}
+
+		for _, repo := range list {
+			if repo.GetArchived() || repo.GetDisabled() {
+				continue
+			}
+
+			repos = append(repos, repo)
+
+			if len(repos) >= max {
+				break
+			}
+		}
+
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	return repos, nil
+}
+
 // Validates enterprise, organization and repo arguments. Both are optional, but at least one should be specified
 func getEnterpriseOrganizationAndRepo(enterprise, org, repo string) (string, string, string, error) {
 	if len(repo) > 0 {
@@ -452,6 +657,15 @@ func getEnterpriseOrganizationAndRepo(enterprise, org, repo string) (string, str
 	return "", "", "", fmt.Errorf("enterprise, organization and repository are all empty")
 }
 
+// ownerOf returns the owner of the API resources for the validated enterprise and organization,
+// which is the organization or the user for repository and organization runners, and the enterprise for enterprise runners.
+func ownerOf(enterprise, org string) string {
+	if len(org) > 0 {
+		return org
+	}
+	return enterprise
+}
+
 func getRegistrationKey(org, repo, enterprise string) string {
 	return fmt.Sprintf("org=%s,repo=%s,enterprise=%s", org, repo, enterprise)
 }
@@ -2,8 +2,13 @@ package github
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -129,6 +134,67 @@ func TestRemoveRunner(t *testing.T) {
 	}
 }
 
+func TestGenerateJITConfig(t *testing.T) {
+	tests := []struct {
+		enterprise string
+		org        string
+		repo       string
+		err        bool
+	}{
+		{enterprise: "", org: "", repo: "test/valid", err: false},
+		{enterprise: "", org: "", repo: "test/error", err: true},
+		{enterprise: "", org: "test", repo: "", err: false},
+		{enterprise: "test", org: "", repo: "", err: false},
+	}
+
+	client := newTestClient()
+	for i, tt := range tests {
+		config, err := client.GenerateJITConfig(context.Background(), tt.enterprise, tt.org, tt.repo, &JITRunnerConfigRequest{
+			Name:          "test3",
+			RunnerGroupID: DefaultRunnerGroupID,
+			Labels:        []string{"self-hosted"},
+		})
+		if !tt.err && err != nil {
+			t.Errorf("[%d] unexpected error: %v", i, err)
+		}
+		if tt.err && err == nil {
+			t.Errorf("[%d] expected error", i)
+		}
+		if !tt.err && (config.EncodedJITConfig != fake.JITConfig || config.Runner.GetID() != 3) {
+			t.Errorf("[%d] unexpected jit config: %+v", i, config)
+		}
+	}
+}
+
+func TestGetRunnerGroupID(t *testing.T) {
+	tests := []struct {
+		org   string
+		group string
+		id    int64
+		err   bool
+	}{
+		{org: "test", group: "", id: DefaultRunnerGroupID},
+		{org: "", group: "", id: DefaultRunnerGroupID},
+		{org: "test", group: "gpu", id: 2},
+		{org: "test", group: "missing", err: true},
+		{org: "", group: "gpu", err: true},
+	}
+
+	client := newTestClient()
+	for i, tt := range tests {
+		id, err := client.GetRunnerGroupID(context.Background(), tt.org, tt.group)
+		if !tt.err && err != nil {
+			t.Errorf("[%d] unexpected error: %v", i, err)
+		}
+		if tt.err && err == nil {
+			t.Errorf("[%d] expected error", i)
+		}
+		if id != tt.id {
+			t.Errorf("[%d] unexpected runner group id: want %d, got %d", i, tt.id, id)
+		}
+	}
+}
+
 func TestCleanup(t *testing.T) {
 	token := "token"
 
@@ -159,3 +225,70 @@ func TestUserAgent(t *testing.T) {
 		t.Errorf("UserAgent should be set to actions-runner-controller")
 	}
 }
+
+func TestListActiveOrganizationRepositoriesRotation(t *testing.T) {
+	// 1 recently pushed repository, and 4 repositories rotated by 2 per call.
+	// repo-c is both recently pushed and rotated.
+	byName := [][]string{{"repo-a", "repo-b"}, {"repo-c", "repo-d"}}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if q.Get("sort") == "pushed" {
+			fmt.Fprint(w, `[{"name": "repo-c"}]`)
+			return
+		}
+
+		page, _ := strconv.Atoi(q.Get("page"))
+		if page == 0 {
+			page = 1
+		}
+
+		if page < len(byName) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=%d>; rel="next"`, "http://"+req.Host, req.URL.Path, page+1))
+		}
+
+		var repos []string
+		for _, n := range byName[page-1] {
+			repos = append(repos, fmt.Sprintf(`{"name": %q}`, n))
+		}
+
+		fmt.Fprintf(w, "[%s]", strings.Join(repos, ","))
+	}))
+	defer srv.Close()
+
+	client := newTestClient()
+	baseURL, err := url.Parse(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Client.BaseURL = baseURL
+
+	names := func() []string {
+		repos, err := client.ListActiveOrganizationRepositories(context.Background(), "test", 4)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var names []string
+		for _, r := range repos {
+			names = append(names, r.GetName())
+		}
+		return names
+	}
+
+	want := [][]string{
+		{"repo-c", "repo-a", "repo-b"},
+		{"repo-c", "repo-d"},
+		// Wraps around to the first page
+		{"repo-c", "repo-a", "repo-b"},
+	}
+
+	for i, w := range want {
+		if got := names(); !reflect.DeepEqual(got, w) {
+			t.Errorf("[%d] unexpected repositories: want %v, got %v", i, w, got)
+		}
+	}
+}
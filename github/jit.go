@@ -0,0 +1,96 @@
+package github
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/actions-runner-controller/actions-runner-controller/github/ratelimit"
+	"github.com/google/go-github/v45/github"
+)
+
+// DefaultRunnerGroupID is the ID of the "Default" runner group of every organization and enterprise.
+const DefaultRunnerGroupID = 1
+
+// JITRunnerConfigRequest is the request to register a just-in-time runner.
+type JITRunnerConfigRequest struct {
+	Name          string   `json:"name"`
+	RunnerGroupID int64    `json:"runner_group_id"`
+	Labels        []string `json:"labels"`
+	WorkFolder    string   `json:"work_folder,omitempty"`
+}
+
+// JITRunnerConfig is the runner registered by a JITRunnerConfigRequest, and its encoded configuration
+// that the runner starts with, instead of configuring itself with a registration token.
+type JITRunnerConfig struct {
+	Runner           *github.Runner `json:"runner"`
+	EncodedJITConfig string         `json:"encoded_jit_config"`
+}
+
+// GenerateJITConfig registers a just-in-time runner with the repository, the organization, or the enterprise,
+// and returns its configuration. The runner stays registered and offline until it starts with the configuration.
+//
+// https://docs.github.com/en/rest/actions/self-hosted-runners#create-configuration-for-a-just-in-time-runner-for-an-organization
+func (c *Client) GenerateJITConfig(ctx context.Context, enterprise, org, repo string, req *JITRunnerConfigRequest) (*JITRunnerConfig, error) {
+	enterprise, owner, repo, err := getEnterpriseOrganizationAndRepo(enterprise, org, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	gh, err := c.clientFor(ctx, ownerOf(enterprise, owner))
+	if err != nil {
+		return nil, err
+	}
+
+	var path string
+
+	if len(repo) > 0 {
+		path = fmt.Sprintf("repos/%s/%s/actions/runners/generate-jitconfig", owner, repo)
+	} else if len(owner) > 0 {
+		path = fmt.Sprintf("orgs/%s/actions/runners/generate-jitconfig", owner)
+	} else {
+		path = fmt.Sprintf("enterprises/%s/actions/runners/generate-jitconfig", enterprise)
+	}
+
+	r, err := gh.NewRequest("POST", path, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var config JITRunnerConfig
+
+	res, err := gh.Do(ratelimit.WithPriority(ctx, ratelimit.PriorityHigh), r, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate jit config: %w", err)
+	}
+
+	if res.StatusCode != 201 {
+		return nil, fmt.Errorf("unexpected status: %d", res.StatusCode)
+	}
+
+	return &config, nil
+}
+
+// GetRunnerGroupID returns the ID of the runner group to register a just-in-time runner with.
+// An empty group means the default runner group. Other groups are looked up by name, which is supported only for organizations.
+func (c *Client) GetRunnerGroupID(ctx context.Context, org, group string) (int64, error) {
+	if group == "" {
+		return DefaultRunnerGroupID, nil
+	}
+
+	if org == "" {
+		return 0, fmt.Errorf("runner group %q is supported only for organizational runners", group)
+	}
+
+	groups, err := c.ListOrganizationRunnerGroups(ctx, org)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, g := range groups {
+		if g.GetName() == group {
+			return g.GetID(), nil
+		}
+	}
+
+	return 0, fmt.Errorf("runner group %q not found in organization %s", group, org)
+}
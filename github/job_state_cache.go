@@ -0,0 +1,264 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v45/github"
+)
+
+// JobStateCache keeps the queued and in_progress workflow runs and their jobs per repository,
+// so that many HorizontalRunnerAutoscalers can read them without walking the GitHub API on their own.
+//
+// Each repository is refreshed at most once per refresh interval.
+// Every refresh sends conditional requests with the ETag of the previous response,
+// so that an unchanged run or job list results in a 304 that doesn't count against the API rate limit.
+//
+// A repository that isn't requested for jobStateCacheIdleIntervals refresh intervals, or minJobStateCacheIdleTTL if longer,
+// is evicted, so that the repositories no longer scanned, like the ones of deleted HRAs, don't pile up.
+type JobStateCache struct {
+	client          *Client
+	refreshInterval time.Duration
+	log             logr.Logger
+
+	mu        sync.Mutex
+	repos     map[string]*repositoryJobStateEntry
+	lastSweep time.Time
+}
+
+const (
+	jobStateCacheIdleIntervals = 10
+	minJobStateCacheIdleTTL    = 10 * time.Minute
+)
+
+type repositoryJobStateEntry struct {
+	mu    sync.Mutex
+	state *RepositoryJobState
+	// etags holds the last successful response for each request URL
+	etags map[string]conditionalResponse
+	// lastRequested is guarded by JobStateCache.mu
+	lastRequested time.Time
+}
+
+type conditionalResponse struct {
+	etag     string
+	body     json.RawMessage
+	nextPage int
+}
+
+// RepositoryJobState is a snapshot of the queued and in_progress workflow runs of a repository.
+type RepositoryJobState struct {
+	Owner, Repository string
+
+	Runs []WorkflowRunState
+
+	FetchedAt time.Time
+}
+
+// WorkflowRunState is a queued or in_progress workflow run along with its jobs.
+type WorkflowRunState struct {
+	ID     int64
+	Status string
+
+	// Name is the name of the workflow, and HeadBranch is the branch the run is triggered for.
+	Name, HeadBranch string
+
+	Jobs []*github.WorkflowJob
+
+	// JobsListed is false when we failed to list the jobs of the run.
+	JobsListed bool
+}
+
+// NewJobStateCache creates a JobStateCache that refreshes each repository at most once per refreshInterval.
+func NewJobStateCache(client *Client, refreshInterval time.Duration, log logr.Logger) *JobStateCache {
+	return &JobStateCache{
+		client:          client,
+		refreshInterval: refreshInterval,
+		log:             log,
+		repos:           map[string]*repositoryJobStateEntry{},
+	}
+}
+
+// Get returns the job state of the repository, refreshing it when it's older than the refresh interval.
+// Concurrent callers for the same repository wait for a single refresh.
+func (c *JobStateCache) Get(ctx context.Context, owner, repo string) (*RepositoryJobState, error) {
+	e := c.entry(owner + "/" + repo)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.state != nil && time.Since(e.state.FetchedAt) < c.refreshInterval {
+		return e.state, nil
+	}
+
+	state, err := c.refresh(ctx, e, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	e.state = state
+
+	return state, nil
+}
+
+func (c *JobStateCache) entry(key string) *repositoryJobStateEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	c.evictIdle(now)
+
+	e, ok := c.repos[key]
+	if !ok {
+		e = &repositoryJobStateEntry{
+			etags: map[string]conditionalResponse{},
+		}
+		c.repos[key] = e
+	}
+
+	e.lastRequested = now
+
+	return e
+}
+
+// evictIdle removes the repositories that aren't requested for the idle TTL.
+// It scans the repositories at most once per the idle TTL. The caller must hold c.mu.
+func (c *JobStateCache) evictIdle(now time.Time) {
+	idleTTL := c.refreshInterval * jobStateCacheIdleIntervals
+	if idleTTL < minJobStateCacheIdleTTL {
+		idleTTL = minJobStateCacheIdleTTL
+	}
+
+	if now.Sub(c.lastSweep) < idleTTL {
+		return
+	}
+
+	c.lastSweep = now
+
+	for key, e := range c.repos {
+		if now.Sub(e.lastRequested) >= idleTTL {
+			delete(c.repos, key)
+		}
+	}
+}
+
+func (c *JobStateCache) refresh(ctx context.Context, e *repositoryJobStateEntry, owner, repo string) (*RepositoryJobState, error) {
+	// We keep only the ETags used in this refresh, so that the ones for completed runs don't pile up
+	etags := map[string]conditionalResponse{}
+
+	state := &RepositoryJobState{
+		Owner:      owner,
+		Repository: repo,
+		FetchedAt:  time.Now(),
+	}
+
+	for _, status := range []string{"queued", "in_progress"} {
+		var runs []*github.WorkflowRun
+
+		for page := 1; page != 0; {
+			u := fmt.Sprintf("repos/%v/%v/actions/runs?status=%s&per_page=100&page=%d", owner, repo, status, page)
+
+			var list github.WorkflowRuns
+			next, err := c.getConditionally(ctx, e, etags, owner, u, &list)
+			if err != nil {
+				return nil, fmt.Errorf("listing %s workflow runs: %w", status, err)
+			}
+
+			runs = append(runs, list.WorkflowRuns...)
+			page = next
+		}
+
+		for _, run := range runs {
+			rs := WorkflowRunState{
+				ID:         run.GetID(),
+				Status:     run.GetStatus(),
+				Name:       run.GetName(),
+				HeadBranch: run.GetHeadBranch(),
+			}
+
+			if rs.ID == 0 {
+				rs.JobsListed = true
+				state.Runs = append(state.Runs, rs)
+				continue
+			}
+
+			jobs, err := c.listWorkflowJobs(ctx, e, etags, owner, repo, rs.ID)
+			if err == nil {
+				rs.Jobs = jobs
+				rs.JobsListed = true
+			} else {
+				c.log.Error(err, "Error listing workflow jobs", "owner", owner, "repository", repo, "run_id", rs.ID)
+			}
+
+			state.Runs = append(state.Runs, rs)
+		}
+	}
+
+	e.etags = etags
+
+	return state, nil
+}
+
+func (c *JobStateCache) listWorkflowJobs(ctx context.Context, e *repositoryJobStateEntry, etags map[string]conditionalResponse, owner, repo string, runID int64) ([]*github.WorkflowJob, error) {
+	var jobs []*github.WorkflowJob
+
+	for page := 1; page != 0; {
+		u := fmt.Sprintf("repos/%v/%v/actions/runs/%v/jobs?per_page=50&page=%d", owner, repo, runID, page)
+
+		var list github.Jobs
+		next, err := c.getConditionally(ctx, e, etags, owner, u, &list)
+		if err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, list.Jobs...)
+		page = next
+	}
+
+	return jobs, nil
+}
+
+// getConditionally sends a GET request with the ETag of the previous response to the same URL, if any,
+// and decodes either the fresh response or the previous one when the server responded with 304 Not Modified.
+// It returns the next page number, which is 0 when it's the last page.
+func (c *JobStateCache) getConditionally(ctx context.Context, e *repositoryJobStateEntry, etags map[string]conditionalResponse, owner, u string, v interface{}) (int, error) {
+	gh, err := c.client.clientFor(ctx, owner)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := gh.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	prev, hasPrev := e.etags[u]
+	if hasPrev && prev.etag != "" {
+		req.Header.Set("If-None-Match", prev.etag)
+	}
+
+	var body json.RawMessage
+
+	res, err := gh.Do(ctx, req, &body)
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusNotModified && hasPrev {
+			etags[u] = prev
+			return prev.nextPage, json.Unmarshal(prev.body, v)
+		}
+		return 0, err
+	}
+
+	etags[u] = conditionalResponse{
+		etag:     res.Header.Get("ETag"),
+		body:     body,
+		nextPage: res.NextPage,
+	}
+
+	return res.NextPage, json.Unmarshal(body, v)
+}
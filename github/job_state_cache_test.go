@@ -0,0 +1,215 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+)
+
+type etagServer struct {
+	mu     sync.Mutex
+	bodies map[string]string
+	etags  map[string]string
+
+	ok, notModified int
+}
+
+func (s *etagServer) set(key, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bodies[key] = body
+	s.etags[key] = fmt.Sprintf(`"%d"`, len(s.etags)+len(body))
+}
+
+func (s *etagServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := req.URL.Path
+	if status := req.URL.Query().Get("status"); status != "" {
+		key += "?status=" + status
+	}
+
+	body, ok := s.bodies[key]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	etag := s.etags[key]
+	if req.Header.Get("If-None-Match") == etag {
+		s.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	s.ok++
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, body)
+}
+
+func newJobStateCacheTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c := Config{
+		Token: "token",
+	}
+	client, err := c.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Client.BaseURL = baseURL
+
+	return client
+}
+
+// jobStatuses returns the status of each job in the state keyed by the job ID.
+func jobStatuses(state *RepositoryJobState) map[int64]string {
+	statuses := map[int64]string{}
+
+	for _, run := range state.Runs {
+		for _, j := range run.Jobs {
+			statuses[j.GetID()] = j.GetStatus()
+		}
+	}
+
+	return statuses
+}
+
+func TestJobStateCache(t *testing.T) {
+	s := &etagServer{
+		bodies: map[string]string{},
+		etags:  map[string]string{},
+	}
+
+	s.set("/repos/test/valid/actions/runs?status=queued", `{"total_count": 2, "workflow_runs": [{"id": 1, "status": "queued"}, {"status": "queued"}]}`)
+	s.set("/repos/test/valid/actions/runs?status=in_progress", `{"total_count": 1, "workflow_runs": [{"id": 2, "status": "in_progress"}]}`)
+	s.set("/repos/test/valid/actions/runs/1/jobs", `{"total_count": 2, "jobs": [
+  {"id": 11, "status": "queued", "labels": ["self-hosted", "linux"]},
+  {"id": 12, "status": "queued", "labels": ["linux", "self-hosted"]}
+]}`)
+	s.set("/repos/test/valid/actions/runs/2/jobs", `{"total_count": 2, "jobs": [
+  {"id": 21, "status": "in_progress", "labels": ["self-hosted", "gpu"]},
+  {"id": 22, "status": "completed", "labels": ["self-hosted", "linux"]}
+]}`)
+
+	cache := NewJobStateCache(newJobStateCacheTestClient(t, s), 0, logr.Discard())
+
+	ctx := context.Background()
+
+	state, err := cache.Get(ctx, "test", "valid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantJobs := map[int64]string{11: "queued", 12: "queued", 21: "in_progress", 22: "completed"}
+	if d := cmp.Diff(wantJobs, jobStatuses(state)); d != "" {
+		t.Errorf("unexpected jobs: (-want +got)\n%s", d)
+	}
+
+	if len(state.Runs) != 3 {
+		t.Errorf("unexpected number of runs: want 3, got %d", len(state.Runs))
+	}
+
+	if s.ok != 4 || s.notModified != 0 {
+		t.Fatalf("unexpected requests on the first refresh: ok=%d, not_modified=%d", s.ok, s.notModified)
+	}
+
+	// Nothing changed, so that every request is expected to be conditional and result in 304
+	state, err = cache.Get(ctx, "test", "valid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d := cmp.Diff(wantJobs, jobStatuses(state)); d != "" {
+		t.Errorf("unexpected jobs after 304s: (-want +got)\n%s", d)
+	}
+
+	if s.ok != 4 || s.notModified != 4 {
+		t.Fatalf("unexpected requests on the second refresh: ok=%d, not_modified=%d", s.ok, s.notModified)
+	}
+
+	// Only the changed job list is expected to be fetched again
+	s.set("/repos/test/valid/actions/runs/2/jobs", `{"total_count": 1, "jobs": [
+  {"id": 21, "status": "completed", "labels": ["self-hosted", "gpu"]}
+]}`)
+
+	state, err = cache.Get(ctx, "test", "valid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantJobs = map[int64]string{11: "queued", 12: "queued", 21: "completed"}
+	if d := cmp.Diff(wantJobs, jobStatuses(state)); d != "" {
+		t.Errorf("unexpected jobs after update: (-want +got)\n%s", d)
+	}
+
+	if s.ok != 5 || s.notModified != 7 {
+		t.Fatalf("unexpected requests on the third refresh: ok=%d, not_modified=%d", s.ok, s.notModified)
+	}
+}
+
+func TestJobStateCache_RefreshInterval(t *testing.T) {
+	s := &etagServer{
+		bodies: map[string]string{},
+		etags:  map[string]string{},
+	}
+
+	s.set("/repos/test/valid/actions/runs?status=queued", `{"total_count": 0, "workflow_runs": []}`)
+	s.set("/repos/test/valid/actions/runs?status=in_progress", `{"total_count": 0, "workflow_runs": []}`)
+
+	cache := NewJobStateCache(newJobStateCacheTestClient(t, s), time.Hour, logr.Discard())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.Get(context.Background(), "test", "valid"); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if s.ok+s.notModified != 2 {
+		t.Errorf("expected the repository to be refreshed only once for all the readers, but got %d requests", s.ok+s.notModified)
+	}
+}
+
+func TestJobStateCache_EvictIdle(t *testing.T) {
+	cache := NewJobStateCache(nil, time.Minute, logr.Discard())
+
+	now := time.Now()
+
+	cache.repos["test/idle"] = &repositoryJobStateEntry{lastRequested: now.Add(-time.Hour)}
+	cache.repos["test/active"] = &repositoryJobStateEntry{lastRequested: now.Add(-time.Minute)}
+
+	cache.evictIdle(now)
+
+	if _, ok := cache.repos["test/idle"]; ok {
+		t.Errorf("expected the repository not requested for the idle TTL to be evicted")
+	}
+
+	if _, ok := cache.repos["test/active"]; !ok {
+		t.Errorf("expected the recently requested repository to be kept")
+	}
+}
@@ -0,0 +1,316 @@
+// Package ratelimit provides a transport that budgets GitHub API calls according to the API rate limit.
+//
+// It tracks the remaining budget and the reset time of the rate limit seen in responses.
+// When the budget runs low, lower priority calls are refused in favor of higher priority ones,
+// and all the calls back off while GitHub asks us to, honoring Retry-After of secondary rate limits.
+// See https://docs.github.com/en/rest/overview/resources-in-the-rest-api#rate-limiting
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/gregjones/httpcache"
+)
+
+const (
+	headerRateLimit          = "X-RateLimit-Limit"
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+	headerRetryAfter         = "Retry-After"
+
+	// DefaultMaxWait is the longest time a call waits for a backoff to end before it's refused.
+	DefaultMaxWait = 10 * time.Second
+
+	// defaultSecondaryRateLimitBackoff is used when GitHub responded with a secondary rate limit without Retry-After.
+	// GitHub recommends waiting at least a minute in that case.
+	defaultSecondaryRateLimitBackoff = time.Minute
+
+	maxRetries = 2
+)
+
+// Priority is the priority of a GitHub API call.
+type Priority int
+
+const (
+	// PriorityLow is for calls that can be skipped until the budget recovers, like metric polling.
+	PriorityLow Priority = iota - 1
+	// PriorityNormal is the default priority.
+	PriorityNormal
+	// PriorityHigh is for calls required to keep runners working, like registration tokens and runner removals.
+	PriorityHigh
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context that makes the GitHub API calls made with it have the priority.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom returns the priority set to the context, or PriorityNormal.
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+// ThrottledError is returned for a call refused to save the budget or to back off.
+type ThrottledError struct {
+	Priority Priority
+	// Remaining is the remaining number of calls in the current rate limit window, or -1 if unknown
+	Remaining int
+	// RetryAfter is the duration after which the call would be allowed
+	RetryAfter time.Duration
+	Reason     string
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("github api call of %s priority throttled: %s: retry after %s", e.Priority, e.Reason, e.RetryAfter.Round(time.Second))
+}
+
+// Budget tracks the rate limit of a GitHub API credential.
+// It must be shared among all the transports that use the same credential.
+type Budget struct {
+	// LowPriorityReserve is the fraction of the rate limit kept for normal and high priority calls.
+	// Low priority calls are refused when the remaining budget is below it. Defaults to 0.2.
+	LowPriorityReserve float64
+	// NormalPriorityReserve is the fraction of the rate limit kept for high priority calls.
+	// Normal priority calls are refused when the remaining budget is below it. Defaults to 0.05.
+	NormalPriorityReserve float64
+
+	mu        sync.Mutex
+	limit     int
+	remaining int
+	reset     time.Time
+	// backoffUntil is the time until which GitHub asked us not to make any call
+	backoffUntil time.Time
+
+	now func() time.Time
+}
+
+// NewBudget creates a Budget with the default reserves.
+func NewBudget() *Budget {
+	return &Budget{
+		LowPriorityReserve:    0.2,
+		NormalPriorityReserve: 0.05,
+		remaining:             -1,
+		now:                   time.Now,
+	}
+}
+
+// Remaining returns the remaining number of calls and the reset time of the current rate limit window.
+// The remaining number is -1 when we haven't seen any response yet.
+func (b *Budget) Remaining() (int, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.remaining, b.reset
+}
+
+// check returns the duration the call of the priority needs to wait for, or a ThrottledError when it shouldn't be made.
+func (b *Budget) check(p Priority, maxWait time.Duration) (time.Duration, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+
+	if wait := b.backoffUntil.Sub(now); wait > 0 {
+		if wait <= maxWait {
+			return wait, nil
+		}
+
+		return 0, &ThrottledError{Priority: p, Remaining: b.remaining, RetryAfter: wait, Reason: "backing off as requested by GitHub"}
+	}
+
+	if b.remaining < 0 || b.limit <= 0 || !b.reset.After(now) {
+		// We haven't seen the rate limit yet, or the window has already been reset
+		return 0, nil
+	}
+
+	var reserve float64
+	switch p {
+	case PriorityLow:
+		reserve = b.LowPriorityReserve
+	case PriorityNormal:
+		reserve = b.NormalPriorityReserve
+	}
+
+	if float64(b.remaining) <= float64(b.limit)*reserve || b.remaining == 0 {
+		return 0, &ThrottledError{
+			Priority:   p,
+			Remaining:  b.remaining,
+			RetryAfter: b.reset.Sub(now),
+			Reason:     fmt.Sprintf("only %d of %d calls remaining", b.remaining, b.limit),
+		}
+	}
+
+	// Count the call in advance so that concurrent calls don't overrun the reserve
+	b.remaining--
+
+	return 0, nil
+}
+
+// observe updates the budget from the response, and returns the duration to back off for if GitHub asked us to.
+func (b *Budget) observe(res *http.Response) time.Duration {
+	if res.Header.Get(httpcache.XFromCache) == "1" {
+		// Do not use outdated rate limit values
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+
+	if v, err := strconv.Atoi(res.Header.Get(headerRateLimit)); err == nil {
+		b.limit = v
+	}
+	if v, err := strconv.Atoi(res.Header.Get(headerRateLimitRemaining)); err == nil {
+		b.remaining = v
+	}
+	if v, err := strconv.ParseInt(res.Header.Get(headerRateLimitReset), 10, 64); err == nil {
+		b.reset = time.Unix(v, 0)
+	}
+
+	if res.StatusCode != http.StatusForbidden && res.StatusCode != http.StatusTooManyRequests {
+		return 0
+	}
+
+	var backoff time.Duration
+
+	if retryAfter := res.Header.Get(headerRetryAfter); retryAfter != "" {
+		if secs, err := strconv.Atoi(retryAfter); err == nil {
+			backoff = time.Duration(secs) * time.Second
+		} else if t, err := http.ParseTime(retryAfter); err == nil {
+			backoff = t.Sub(now)
+		}
+	} else if res.Header.Get(headerRateLimitRemaining) == "0" && b.reset.After(now) {
+		// The primary rate limit is exhausted
+		backoff = b.reset.Sub(now)
+	} else if res.StatusCode == http.StatusTooManyRequests {
+		backoff = defaultSecondaryRateLimitBackoff
+	} else {
+		// A 403 without any rate limit hint is an ordinary permission error
+		return 0
+	}
+
+	if backoff <= 0 {
+		return 0
+	}
+
+	if until := now.Add(backoff); until.After(b.backoffUntil) {
+		b.backoffUntil = until
+	}
+
+	return backoff
+}
+
+// Transport wraps a transport with rate limit budgeting and backoff.
+type Transport struct {
+	Transport http.RoundTripper
+
+	Budget *Budget
+
+	// MaxWait is the longest time a call waits for a backoff to end before it's refused. Defaults to DefaultMaxWait.
+	MaxWait time.Duration
+}
+
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	maxWait := t.MaxWait
+	if maxWait == 0 {
+		maxWait = DefaultMaxWait
+	}
+
+	p := PriorityFrom(req.Context())
+
+	for attempt := 0; ; attempt++ {
+		wait, err := t.Budget.check(p, maxWait)
+		if err != nil {
+			return nil, err
+		}
+
+		if wait > 0 {
+			if err := sleep(req.Context(), wait); err != nil {
+				return nil, err
+			}
+		}
+
+		res, err := t.Transport.RoundTrip(req)
+		if err != nil {
+			return res, err
+		}
+
+		backoff := t.Budget.observe(res)
+		if backoff == 0 || backoff > maxWait || attempt >= maxRetries || !replayable(req) {
+			return res, nil
+		}
+
+		// Retry after the backoff, which is done by the budget check above
+		res.Body.Close()
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// IsThrottled returns true and the duration to wait for when the error is caused by a call refused by Transport,
+// or by GitHub due to the primary or secondary rate limit.
+func IsThrottled(err error) (time.Duration, bool) {
+	var terr *ThrottledError
+	if errors.As(err, &terr) {
+		return terr.RetryAfter, true
+	}
+
+	var rerr *github.RateLimitError
+	if errors.As(err, &rerr) {
+		return time.Until(rerr.Rate.Reset.Time), true
+	}
+
+	var aerr *github.AbuseRateLimitError
+	if errors.As(err, &aerr) {
+		return aerr.GetRetryAfter(), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,220 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeGitHub struct {
+	mu sync.Mutex
+
+	limit, remaining int
+	reset            time.Time
+
+	// responses are served in order before falling back to 200 OK
+	responses []func(w http.ResponseWriter)
+
+	calls int
+}
+
+func (s *fakeGitHub) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.remaining > 0 {
+		s.remaining--
+	}
+
+	w.Header().Set(headerRateLimit, fmt.Sprintf("%d", s.limit))
+	w.Header().Set(headerRateLimitRemaining, fmt.Sprintf("%d", s.remaining))
+	w.Header().Set(headerRateLimitReset, fmt.Sprintf("%d", s.reset.Unix()))
+
+	if len(s.responses) > 0 {
+		r := s.responses[0]
+		s.responses = s.responses[1:]
+		r(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func newTestClient(t *testing.T, s *fakeGitHub, maxWait time.Duration) (*http.Client, string) {
+	t.Helper()
+
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	return &http.Client{
+		Transport: Transport{
+			Transport: http.DefaultTransport,
+			Budget:    NewBudget(),
+			MaxWait:   maxWait,
+		},
+	}, server.URL
+}
+
+func get(ctx context.Context, c *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.Do(req)
+	if err == nil {
+		res.Body.Close()
+	}
+
+	return res, err
+}
+
+func TestTransport_Priorities(t *testing.T) {
+	s := &fakeGitHub{
+		limit:     100,
+		remaining: 16,
+		reset:     time.Now().Add(time.Hour),
+	}
+
+	c, url := newTestClient(t, s, time.Second)
+
+	ctx := context.Background()
+
+	// The first call is always made as the budget is unknown yet, and results in 15 remaining calls
+	if _, err := get(ctx, c, url); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err := get(WithPriority(ctx, PriorityLow), c, url)
+	if d, throttled := IsThrottled(err); !throttled {
+		t.Fatalf("expected low priority call to be throttled, got %v", err)
+	} else if d < 59*time.Minute {
+		t.Errorf("expected to retry after the reset, got %s", d)
+	}
+
+	// Normal priority calls are made until the 5% reserve
+	for i := 0; i < 10; i++ {
+		if _, err := get(ctx, c, url); err != nil {
+			t.Fatalf("unexpected error on normal priority call %d: %v", i, err)
+		}
+	}
+
+	if _, err := get(ctx, c, url); err == nil {
+		t.Fatalf("expected normal priority call to be throttled")
+	}
+
+	// High priority calls are made until the budget is exhausted
+	for i := 0; i < 5; i++ {
+		if _, err := get(WithPriority(ctx, PriorityHigh), c, url); err != nil {
+			t.Fatalf("unexpected error on high priority call %d: %v", i, err)
+		}
+	}
+
+	if _, err := get(WithPriority(ctx, PriorityHigh), c, url); err == nil {
+		t.Fatalf("expected high priority call to be throttled after the budget is exhausted")
+	}
+
+	if s.calls != 16 {
+		t.Errorf("unexpected number of calls: want 16, got %d", s.calls)
+	}
+}
+
+func TestTransport_RetryAfter(t *testing.T) {
+	s := &fakeGitHub{
+		limit:     5000,
+		remaining: 5000,
+		reset:     time.Now().Add(time.Hour),
+		responses: []func(w http.ResponseWriter){
+			func(w http.ResponseWriter) {
+				w.Header().Set(headerRetryAfter, "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+		},
+	}
+
+	c, url := newTestClient(t, s, 5*time.Second)
+
+	start := time.Now()
+
+	res, err := get(context.Background(), c, url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("expected the call to be retried and succeed, got status %d", res.StatusCode)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected the retry to wait for Retry-After, but it took only %s", elapsed)
+	}
+
+	if s.calls != 2 {
+		t.Errorf("unexpected number of calls: want 2, got %d", s.calls)
+	}
+}
+
+func TestTransport_SecondaryRateLimitBeyondMaxWait(t *testing.T) {
+	s := &fakeGitHub{
+		limit:     5000,
+		remaining: 5000,
+		reset:     time.Now().Add(time.Hour),
+		responses: []func(w http.ResponseWriter){
+			func(w http.ResponseWriter) {
+				w.Header().Set(headerRetryAfter, "60")
+				w.WriteHeader(http.StatusForbidden)
+			},
+		},
+	}
+
+	c, url := newTestClient(t, s, time.Second)
+
+	res, err := get(context.Background(), c, url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected the secondary rate limit response to be returned as-is, got status %d", res.StatusCode)
+	}
+
+	// Every call, including high priority ones, backs off as requested by GitHub
+	_, err = get(WithPriority(context.Background(), PriorityHigh), c, url)
+	if d, throttled := IsThrottled(err); !throttled {
+		t.Fatalf("expected the call to be throttled, got %v", err)
+	} else if d < 58*time.Second || d > 60*time.Second {
+		t.Errorf("unexpected retry after: %s", d)
+	}
+
+	if s.calls != 1 {
+		t.Errorf("unexpected number of calls: want 1, got %d", s.calls)
+	}
+}
+
+func TestTransport_ForbiddenWithoutRateLimit(t *testing.T) {
+	s := &fakeGitHub{
+		limit:     5000,
+		remaining: 5000,
+		reset:     time.Now().Add(time.Hour),
+		responses: []func(w http.ResponseWriter){
+			func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+		},
+	}
+
+	c, url := newTestClient(t, s, time.Second)
+
+	if _, err := get(context.Background(), c, url); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := get(context.Background(), c, url); err != nil {
+		t.Fatalf("expected a permission error not to trigger backoff, got %v", err)
+	}
+}
@@ -77,6 +77,8 @@ func main() {
 		gitHubAPICacheDuration time.Duration
 		defaultScaleDownDelay  time.Duration
 
+		runnerGitHubStatusSyncInterval time.Duration
+
 		runnerImage            string
 		runnerImagePullSecrets stringSlice
 
@@ -114,6 +116,7 @@ func main() {
 	flag.StringVar(&c.RunnerGitHubURL, "runner-github-url", c.RunnerGitHubURL, "GitHub URL to be used by runners during registration")
 	flag.DurationVar(&gitHubAPICacheDuration, "github-api-cache-duration", 0, "DEPRECATED: The duration until the GitHub API cache expires. Setting this to e.g. 10m results in the controller tries its best not to make the same API call within 10m to reduce the chance of being rate-limited. Defaults to mostly the same value as sync-period. If you're tweaking this in order to make autoscaling more responsive, you'll probably want to tweak sync-period, too")
 	flag.DurationVar(&defaultScaleDownDelay, "default-scale-down-delay", controllers.DefaultScaleDownDelay, "The approximate delay for a scale down followed by a scale up, used to prevent flapping (down->up->down->... loop)")
+	flag.DurationVar(&runnerGitHubStatusSyncInterval, "runner-github-status-sync-interval", controllers.DefaultRunnerGitHubStatusSyncInterval, "The interval of syncing the runner status with the state of the runner seen in the GitHub API, like the runner ID, online/offline, and busy. Set to 0 to disable the sync")
 	flag.IntVar(&port, "port", 9443, "The port to which the admission webhook endpoint should bind")
 	flag.DurationVar(&syncPeriod, "sync-period", 1*time.Minute, "Determines the minimum frequency at which K8s resources managed by this controller are reconciled.")
 	flag.Var(&commonRunnerLabels, "common-runner-labels", "Runner labels in the K1=V1,K2=V2,... format that are inherited all the runners created by the controller. See https://github.com/actions-runner-controller/actions-runner-controller/issues/321 for more information")
@@ -147,16 +150,23 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Runners, runner sets, and HRAs can use their own GitHub API credentials instead of the controller-wide ones
+	ghClients := controllers.NewMultiGitHubClient(mgr.GetClient(), c)
+
 	runnerReconciler := &controllers.RunnerReconciler{
 		Client:               mgr.GetClient(),
 		Log:                  log.WithName("runner"),
 		Scheme:               mgr.GetScheme(),
 		GitHubClient:         ghClient,
+		GitHubClients:        ghClients,
 		DockerImage:          dockerImage,
 		DockerRegistryMirror: dockerRegistryMirror,
 		// Defaults for self-hosted runner containers
 		RunnerImage:            runnerImage,
 		RunnerImagePullSecrets: runnerImagePullSecrets,
+
+		GitHubStatusSyncInterval: runnerGitHubStatusSyncInterval,
+		APIReader:                mgr.GetAPIReader(),
 	}
 
 	if err = runnerReconciler.SetupWithManager(mgr); err != nil {
@@ -231,15 +241,19 @@ func main() {
 		Log:                   log.WithName("horizontalrunnerautoscaler"),
 		Scheme:                mgr.GetScheme(),
 		GitHubClient:          ghClient,
+		GitHubClients:         ghClients,
 		CacheDuration:         gitHubAPICacheDuration,
 		DefaultScaleDownDelay: defaultScaleDownDelay,
+		JobStateCache:         github.NewJobStateCache(ghClient, gitHubAPICacheDuration, log.WithName("jobstatecache")),
+		APIReader:             mgr.GetAPIReader(),
 	}
 
 	runnerPodReconciler := &controllers.RunnerPodReconciler{
-		Client:       mgr.GetClient(),
-		Log:          log.WithName("runnerpod"),
-		Scheme:       mgr.GetScheme(),
-		GitHubClient: ghClient,
+		Client:        mgr.GetClient(),
+		Log:           log.WithName("runnerpod"),
+		Scheme:        mgr.GetScheme(),
+		GitHubClient:  ghClient,
+		GitHubClients: ghClients,
 	}
 
 	runnerPersistentVolumeReconciler := &controllers.RunnerPersistentVolumeReconciler{
@@ -286,12 +300,17 @@ func main() {
 		log.Error(err, "unable to create webhook", "webhook", "RunnerReplicaSet")
 		os.Exit(1)
 	}
+	if err = (&actionsv1alpha1.HorizontalRunnerAutoscaler{}).SetupWebhookWithManager(mgr); err != nil {
+		log.Error(err, "unable to create webhook", "webhook", "HorizontalRunnerAutoscaler")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
 	injector := &controllers.PodRunnerTokenInjector{
-		Client:       mgr.GetClient(),
-		GitHubClient: ghClient,
-		Log:          ctrl.Log.WithName("webhook").WithName("PodRunnerTokenInjector"),
+		Client:        mgr.GetClient(),
+		GitHubClient:  ghClient,
+		GitHubClients: ghClients,
+		Log:           ctrl.Log.WithName("webhook").WithName("PodRunnerTokenInjector"),
 	}
 	if err = injector.SetupWithManager(mgr); err != nil {
 		log.Error(err, "unable to create webhook server", "webhook", "PodRunnerTokenInjector")
@@ -0,0 +1,233 @@
+// Package cron parses the standard 5-field cron expressions used by the scheduled overrides of HorizontalRunnerAutoscaler.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed standard 5-field cron expression, like "0 8 * * 1-5".
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// domAny and dowAny are true when the field is "*", which follows the cron convention that
+	// a day matches either of the day-of-month and day-of-week fields when both are restricted.
+	domAny, dowAny bool
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute = cronField{min: 0, max: 59}
+	cronHour   = cronField{min: 0, max: 23}
+	cronDOM    = cronField{min: 1, max: 31}
+	cronMonth  = cronField{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is also accepted as Sunday
+	cronDOW = cronField{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses the cron expression, which is either 5 fields of minute, hour, day of month, month, and day of week,
+// or one of the macros like "@daily". Each field is a comma-separated list of "*", "n", or "a-b", optionally followed by "/step".
+// Months and days of week can also be specified by their three-letter English names.
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+
+	if m, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = m
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: it must have 5 fields of minute, hour, day of month, month, and day of week", expr)
+	}
+
+	var (
+		s   Schedule
+		err error
+	)
+
+	for _, f := range []struct {
+		value string
+		field cronField
+		bits  *uint64
+	}{
+		{fields[0], cronMinute, &s.minute},
+		{fields[1], cronHour, &s.hour},
+		{fields[2], cronDOM, &s.dom},
+		{fields[3], cronMonth, &s.month},
+		{fields[4], cronDOW, &s.dow},
+	} {
+		*f.bits, err = parseCronField(f.value, f.field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+	}
+
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	s.domAny = strings.HasPrefix(fields[2], "*")
+	s.dowAny = strings.HasPrefix(fields[4], "*")
+
+	return &s, nil
+}
+
+// parseCronField parses a comma-separated list of "*", "n", "a-b", optionally followed by "/step", into a bit set.
+func parseCronField(value string, field cronField) (uint64, error) {
+	var bits uint64
+
+	for _, item := range strings.Split(value, ",") {
+		rangeAndStep := strings.Split(item, "/")
+		if len(rangeAndStep) > 2 {
+			return 0, fmt.Errorf("invalid item %q", item)
+		}
+
+		step := 1
+
+		if len(rangeAndStep) == 2 {
+			s, err := strconv.Atoi(rangeAndStep[1])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", item)
+			}
+
+			step = s
+		}
+
+		var start, end int
+
+		if r := rangeAndStep[0]; r == "*" {
+			start, end = field.min, field.max
+		} else {
+			bounds := strings.Split(r, "-")
+			if len(bounds) > 2 {
+				return 0, fmt.Errorf("invalid range %q", r)
+			}
+
+			var err error
+
+			start, err = parseCronValue(bounds[0], field)
+			if err != nil {
+				return 0, err
+			}
+
+			end = start
+
+			if len(bounds) == 2 {
+				end, err = parseCronValue(bounds[1], field)
+				if err != nil {
+					return 0, err
+				}
+			} else if len(rangeAndStep) == 2 {
+				// "n/step" means "n-max/step"
+				end = field.max
+			}
+
+			if end < start {
+				return 0, fmt.Errorf("invalid range %q: the end must not be less than the start", r)
+			}
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, nil
+}
+
+func parseCronValue(v string, field cronField) (int, error) {
+	if n, ok := field.names[strings.ToLower(v)]; ok {
+		return n, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", v)
+	}
+
+	if n < field.min || n > field.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", n, field.min, field.max)
+	}
+
+	return n, nil
+}
+
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+// Next returns the first time after t that matches the schedule, in the location of t.
+// The schedule matches the wall clock time, so a time skipped by a daylight saving time change never matches,
+// and a time repeated by a change matches only the first time.
+// It returns the zero time when nothing matches within five years, like "0 0 30 2 *".
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	limit := t.AddDate(5, 0, 0)
+
+	// advance moves t forward to the next candidate, falling back to the next minute
+	// when time.Date moves it backward, which happens around daylight saving time changes
+	advance := func(next time.Time) {
+		if next.After(t) {
+			t = next
+		} else {
+			t = t.Add(time.Minute)
+		}
+	}
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			advance(time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+			continue
+		}
+
+		if !s.matchesDay(t) {
+			advance(time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+			continue
+		}
+
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			advance(time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc))
+			continue
+		}
+
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			advance(time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc))
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,261 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse_Errors(t *testing.T) {
+	testcases := []struct {
+		expr string
+		want string
+	}{
+		{"", "it must have 5 fields"},
+		{"* * * *", "it must have 5 fields"},
+		{"* * * * * *", "it must have 5 fields"},
+		{"@every 5m", "it must have 5 fields"},
+		{"60 * * * *", "value 60 out of range [0, 59]"},
+		{"* 24 * * *", "value 24 out of range [0, 23]"},
+		{"* * 0 * *", "value 0 out of range [1, 31]"},
+		{"* * * 13 *", "value 13 out of range [1, 12]"},
+		{"* * * * 8", "value 8 out of range [0, 7]"},
+		{"*/0 * * * *", `invalid step in "*/0"`},
+		{"*/x * * * *", `invalid step in "*/x"`},
+		{"1/2/3 * * * *", `invalid item "1/2/3"`},
+		{"5-1 * * * *", `invalid range "5-1"`},
+		{"1-2-3 * * * *", `invalid range "1-2-3"`},
+		{"1,,2 * * * *", `invalid value ""`},
+		{"* * * foo *", `invalid value "foo"`},
+		// Names are specific to their fields
+		{"* * * mon *", `invalid value "mon"`},
+		{"* * * * jan", `invalid value "jan"`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := Parse(tc.expr)
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("unexpected error: want %q in %q", tc.want, err.Error())
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	// 2022-06-01 is a Wednesday
+	from := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	date := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	testcases := []struct {
+		name string
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		{
+			name: "every minute, strictly after the time with seconds truncated",
+			expr: "* * * * *",
+			from: from.Add(30 * time.Second),
+			want: []time.Time{date(2022, 6, 1, 0, 1), date(2022, 6, 1, 0, 2)},
+		},
+		{
+			name: "list",
+			expr: "0 8,12,18 * * *",
+			from: from,
+			want: []time.Time{date(2022, 6, 1, 8, 0), date(2022, 6, 1, 12, 0), date(2022, 6, 1, 18, 0), date(2022, 6, 2, 8, 0)},
+		},
+		{
+			name: "step from a value",
+			expr: "5/15 10 * * *",
+			from: from,
+			want: []time.Time{date(2022, 6, 1, 10, 5), date(2022, 6, 1, 10, 20), date(2022, 6, 1, 10, 35), date(2022, 6, 1, 10, 50), date(2022, 6, 2, 10, 5)},
+		},
+		{
+			name: "step in a range",
+			expr: "10-20/5 8 * * *",
+			from: from,
+			want: []time.Time{date(2022, 6, 1, 8, 10), date(2022, 6, 1, 8, 15), date(2022, 6, 1, 8, 20), date(2022, 6, 2, 8, 10)},
+		},
+		{
+			name: "month and day of week names in any case",
+			expr: "0 9 * feb MON-wed",
+			from: from,
+			// 2023-02-01 is a Wednesday
+			want: []time.Time{date(2023, 2, 1, 9, 0), date(2023, 2, 6, 9, 0)},
+		},
+		{
+			name: "7 is Sunday",
+			expr: "0 0 * * 7",
+			from: from,
+			want: []time.Time{date(2022, 6, 5, 0, 0), date(2022, 6, 12, 0, 0)},
+		},
+		{
+			name: "either of the restricted day of month and day of week",
+			expr: "0 0 13 * 5",
+			from: from,
+			// 2022-06-13 is a Monday
+			want: []time.Time{date(2022, 6, 3, 0, 0), date(2022, 6, 10, 0, 0), date(2022, 6, 13, 0, 0), date(2022, 6, 17, 0, 0)},
+		},
+		{
+			name: "day of month only",
+			expr: "0 0 13 * *",
+			from: from,
+			want: []time.Time{date(2022, 6, 13, 0, 0), date(2022, 7, 13, 0, 0)},
+		},
+		{
+			name: "both of the day of month starting with an asterisk and day of week",
+			expr: "0 0 */10 * 1",
+			from: from,
+			// The first Monday on the 1st, 11th, 21st, or 31st
+			want: []time.Time{date(2022, 7, 11, 0, 0), date(2022, 8, 1, 0, 0)},
+		},
+		{
+			name: "leap day",
+			expr: "0 0 29 2 *",
+			from: from,
+			want: []time.Time{date(2024, 2, 29, 0, 0)},
+		},
+		{
+			name: "@yearly",
+			expr: "@yearly",
+			from: from,
+			want: []time.Time{date(2023, 1, 1, 0, 0), date(2024, 1, 1, 0, 0)},
+		},
+		{
+			name: "@monthly",
+			expr: "@monthly",
+			from: from,
+			want: []time.Time{date(2022, 7, 1, 0, 0), date(2022, 8, 1, 0, 0)},
+		},
+		{
+			name: "@weekly",
+			expr: "@weekly",
+			from: from,
+			want: []time.Time{date(2022, 6, 5, 0, 0), date(2022, 6, 12, 0, 0)},
+		},
+		{
+			name: "@daily",
+			expr: "@daily",
+			from: from,
+			want: []time.Time{date(2022, 6, 2, 0, 0), date(2022, 6, 3, 0, 0)},
+		},
+		{
+			name: "@HOURLY in upper case",
+			expr: " @HOURLY ",
+			from: from.Add(30 * time.Minute),
+			want: []time.Time{date(2022, 6, 1, 1, 0), date(2022, 6, 1, 2, 0)},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			next := tc.from
+
+			for i, want := range tc.want {
+				next = s.Next(next)
+
+				if !next.Equal(want) {
+					t.Fatalf("unexpected time #%d: want %s, got %s", i, want, next)
+				}
+			}
+		})
+	}
+}
+
+func TestNext_NeverMatches(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if next := s.Next(time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)); !next.IsZero() {
+		t.Errorf("expected the zero time for the schedule never matching, got %s", next)
+	}
+}
+
+func TestNext_DaylightSavingTime(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The clocks go from 01:59 EST to 03:00 EDT on 2022-03-13, and from 01:59 EDT back to 01:00 EST on 2022-11-06
+	springForward := time.Date(2022, 3, 13, 0, 0, 0, 0, loc)
+	fallBack := time.Date(2022, 11, 6, 0, 0, 0, 0, loc)
+
+	edt := time.FixedZone("EDT", -4*60*60)
+	est := time.FixedZone("EST", -5*60*60)
+
+	testcases := []struct {
+		name string
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		{
+			name: "the time skipped by the clocks is skipped",
+			expr: "30 2 * * *",
+			from: springForward,
+			want: []time.Time{time.Date(2022, 3, 14, 2, 30, 0, 0, edt)},
+		},
+		{
+			name: "the time right after the skipped hour",
+			expr: "0 3 * * *",
+			from: springForward,
+			want: []time.Time{time.Date(2022, 3, 13, 3, 0, 0, 0, edt), time.Date(2022, 3, 14, 3, 0, 0, 0, edt)},
+		},
+		{
+			name: "the time repeated by the clocks matches only once",
+			expr: "30 1 * * *",
+			from: fallBack,
+			want: []time.Time{time.Date(2022, 11, 6, 1, 30, 0, 0, edt), time.Date(2022, 11, 7, 1, 30, 0, 0, est)},
+		},
+		{
+			name: "the repeated hour matches only once",
+			expr: "*/30 * * * *",
+			from: fallBack,
+			want: []time.Time{
+				time.Date(2022, 11, 6, 0, 30, 0, 0, edt),
+				time.Date(2022, 11, 6, 1, 0, 0, 0, edt),
+				time.Date(2022, 11, 6, 1, 30, 0, 0, edt),
+				time.Date(2022, 11, 6, 2, 0, 0, 0, est),
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			next := tc.from
+
+			for i, want := range tc.want {
+				next = s.Next(next)
+
+				if !next.Equal(want) {
+					t.Fatalf("unexpected time #%d: want %s, got %s", i, want, next)
+				}
+
+				if next.Location() != loc {
+					t.Errorf("unexpected location #%d: want %s, got %s", i, loc, next.Location())
+				}
+			}
+		})
+	}
+}
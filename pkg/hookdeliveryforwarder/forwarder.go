@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -121,11 +122,11 @@ func (f *Forwarder) Run(ctx context.Context) error {
 LOOP:
 	for {
 		var (
-			err      error
-			payloads [][]byte
+			err        error
+			deliveries []*gogithub.HookDelivery
 		)
 
-		payloads, cur, err = f.getUnprocessedDeliveries(ctx, hookDeliveries, *cur)
+		deliveries, cur, err = f.getUnprocessedDeliveries(ctx, hookDeliveries, *cur)
 		if err != nil {
 			f.Errorf("failed getting unprocessed deliveries: %v", err)
 
@@ -134,8 +135,8 @@ LOOP:
 			}
 		}
 
-		for _, p := range payloads {
-			if _, err := http.Post(f.Target, "application/json", bytes.NewReader(p)); err != nil {
+		for _, d := range deliveries {
+			if err := f.forward(ctx, hook.GetID(), d); err != nil {
 				f.Errorf("failed forwarding delivery: %v", err)
 
 				retryDelay := 5 * time.Second
@@ -173,12 +174,41 @@ LOOP:
 	}
 }
 
+// forward POSTs the delivery to the target along with the headers GitHub sends,
+// so that the target can tell the event type and deduplicate the redelivered deliveries by their GUIDs.
+func (f *Forwarder) forward(ctx context.Context, hookID int64, d *gogithub.HookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.Target, bytes.NewReader(*d.Request.RawPayload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Delivery", d.GetGUID())
+	req.Header.Set("X-GitHub-Event", d.GetEvent())
+	req.Header.Set("X-GitHub-Hook-ID", strconv.FormatInt(hookID, 10))
+
+	for _, h := range []string{"X-Hub-Signature", "X-Hub-Signature-256"} {
+		for k, v := range d.Request.Headers {
+			if strings.EqualFold(k, h) {
+				req.Header.Set(h, v)
+			}
+		}
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	return res.Body.Close()
+}
+
 type State struct {
 	DeliveredAt time.Time
 	ID          int64
 }
 
-func (f *Forwarder) getUnprocessedDeliveries(ctx context.Context, hookDeliveries *hookDeliveriesAPI, pos State) ([][]byte, *State, error) {
+func (f *Forwarder) getUnprocessedDeliveries(ctx context.Context, hookDeliveries *hookDeliveriesAPI, pos State) ([]*gogithub.HookDelivery, *State, error) {
 	var (
 		opts gogithub.ListCursorOptions
 	)
@@ -243,11 +273,5 @@ OUTER:
 		return deliveries[b].GetDeliveredAt().After(deliveries[a].GetDeliveredAt().Time)
 	})
 
-	var payloads [][]byte
-
-	for _, d := range deliveries {
-		payloads = append(payloads, *d.Request.RawPayload)
-	}
-
-	return payloads, &pos, nil
+	return deliveries, &pos, nil
 }
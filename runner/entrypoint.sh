@@ -46,8 +46,8 @@ else
   exit 1
 fi
 
-if [ -z "${RUNNER_TOKEN}" ]; then
-  log.error 'RUNNER_TOKEN must be set'
+if [ -z "${RUNNER_TOKEN}" ] && [ -z "${RUNNER_JITCONFIG_FILE}" ]; then
+  log.error 'Either RUNNER_TOKEN or RUNNER_JITCONFIG_FILE must be set'
   exit 1
 fi
 
@@ -85,34 +85,39 @@ if [ "${DISABLE_RUNNER_UPDATE:-}" == "true" ]; then
   log.debug 'Passing --disableupdate to config.sh to disable automatic runner updates.'
 fi
 
-retries_left=10
-while [[ ${retries_left} -gt 0 ]]; do
-  log.debug 'Configuring the runner.'
-  ./config.sh --unattended --replace \
-    --name "${RUNNER_NAME}" \
-    --url "${GITHUB_URL}${ATTACH}" \
-    --token "${RUNNER_TOKEN}" \
-    --runnergroup "${RUNNER_GROUPS}" \
-    --labels "${RUNNER_LABELS}" \
-    --work "${RUNNER_WORKDIR}" "${config_args[@]}"
-
-  if [ -f .runner ]; then
-    log.debug 'Runner successfully configured.'
-    break
+if [ -n "${RUNNER_JITCONFIG_FILE}" ]; then
+  # The runner has been registered by the controller, and starts with the just-in-time config instead of running config.sh
+  log.debug 'Skipping the configuration of the runner as it starts with the JIT config.'
+else
+  retries_left=10
+  while [[ ${retries_left} -gt 0 ]]; do
+    log.debug 'Configuring the runner.'
+    ./config.sh --unattended --replace \
+      --name "${RUNNER_NAME}" \
+      --url "${GITHUB_URL}${ATTACH}" \
+      --token "${RUNNER_TOKEN}" \
+      --runnergroup "${RUNNER_GROUPS}" \
+      --labels "${RUNNER_LABELS}" \
+      --work "${RUNNER_WORKDIR}" "${config_args[@]}"
+
+    if [ -f .runner ]; then
+      log.debug 'Runner successfully configured.'
+      break
+    fi
+
+    log.debug 'Configuration failed. Retrying'
+    retries_left=$((retries_left - 1))
+    sleep 1
+  done
+
+  if [ ! -f .runner ]; then
+    # we couldn't configure and register the runner; no point continuing
+    log.error 'Configuration failed!'
+    exit 2
   fi
 
-  log.debug 'Configuration failed. Retrying'
-  retries_left=$((retries_left - 1))
-  sleep 1
-done
-
-if [ ! -f .runner ]; then
-  # we couldn't configure and register the runner; no point continuing
-  log.error 'Configuration failed!'
-  exit 2
+  cat .runner
 fi
-
-cat .runner
 # Note: the `.runner` file's content should be something like the below:
 #
 # $ cat /runner/.runner
@@ -149,8 +154,12 @@ if [ "${RUNNER_FEATURE_FLAG_ONCE:-}" == "true" -a "${RUNNER_EPHEMERAL}" == "true
     'you are using github.com ignore this warning.'
 fi
 
+if [ -n "${RUNNER_JITCONFIG_FILE}" ]; then
+  args+=(--jitconfig "$(cat "${RUNNER_JITCONFIG_FILE}")")
+fi
+
 # Unset entrypoint environment variables so they don't leak into the runner environment
-unset RUNNER_NAME RUNNER_REPO RUNNER_TOKEN STARTUP_DELAY_IN_SECONDS DISABLE_WAIT_FOR_DOCKER
+unset RUNNER_NAME RUNNER_REPO RUNNER_TOKEN RUNNER_JITCONFIG_FILE STARTUP_DELAY_IN_SECONDS DISABLE_WAIT_FOR_DOCKER
 
 # Docker ignores PAM and thus never loads the system environment variables that
 # are meant to be set in every environment of every user. We emulate the PAM